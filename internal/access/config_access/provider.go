@@ -16,7 +16,11 @@ func Register(cfg *sdkconfig.SDKConfig) {
 		return
 	}
 
-	keys := normalizeKeys(cfg.APIKeys)
+	keys := append([]string(nil), cfg.APIKeys...)
+	for _, policy := range cfg.APIKeyPolicies {
+		keys = append(keys, policy.APIKey)
+	}
+	keys = normalizeKeys(keys)
 	if len(keys) == 0 {
 		sdkaccess.UnregisterProvider(sdkaccess.AccessProviderTypeConfigAPIKey)
 		return
@@ -0,0 +1,387 @@
+// Package oidcjwt implements the "oidc-jwt" access provider, which authenticates requests
+// carrying a bearer JWT issued by an OpenID Connect identity provider. Tokens are verified
+// against a JWKS loaded from a file or URL, and their claims become the request principal.
+package oidcjwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	sdkaccess "github.com/router-for-me/CLIProxyAPI/v6/sdk/access"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultPrincipalClaim = "sub"
+	defaultJWKSRefresh    = 5 * time.Minute
+	defaultLeeway         = time.Minute
+)
+
+var (
+	registeredMu sync.Mutex
+	// registered maps registry keys to the provider built for them, so unchanged entries keep
+	// their cached JWKS across config reloads.
+	registered = make(map[string]*provider)
+)
+
+// Register builds an oidc-jwt provider for every matching entry in cfg.Access.Providers and
+// registers it with the access registry, removing providers no longer configured.
+func Register(cfg *sdkconfig.SDKConfig) {
+	registeredMu.Lock()
+	defer registeredMu.Unlock()
+
+	desired := make(map[string]*provider)
+	if cfg != nil {
+		for _, entry := range cfg.Access.Providers {
+			if !strings.EqualFold(strings.TrimSpace(entry.Type), sdkaccess.AccessProviderTypeOIDCJWT) {
+				continue
+			}
+			name := strings.TrimSpace(entry.Name)
+			if name == "" {
+				name = sdkaccess.AccessProviderTypeOIDCJWT
+			}
+			registryKey := sdkaccess.AccessProviderTypeOIDCJWT + ":" + name
+			if existing, ok := registered[registryKey]; ok && reflect.DeepEqual(existing.raw, entry.Config) {
+				desired[registryKey] = existing
+				continue
+			}
+			p, err := newProvider(name, entry.Config)
+			if err != nil {
+				log.Errorf("access provider %s: %v", name, err)
+				continue
+			}
+			desired[registryKey] = p
+		}
+	}
+
+	for registryKey := range registered {
+		if _, ok := desired[registryKey]; !ok {
+			sdkaccess.UnregisterProvider(registryKey)
+		}
+	}
+	for registryKey, p := range desired {
+		sdkaccess.RegisterProvider(registryKey, p)
+	}
+	registered = desired
+}
+
+type provider struct {
+	name           string
+	raw            map[string]any
+	issuer         string
+	audiences      []string
+	principalClaim string
+	metadataClaims []string
+	leeway         time.Duration
+	keys           *keySet
+	now            func() time.Time
+}
+
+// newProvider parses the provider options:
+//
+//	issuer, audience (string or list), jwks-file or jwks-url, jwks-refresh-seconds,
+//	principal-claim, metadata-claims (list), leeway-seconds
+func newProvider(name string, options map[string]any) (*provider, error) {
+	p := &provider{
+		name:           name,
+		raw:            options,
+		issuer:         optionString(options, "issuer"),
+		audiences:      optionStrings(options, "audience"),
+		principalClaim: optionString(options, "principal-claim"),
+		metadataClaims: optionStrings(options, "metadata-claims"),
+		leeway:         defaultLeeway,
+		now:            time.Now,
+	}
+	if p.issuer == "" {
+		return nil, fmt.Errorf("issuer is required")
+	}
+	if len(p.audiences) == 0 {
+		return nil, fmt.Errorf("audience is required")
+	}
+	if p.principalClaim == "" {
+		p.principalClaim = defaultPrincipalClaim
+	}
+	if seconds, ok := optionInt(options, "leeway-seconds"); ok && seconds >= 0 {
+		p.leeway = time.Duration(seconds) * time.Second
+	}
+	refresh := defaultJWKSRefresh
+	if seconds, ok := optionInt(options, "jwks-refresh-seconds"); ok && seconds > 0 {
+		refresh = time.Duration(seconds) * time.Second
+	}
+	file, url := optionString(options, "jwks-file"), optionString(options, "jwks-url")
+	if (file == "") == (url == "") {
+		return nil, fmt.Errorf("exactly one of jwks-file or jwks-url is required")
+	}
+	p.keys = newKeySet(file, url, refresh)
+	return p, nil
+}
+
+func (p *provider) Identifier() string {
+	if p == nil || p.name == "" {
+		return sdkaccess.AccessProviderTypeOIDCJWT
+	}
+	return p.name
+}
+
+// Authenticate accepts requests whose bearer token (or x-api-key / x-goog-api-key value) is a
+// JWT signed by a key in the JWKS with the configured issuer and audience. Requests without
+// a JWT are left to the other providers.
+func (p *provider) Authenticate(ctx context.Context, r *http.Request) (*sdkaccess.Result, *sdkaccess.AuthError) {
+	if p == nil || r == nil {
+		return nil, sdkaccess.NewNotHandledError()
+	}
+	token, source := extractToken(r)
+	if token == "" {
+		return nil, sdkaccess.NewNotHandledError()
+	}
+	claims, err := p.verify(ctx, token)
+	if err != nil {
+		log.Debugf("access provider %s rejected token: %v", p.name, err)
+		return nil, sdkaccess.NewInvalidCredentialError()
+	}
+	principal := claimString(claims[p.principalClaim])
+	if principal == "" {
+		log.Debugf("access provider %s rejected token: missing %s claim", p.name, p.principalClaim)
+		return nil, sdkaccess.NewInvalidCredentialError()
+	}
+	metadata := map[string]string{
+		"source":  source,
+		"issuer":  p.issuer,
+		"subject": claimString(claims["sub"]),
+	}
+	for _, claim := range p.metadataClaims {
+		if value := claimString(claims[claim]); value != "" {
+			metadata[claim] = value
+		}
+	}
+	return &sdkaccess.Result{Provider: p.Identifier(), Principal: principal, Metadata: metadata}, nil
+}
+
+// extractToken returns the first credential that looks like a compact JWS.
+func extractToken(r *http.Request) (string, string) {
+	candidates := []struct {
+		value  string
+		source string
+	}{
+		{r.Header.Get("Authorization"), "authorization"},
+		{r.Header.Get("X-Api-Key"), "x-api-key"},
+		{r.Header.Get("X-Goog-Api-Key"), "x-goog-api-key"},
+	}
+	for _, candidate := range candidates {
+		value := strings.TrimSpace(candidate.value)
+		if candidate.source == "authorization" {
+			parts := strings.SplitN(value, " ", 2)
+			if len(parts) != 2 || !strings.EqualFold(parts[0], "bearer") {
+				continue
+			}
+			value = strings.TrimSpace(parts[1])
+		}
+		if strings.Count(value, ".") == 2 && strings.HasPrefix(value, "eyJ") {
+			return value, candidate.source
+		}
+	}
+	return "", ""
+}
+
+// verify checks the token signature, issuer, audience and validity window, returning its claims.
+func (p *provider) verify(ctx context.Context, token string) (map[string]any, error) {
+	parts := strings.Split(token, ".")
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("decode header: %w", err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("decode signature: %w", err)
+	}
+	keys, err := p.keys.lookup(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	signed := []byte(parts[0] + "." + parts[1])
+	verified := false
+	for _, key := range keys {
+		if key.alg != "" && key.alg != header.Alg {
+			continue
+		}
+		if verifySignature(header.Alg, key.key, signed, signature) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, fmt.Errorf("signature verification failed (alg %q, kid %q)", header.Alg, header.Kid)
+	}
+
+	var claims map[string]any
+	if err = decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("decode claims: %w", err)
+	}
+	if iss := claimString(claims["iss"]); iss != p.issuer {
+		return nil, fmt.Errorf("unexpected issuer %q", iss)
+	}
+	if !p.audienceAllowed(claims["aud"]) {
+		return nil, fmt.Errorf("unexpected audience")
+	}
+	now := p.now()
+	exp, ok := claimTime(claims["exp"])
+	if !ok {
+		return nil, fmt.Errorf("missing exp claim")
+	}
+	if now.After(exp.Add(p.leeway)) {
+		return nil, fmt.Errorf("token expired at %s", exp.Format(time.RFC3339))
+	}
+	if nbf, ok := claimTime(claims["nbf"]); ok && now.Add(p.leeway).Before(nbf) {
+		return nil, fmt.Errorf("token not valid before %s", nbf.Format(time.RFC3339))
+	}
+	return claims, nil
+}
+
+func (p *provider) audienceAllowed(value any) bool {
+	var audiences []string
+	switch v := value.(type) {
+	case string:
+		audiences = []string{v}
+	case []any:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				audiences = append(audiences, s)
+			}
+		}
+	}
+	for _, aud := range audiences {
+		for _, allowed := range p.audiences {
+			if aud == allowed {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// verifySignature checks a JWS signature for the RS*, PS* and ES* algorithm families.
+func verifySignature(alg string, key crypto.PublicKey, signed, signature []byte) bool {
+	if len(alg) != 5 {
+		return false
+	}
+	var hash crypto.Hash
+	switch alg[len(alg)-3:] {
+	case "256":
+		hash = crypto.SHA256
+	case "384":
+		hash = crypto.SHA384
+	case "512":
+		hash = crypto.SHA512
+	default:
+		return false
+	}
+	h := hash.New()
+	h.Write(signed)
+	digest := h.Sum(nil)
+
+	switch {
+	case strings.HasPrefix(alg, "RS"):
+		pub, ok := key.(*rsa.PublicKey)
+		return ok && rsa.VerifyPKCS1v15(pub, hash, digest, signature) == nil
+	case strings.HasPrefix(alg, "PS"):
+		pub, ok := key.(*rsa.PublicKey)
+		return ok && rsa.VerifyPSS(pub, hash, digest, signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}) == nil
+	case strings.HasPrefix(alg, "ES"):
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return false
+		}
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return false
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		return ecdsa.Verify(pub, digest, r, s)
+	default:
+		return false
+	}
+}
+
+func decodeSegment(segment string, out any) error {
+	raw, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, out)
+}
+
+func claimString(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return ""
+		}
+		return string(raw)
+	}
+}
+
+func claimTime(value any) (time.Time, bool) {
+	seconds, ok := value.(float64)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(seconds), 0), true
+}
+
+func optionString(options map[string]any, key string) string {
+	value, _ := options[key].(string)
+	return strings.TrimSpace(value)
+}
+
+func optionStrings(options map[string]any, key string) []string {
+	switch v := options[key].(type) {
+	case string:
+		if trimmed := strings.TrimSpace(v); trimmed != "" {
+			return []string{trimmed}
+		}
+	case []string:
+		return v
+	case []any:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok && strings.TrimSpace(s) != "" {
+				out = append(out, strings.TrimSpace(s))
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func optionInt(options map[string]any, key string) (int, bool) {
+	switch v := options[key].(type) {
+	case int:
+		return v, true
+	case int64:
+		return int(v), true
+	case float64:
+		return int(v), true
+	}
+	return 0, false
+}
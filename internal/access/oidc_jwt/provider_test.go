@@ -0,0 +1,160 @@
+package oidcjwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	sdkaccess "github.com/router-for-me/CLIProxyAPI/v6/sdk/access"
+)
+
+func b64(data []byte) string { return base64.RawURLEncoding.EncodeToString(data) }
+
+func signRS256(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]any) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := b64(header) + "." + b64(payload)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+	return signed + "." + b64(signature)
+}
+
+func writeRSAJWKS(t *testing.T, key *rsa.PrivateKey, kid string) string {
+	t.Helper()
+	doc, _ := json.Marshal(map[string]any{"keys": []map[string]string{{
+		"kty": "RSA", "kid": kid, "use": "sig", "alg": "RS256",
+		"n": b64(key.N.Bytes()), "e": b64(big.NewInt(int64(key.E)).Bytes()),
+	}}})
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, doc, 0o600); err != nil {
+		t.Fatalf("write jwks: %v", err)
+	}
+	return path
+}
+
+func bearerRequest(token string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	return req
+}
+
+func TestProviderAuthenticateRS256(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	p, err := newProvider("sso", map[string]any{
+		"issuer":          "https://sso.example.com",
+		"audience":        []any{"cliproxy"},
+		"jwks-file":       writeRSAJWKS(t, key, "k1"),
+		"principal-claim": "email",
+		"metadata-claims": []any{"groups"},
+	})
+	if err != nil {
+		t.Fatalf("newProvider() error = %v", err)
+	}
+	valid := map[string]any{
+		"iss": "https://sso.example.com", "aud": "cliproxy", "sub": "u-1",
+		"email": "dev@example.com", "groups": []string{"team-a"}, "exp": time.Now().Add(time.Hour).Unix(),
+	}
+
+	result, authErr := p.Authenticate(context.Background(), bearerRequest(signRS256(t, key, "k1", valid)))
+	if authErr != nil {
+		t.Fatalf("Authenticate() error = %v", authErr)
+	}
+	if result.Principal != "dev@example.com" || result.Metadata["subject"] != "u-1" || result.Metadata["groups"] != `["team-a"]` {
+		t.Fatalf("Authenticate() result = %+v", result)
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(map[string]any)
+	}{
+		{"wrong issuer", func(c map[string]any) { c["iss"] = "https://evil.example.com" }},
+		{"wrong audience", func(c map[string]any) { c["aud"] = []string{"other"} }},
+		{"expired", func(c map[string]any) { c["exp"] = time.Now().Add(-time.Hour).Unix() }},
+		{"missing principal", func(c map[string]any) { delete(c, "email") }},
+	}
+	for _, tc := range tests {
+		claims := make(map[string]any, len(valid))
+		for k, v := range valid {
+			claims[k] = v
+		}
+		tc.mutate(claims)
+		if _, authErr = p.Authenticate(context.Background(), bearerRequest(signRS256(t, key, "k1", claims))); !sdkaccess.IsAuthErrorCode(authErr, sdkaccess.AuthErrorCodeInvalidCredential) {
+			t.Fatalf("%s: Authenticate() error = %v, want invalid credential", tc.name, authErr)
+		}
+	}
+
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	if _, authErr = p.Authenticate(context.Background(), bearerRequest(signRS256(t, otherKey, "k1", valid))); !sdkaccess.IsAuthErrorCode(authErr, sdkaccess.AuthErrorCodeInvalidCredential) {
+		t.Fatalf("forged signature: Authenticate() error = %v, want invalid credential", authErr)
+	}
+	if _, authErr = p.Authenticate(context.Background(), bearerRequest("sk-static-key")); !sdkaccess.IsAuthErrorCode(authErr, sdkaccess.AuthErrorCodeNotHandled) {
+		t.Fatalf("static key: Authenticate() error = %v, want not handled", authErr)
+	}
+}
+
+func TestProviderAuthenticateES256FromURL(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "EC", "crv": "P-256", "kid": "ec1",
+			"x": b64(key.X.FillBytes(make([]byte, 32))), "y": b64(key.Y.FillBytes(make([]byte, 32))),
+		}}})
+	}))
+	defer server.Close()
+
+	p, err := newProvider("sso", map[string]any{"issuer": "iss", "audience": "aud", "jwks-url": server.URL})
+	if err != nil {
+		t.Fatalf("newProvider() error = %v", err)
+	}
+	header, _ := json.Marshal(map[string]string{"alg": "ES256", "kid": "ec1"})
+	payload, _ := json.Marshal(map[string]any{"iss": "iss", "aud": "aud", "sub": "svc", "exp": time.Now().Add(time.Minute).Unix()})
+	signed := b64(header) + "." + b64(payload)
+	digest := sha256.Sum256([]byte(signed))
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+	signature := append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", nil)
+	req.Header.Set("X-Api-Key", signed+"."+b64(signature))
+	result, authErr := p.Authenticate(context.Background(), req)
+	if authErr != nil {
+		t.Fatalf("Authenticate() error = %v", authErr)
+	}
+	if result.Principal != "svc" || result.Metadata["source"] != "x-api-key" {
+		t.Fatalf("Authenticate() result = %+v", result)
+	}
+}
+
+func TestNewProviderValidatesOptions(t *testing.T) {
+	if _, err := newProvider("sso", map[string]any{"issuer": "iss", "audience": "aud"}); err == nil {
+		t.Fatal("newProvider() without JWKS source succeeded")
+	}
+	if _, err := newProvider("sso", map[string]any{"audience": "aud", "jwks-file": "x"}); err == nil {
+		t.Fatal("newProvider() without issuer succeeded")
+	}
+}
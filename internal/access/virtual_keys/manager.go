@@ -0,0 +1,441 @@
+// Package virtualkeys implements managed client API keys. The proxy generates each key,
+// stores only its SHA-256 hash in the token store, and tracks ownership, expiry and usage.
+// Keys authenticate requests through the "virtual-key" access provider.
+package virtualkeys
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	sdkaccess "github.com/router-for-me/CLIProxyAPI/v6/sdk/access"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// ProviderType identifies the virtual key provider in the access registry.
+	ProviderType = "virtual-key"
+
+	// SecretPrefix starts every generated secret so the provider can recognise them.
+	SecretPrefix = "sk-vk-"
+
+	idPrefix = "vk_"
+
+	// lastUsedFlushInterval bounds how often last-used timestamps alone trigger a save.
+	lastUsedFlushInterval = 5 * time.Minute
+)
+
+// ErrNotFound is returned when no key has the requested ID.
+var ErrNotFound = errors.New("virtual key not found")
+
+// Store persists the virtual key document. Token stores implement it so keys live next to
+// the credentials they protect (file, Postgres, git or object storage).
+type Store interface {
+	LoadVirtualKeys(ctx context.Context) ([]byte, error)
+	SaveVirtualKeys(ctx context.Context, data []byte) error
+}
+
+// Key describes a managed client API key. The secret itself is never stored.
+type Key struct {
+	ID         string            `json:"id"`
+	Name       string            `json:"name"`
+	Owner      string            `json:"owner,omitempty"`
+	Hint       string            `json:"hint"`
+	Hash       string            `json:"hash,omitempty"`
+	Enabled    bool              `json:"enabled"`
+	CreatedAt  time.Time         `json:"created-at"`
+	ExpiresAt  *time.Time        `json:"expires-at,omitempty"`
+	LastUsedAt *time.Time        `json:"last-used-at,omitempty"`
+	RotatedAt  *time.Time        `json:"rotated-at,omitempty"`
+	Metadata   map[string]string `json:"metadata,omitempty"`
+}
+
+// Expired reports whether the key's expiry has passed at now.
+func (k *Key) Expired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
+
+// Public returns a copy of the key safe to show to management clients.
+func (k *Key) Public() Key {
+	out := *k
+	out.Hash = ""
+	if len(k.Metadata) > 0 {
+		out.Metadata = make(map[string]string, len(k.Metadata))
+		for name, value := range k.Metadata {
+			out.Metadata[name] = value
+		}
+	}
+	return out
+}
+
+// Spec holds the caller-supplied attributes of a new key.
+type Spec struct {
+	Name      string
+	Owner     string
+	ExpiresAt *time.Time
+	Metadata  map[string]string
+}
+
+// Update lists the attributes to change on an existing key; nil fields are left unchanged.
+// A zero ExpiresAt clears the expiry.
+type Update struct {
+	Name      *string
+	Owner     *string
+	Enabled   *bool
+	ExpiresAt *time.Time
+	Metadata  *map[string]string
+}
+
+type document struct {
+	Version int    `json:"version"`
+	Keys    []*Key `json:"keys"`
+}
+
+var defaultManager = NewManager()
+
+// Default returns the process-wide virtual key manager.
+func Default() *Manager { return defaultManager }
+
+// Manager owns the virtual keys, authenticates secrets and persists changes to the store.
+type Manager struct {
+	mu       sync.Mutex
+	store    Store
+	keys     map[string]*Key
+	byHash   map[string]*Key
+	onChange func()
+	now      func() time.Time
+
+	dirty      bool
+	flushTimer *time.Timer
+	writeMu    sync.Mutex
+}
+
+// NewManager returns an empty manager without a backing store.
+func NewManager() *Manager {
+	return &Manager{
+		keys:   make(map[string]*Key),
+		byHash: make(map[string]*Key),
+		now:    time.Now,
+	}
+}
+
+// SetOnChange registers a callback invoked after the provider registration changes, so the
+// caller can refresh its access manager.
+func (m *Manager) SetOnChange(fn func()) {
+	m.mu.Lock()
+	m.onChange = fn
+	m.mu.Unlock()
+}
+
+// Load attaches store and replaces the in-memory keys with the persisted ones. Stores that do
+// not implement Store leave the manager empty and key management disabled.
+func (m *Manager) Load(ctx context.Context, store any) error {
+	keyStore, ok := store.(Store)
+	m.mu.Lock()
+	if !ok {
+		m.store = nil
+		m.mu.Unlock()
+		return nil
+	}
+	m.store = keyStore
+	m.mu.Unlock()
+
+	data, err := keyStore.LoadVirtualKeys(ctx)
+	if err != nil {
+		return fmt.Errorf("load virtual keys: %w", err)
+	}
+	var doc document
+	if len(data) > 0 {
+		if err = json.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("decode virtual keys: %w", err)
+		}
+	}
+	m.mu.Lock()
+	m.keys = make(map[string]*Key, len(doc.Keys))
+	m.byHash = make(map[string]*Key, len(doc.Keys))
+	for _, key := range doc.Keys {
+		if key == nil || key.ID == "" || key.Hash == "" {
+			continue
+		}
+		m.keys[key.ID] = key
+		m.byHash[key.Hash] = key
+	}
+	m.mu.Unlock()
+	m.syncRegistration()
+	return nil
+}
+
+// Enabled reports whether a store supporting virtual keys is attached.
+func (m *Manager) Enabled() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.store != nil
+}
+
+// List returns every key, without hashes, ordered by creation time.
+func (m *Manager) List() []Key {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]Key, 0, len(m.keys))
+	for _, key := range m.keys {
+		out = append(out, key.Public())
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].ID < out[j].ID
+		}
+		return out[i].CreatedAt.Before(out[j].CreatedAt)
+	})
+	return out
+}
+
+// Create generates a new enabled key and returns it with its secret, which is not
+// recoverable afterwards.
+func (m *Manager) Create(ctx context.Context, spec Spec) (Key, string, error) {
+	name := strings.TrimSpace(spec.Name)
+	if name == "" {
+		return Key{}, "", fmt.Errorf("name is required")
+	}
+	idBytes := make([]byte, 8)
+	if _, err := rand.Read(idBytes); err != nil {
+		return Key{}, "", fmt.Errorf("generate virtual key id: %w", err)
+	}
+	secret, hash, err := newSecret()
+	if err != nil {
+		return Key{}, "", err
+	}
+	key := &Key{
+		ID:        idPrefix + hex.EncodeToString(idBytes),
+		Name:      name,
+		Owner:     strings.TrimSpace(spec.Owner),
+		Hint:      hint(secret),
+		Hash:      hash,
+		Enabled:   true,
+		CreatedAt: m.now().UTC(),
+		ExpiresAt: spec.ExpiresAt,
+		Metadata:  spec.Metadata,
+	}
+	m.mu.Lock()
+	if m.store == nil {
+		m.mu.Unlock()
+		return Key{}, "", fmt.Errorf("token store does not support virtual keys")
+	}
+	m.keys[key.ID] = key
+	m.byHash[key.Hash] = key
+	public := key.Public()
+	m.mu.Unlock()
+	if err = m.save(ctx); err != nil {
+		m.mu.Lock()
+		delete(m.keys, key.ID)
+		delete(m.byHash, key.Hash)
+		m.mu.Unlock()
+		return Key{}, "", err
+	}
+	m.syncRegistration()
+	return public, secret, nil
+}
+
+// Rotate replaces the secret of key id, invalidating the previous one immediately.
+func (m *Manager) Rotate(ctx context.Context, id string) (Key, string, error) {
+	secret, hash, err := newSecret()
+	if err != nil {
+		return Key{}, "", err
+	}
+	m.mu.Lock()
+	key, ok := m.keys[id]
+	if !ok {
+		m.mu.Unlock()
+		return Key{}, "", ErrNotFound
+	}
+	delete(m.byHash, key.Hash)
+	now := m.now().UTC()
+	key.Hash = hash
+	key.Hint = hint(secret)
+	key.RotatedAt = &now
+	m.byHash[hash] = key
+	public := key.Public()
+	m.mu.Unlock()
+	if err = m.save(ctx); err != nil {
+		return Key{}, "", err
+	}
+	return public, secret, nil
+}
+
+// Modify applies update to key id.
+func (m *Manager) Modify(ctx context.Context, id string, update Update) (Key, error) {
+	m.mu.Lock()
+	key, ok := m.keys[id]
+	if !ok {
+		m.mu.Unlock()
+		return Key{}, ErrNotFound
+	}
+	if update.Name != nil {
+		if name := strings.TrimSpace(*update.Name); name != "" {
+			key.Name = name
+		}
+	}
+	if update.Owner != nil {
+		key.Owner = strings.TrimSpace(*update.Owner)
+	}
+	if update.Enabled != nil {
+		key.Enabled = *update.Enabled
+	}
+	if update.ExpiresAt != nil {
+		if update.ExpiresAt.IsZero() {
+			key.ExpiresAt = nil
+		} else {
+			expiresAt := update.ExpiresAt.UTC()
+			key.ExpiresAt = &expiresAt
+		}
+	}
+	if update.Metadata != nil {
+		key.Metadata = *update.Metadata
+	}
+	public := key.Public()
+	m.mu.Unlock()
+	return public, m.save(ctx)
+}
+
+// Revoke deletes key id permanently.
+func (m *Manager) Revoke(ctx context.Context, id string) error {
+	m.mu.Lock()
+	key, ok := m.keys[id]
+	if !ok {
+		m.mu.Unlock()
+		return ErrNotFound
+	}
+	delete(m.keys, id)
+	delete(m.byHash, key.Hash)
+	m.mu.Unlock()
+	if err := m.save(ctx); err != nil {
+		return err
+	}
+	m.syncRegistration()
+	return nil
+}
+
+// Authenticate returns the enabled, unexpired key whose secret is presented and records
+// its last use.
+func (m *Manager) Authenticate(secret string) (Key, bool) {
+	if !strings.HasPrefix(secret, SecretPrefix) {
+		return Key{}, false
+	}
+	hash := hashSecret(secret)
+	m.mu.Lock()
+	key, ok := m.byHash[hash]
+	now := m.now().UTC()
+	if !ok || !key.Enabled || key.Expired(now) {
+		m.mu.Unlock()
+		return Key{}, false
+	}
+	key.LastUsedAt = &now
+	m.dirty = true
+	if m.flushTimer == nil {
+		m.flushTimer = time.AfterFunc(lastUsedFlushInterval, func() {
+			if err := m.Flush(context.Background()); err != nil {
+				log.Warnf("virtual keys: failed to save last-used timestamps: %v", err)
+			}
+		})
+	}
+	public := key.Public()
+	m.mu.Unlock()
+	return public, true
+}
+
+// Flush persists pending last-used timestamps.
+func (m *Manager) Flush(ctx context.Context) error {
+	m.mu.Lock()
+	if m.flushTimer != nil {
+		m.flushTimer.Stop()
+		m.flushTimer = nil
+	}
+	dirty := m.dirty
+	m.mu.Unlock()
+	if !dirty {
+		return nil
+	}
+	return m.save(ctx)
+}
+
+// save writes every key to the store.
+func (m *Manager) save(ctx context.Context) error {
+	m.writeMu.Lock()
+	defer m.writeMu.Unlock()
+	m.mu.Lock()
+	store := m.store
+	doc := document{Version: 1, Keys: make([]*Key, 0, len(m.keys))}
+	for _, key := range m.keys {
+		clone := *key
+		doc.Keys = append(doc.Keys, &clone)
+	}
+	m.dirty = false
+	m.mu.Unlock()
+	if store == nil {
+		return fmt.Errorf("token store does not support virtual keys")
+	}
+	sort.Slice(doc.Keys, func(i, j int) bool { return doc.Keys[i].ID < doc.Keys[j].ID })
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("encode virtual keys: %w", err)
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if err = store.SaveVirtualKeys(ctx, data); err != nil {
+		return fmt.Errorf("save virtual keys: %w", err)
+	}
+	return nil
+}
+
+// syncRegistration registers the access provider while keys exist and removes it otherwise,
+// so a proxy without any client keys keeps accepting unauthenticated requests.
+func (m *Manager) syncRegistration() {
+	m.mu.Lock()
+	hasKeys := len(m.keys) > 0
+	onChange := m.onChange
+	m.mu.Unlock()
+	if hasKeys {
+		sdkaccess.RegisterProvider(ProviderType, &provider{manager: m})
+	} else {
+		sdkaccess.UnregisterProvider(ProviderType)
+	}
+	if onChange != nil {
+		onChange()
+	}
+}
+
+func newSecret() (string, string, error) {
+	token, err := randomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+	secret := SecretPrefix + token
+	return secret, hashSecret(secret), nil
+}
+
+func randomToken(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate virtual key: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// hint keeps enough of a secret for humans to tell keys apart.
+func hint(secret string) string {
+	return secret[:len(SecretPrefix)+4] + "..." + secret[len(secret)-4:]
+}
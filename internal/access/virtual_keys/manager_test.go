@@ -0,0 +1,117 @@
+package virtualkeys
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	sdkaccess "github.com/router-for-me/CLIProxyAPI/v6/sdk/access"
+)
+
+type memoryStore struct {
+	data []byte
+}
+
+func (s *memoryStore) LoadVirtualKeys(context.Context) ([]byte, error) { return s.data, nil }
+
+func (s *memoryStore) SaveVirtualKeys(_ context.Context, data []byte) error {
+	s.data = append([]byte(nil), data...)
+	return nil
+}
+
+func TestManagerLifecycle(t *testing.T) {
+	ctx := context.Background()
+	store := &memoryStore{}
+	manager := NewManager()
+	if err := manager.Load(ctx, store); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	t.Cleanup(func() { sdkaccess.UnregisterProvider(ProviderType) })
+
+	key, secret, err := manager.Create(ctx, Spec{Name: "ci", Owner: "platform"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if !strings.HasPrefix(secret, SecretPrefix) || key.Hash != "" || strings.Contains(string(store.data), secret) {
+		t.Fatalf("Create() leaked the secret: key = %+v, stored = %s", key, store.data)
+	}
+	if got, ok := manager.Authenticate(secret); !ok || got.ID != key.ID || got.LastUsedAt == nil {
+		t.Fatalf("Authenticate() = %+v, %v", got, ok)
+	}
+
+	_, rotated, err := manager.Rotate(ctx, key.ID)
+	if err != nil {
+		t.Fatalf("Rotate() error = %v", err)
+	}
+	if _, ok := manager.Authenticate(secret); ok {
+		t.Fatal("old secret still valid after rotation")
+	}
+	if _, ok := manager.Authenticate(rotated); !ok {
+		t.Fatal("rotated secret rejected")
+	}
+
+	disabled := false
+	if _, err = manager.Modify(ctx, key.ID, Update{Enabled: &disabled}); err != nil {
+		t.Fatalf("Modify() error = %v", err)
+	}
+	if _, ok := manager.Authenticate(rotated); ok {
+		t.Fatal("disabled key accepted")
+	}
+	enabled, expired := true, time.Now().Add(-time.Minute)
+	if _, err = manager.Modify(ctx, key.ID, Update{Enabled: &enabled, ExpiresAt: &expired}); err != nil {
+		t.Fatalf("Modify() error = %v", err)
+	}
+	if _, ok := manager.Authenticate(rotated); ok {
+		t.Fatal("expired key accepted")
+	}
+
+	restored := NewManager()
+	if err = restored.Load(ctx, store); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if keys := restored.List(); len(keys) != 1 || keys[0].Owner != "platform" || keys[0].ExpiresAt == nil {
+		t.Fatalf("List() after reload = %+v", keys)
+	}
+
+	if err = restored.Revoke(ctx, key.ID); err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+	if err = restored.Revoke(ctx, key.ID); err != ErrNotFound {
+		t.Fatalf("second Revoke() error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestProviderAuthenticate(t *testing.T) {
+	ctx := context.Background()
+	manager := NewManager()
+	if err := manager.Load(ctx, &memoryStore{}); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	t.Cleanup(func() { sdkaccess.UnregisterProvider(ProviderType) })
+	key, secret, err := manager.Create(ctx, Spec{Name: "team-a"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	p := &provider{manager: manager}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", nil)
+	req.Header.Set("X-Api-Key", secret)
+	result, authErr := p.Authenticate(ctx, req)
+	if authErr != nil || result.Principal != key.ID || result.Metadata["key-name"] != "team-a" {
+		t.Fatalf("Authenticate() = %+v, %v", result, authErr)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	req.Header.Set("Authorization", "Bearer "+SecretPrefix+"unknown")
+	if _, authErr = p.Authenticate(ctx, req); !sdkaccess.IsAuthErrorCode(authErr, sdkaccess.AuthErrorCodeInvalidCredential) {
+		t.Fatalf("unknown secret: Authenticate() error = %v, want invalid credential", authErr)
+	}
+
+	req.Header.Set("Authorization", "Bearer static-key")
+	if _, authErr = p.Authenticate(ctx, req); !sdkaccess.IsAuthErrorCode(authErr, sdkaccess.AuthErrorCodeNotHandled) {
+		t.Fatalf("static key: Authenticate() error = %v, want not handled", authErr)
+	}
+}
@@ -0,0 +1,78 @@
+package virtualkeys
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	sdkaccess "github.com/router-for-me/CLIProxyAPI/v6/sdk/access"
+)
+
+// provider authenticates requests presenting a virtual key secret. The key ID becomes the
+// principal, so api-key-policies, budgets and usage records refer to keys by ID and survive
+// rotation.
+type provider struct {
+	manager *Manager
+}
+
+func (p *provider) Identifier() string { return ProviderType }
+
+func (p *provider) Authenticate(_ context.Context, r *http.Request) (*sdkaccess.Result, *sdkaccess.AuthError) {
+	if p == nil || p.manager == nil || r == nil {
+		return nil, sdkaccess.NewNotHandledError()
+	}
+	candidates := []struct {
+		value  string
+		source string
+	}{
+		{bearerToken(r.Header.Get("Authorization")), "authorization"},
+		{r.Header.Get("X-Goog-Api-Key"), "x-goog-api-key"},
+		{r.Header.Get("X-Api-Key"), "x-api-key"},
+	}
+	if r.URL != nil {
+		query := r.URL.Query()
+		candidates = append(candidates,
+			struct {
+				value  string
+				source string
+			}{query.Get("key"), "query-key"},
+			struct {
+				value  string
+				source string
+			}{query.Get("auth_token"), "query-auth-token"},
+		)
+	}
+	presented := false
+	for _, candidate := range candidates {
+		secret := strings.TrimSpace(candidate.value)
+		if !strings.HasPrefix(secret, SecretPrefix) {
+			continue
+		}
+		presented = true
+		key, ok := p.manager.Authenticate(secret)
+		if !ok {
+			continue
+		}
+		metadata := map[string]string{
+			"source":   candidate.source,
+			"key-id":   key.ID,
+			"key-name": key.Name,
+		}
+		if key.Owner != "" {
+			metadata["owner"] = key.Owner
+		}
+		return &sdkaccess.Result{Provider: ProviderType, Principal: key.ID, Metadata: metadata}, nil
+	}
+	if presented {
+		return nil, sdkaccess.NewInvalidCredentialError()
+	}
+	return nil, sdkaccess.NewNotHandledError()
+}
+
+func bearerToken(header string) string {
+	parts := strings.SplitN(strings.TrimSpace(header), " ", 2)
+	if len(parts) != 2 || !strings.EqualFold(parts[0], "bearer") {
+		return ""
+	}
+	return strings.TrimSpace(parts[1])
+}
@@ -0,0 +1,170 @@
+package management
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/audit"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/watcher/diff"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	defaultAuditQueryLimit = 200
+	maxAuditQueryLimit     = 5000
+)
+
+// auditAuthIDsKey is the gin context key under which mutating handlers list the auth IDs
+// they touched.
+const auditAuthIDsKey = "audit.auth_ids"
+
+// AuditMiddleware records every PUT, PATCH, DELETE and POST with the caller, the resulting
+// status, a redacted config diff and the auth IDs reported by the handler. It must run before
+// Middleware so rejected attempts are recorded too; the caller identity is read once the
+// chain returns.
+func (h *Handler) AuditMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodPost:
+		default:
+			c.Next()
+			return
+		}
+		logger := h.auditLog.Load()
+		if logger == nil {
+			c.Next()
+			return
+		}
+		before := h.configSnapshot()
+
+		c.Next()
+
+		entry := audit.Entry{
+			Timestamp: time.Now().UTC(),
+			IP:        c.ClientIP(),
+			Identity:  ManagementIdentity(c),
+			Role:      c.GetString(managementRoleKey),
+			Method:    c.Request.Method,
+			Path:      c.Request.URL.Path,
+			Status:    c.Writer.Status(),
+			AuthIDs:   auditAuthIDs(c),
+		}
+		if entry.Identity != "" && before != nil {
+			if after := h.configSnapshot(); after != nil {
+				entry.ConfigChanges = diff.BuildConfigChangeDetails(before, after)
+			}
+		}
+		if err := logger.Append(entry); err != nil {
+			log.Warnf("management audit: failed to record %s %s: %v", entry.Method, entry.Path, err)
+		}
+	}
+}
+
+// recordAuditAuthIDs adds ids to the auths the audit entry for c reports as changed.
+func recordAuditAuthIDs(c *gin.Context, ids ...string) {
+	if c == nil {
+		return
+	}
+	existing, _ := c.Get(auditAuthIDsKey)
+	list, _ := existing.([]string)
+	for _, id := range ids {
+		if id = strings.TrimSpace(id); id != "" {
+			list = append(list, id)
+		}
+	}
+	c.Set(auditAuthIDsKey, list)
+}
+
+// auditAuthIDs returns the sorted, de-duplicated auth IDs recorded for c.
+func auditAuthIDs(c *gin.Context) []string {
+	value, _ := c.Get(auditAuthIDsKey)
+	ids, _ := value.([]string)
+	if len(ids) == 0 {
+		return nil
+	}
+	ids = append([]string(nil), ids...)
+	sort.Strings(ids)
+	return slices.Compact(ids)
+}
+
+// GetAudit returns audit entries, newest first. Supports ?from= and ?to= (RFC 3339 or Unix
+// seconds), ?path= (endpoint prefix, with or without /v0/management), ?identity= and ?limit=.
+func (h *Handler) GetAudit(c *gin.Context) {
+	logger := h.auditLog.Load()
+	if logger == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "audit log not configured"})
+		return
+	}
+	filter := audit.Filter{Identity: strings.TrimSpace(c.Query("identity")), Limit: defaultAuditQueryLimit}
+	var err error
+	if filter.From, err = parseAuditTime(c.Query("from")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid from: %v", err)})
+		return
+	}
+	if filter.To, err = parseAuditTime(c.Query("to")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid to: %v", err)})
+		return
+	}
+	if path := strings.TrimSpace(c.Query("path")); path != "" {
+		if !strings.HasPrefix(path, managementRoutePrefix) {
+			path = managementRoutePrefix + "/" + strings.TrimPrefix(path, "/")
+		}
+		filter.Path = path
+	}
+	if raw := strings.TrimSpace(c.Query("limit")); raw != "" {
+		limit, errLimit := strconv.Atoi(raw)
+		if errLimit != nil || limit <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+		filter.Limit = min(limit, maxAuditQueryLimit)
+	}
+	entries, err := logger.Query(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"entries": entries})
+}
+
+func parseAuditTime(raw string) (time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	if seconds, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+	return time.Parse(time.RFC3339, raw)
+}
+
+// configSnapshot copies the handler's config under the lock config writers hold.
+func (h *Handler) configSnapshot() *config.Config {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return cloneConfig(h.cfg)
+}
+
+// cloneConfig deep-copies cfg so handlers that edit it in place can be diffed afterwards.
+func cloneConfig(cfg *config.Config) *config.Config {
+	if cfg == nil {
+		return nil
+	}
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return nil
+	}
+	var clone config.Config
+	if err = yaml.Unmarshal(data, &clone); err != nil {
+		return nil
+	}
+	return &clone
+}
@@ -0,0 +1,107 @@
+package management
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/audit"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/watcher/diff"
+)
+
+func TestAuditMiddlewareRecordsMutations(t *testing.T) {
+	t.Setenv("MANAGEMENT_PASSWORD", "")
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{RemoteManagement: config.RemoteManagement{
+		AllowRemote: true,
+		Keys:        []config.ManagementKey{{Name: "ops-admin", Key: "admin-key", Role: config.ManagementRoleAdmin}},
+	}}
+	h := NewHandler(cfg, "", nil)
+	h.SetLogDirectory(t.TempDir())
+
+	engine := gin.New()
+	mgmt := engine.Group("/v0/management")
+	mgmt.Use(h.AuditMiddleware(), h.Middleware())
+	mgmt.PUT("/debug", func(c *gin.Context) {
+		h.cfg.Debug = true
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	})
+	mgmt.PATCH("/auth-files/status", func(c *gin.Context) {
+		recordAuditAuthIDs(c, "b.json", "a.json", "b.json")
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	})
+	mgmt.GET("/usage", func(c *gin.Context) { c.Status(http.StatusOK) })
+	mgmt.GET("/audit", h.GetAudit)
+
+	doWithKey := func(method, target, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(`{"value":true}`))
+		req.Header.Set("Authorization", "Bearer "+key)
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, req)
+		return rec
+	}
+	do := func(method, target string) *httptest.ResponseRecorder {
+		return doWithKey(method, target, "admin-key")
+	}
+	do(http.MethodPut, "/v0/management/debug")
+	do(http.MethodGet, "/v0/management/usage")
+	doWithKey(http.MethodPut, "/v0/management/debug", "wrong-key")
+	do(http.MethodPatch, "/v0/management/auth-files/status")
+
+	rec := do(http.MethodGet, "/v0/management/audit?path=/debug")
+	var body struct {
+		Entries []audit.Entry `json:"entries"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode audit response: %v (%s)", err, rec.Body.String())
+	}
+	if len(body.Entries) != 2 {
+		t.Fatalf("entries = %+v, want the rejected and the accepted PUT /debug", body.Entries)
+	}
+	if rejected := body.Entries[0]; rejected.Identity != "" || rejected.Status != http.StatusUnauthorized || len(rejected.ConfigChanges) != 0 {
+		t.Fatalf("rejected entry = %+v", rejected)
+	}
+	entry := body.Entries[1]
+	if entry.Identity != "ops-admin" || entry.Method != http.MethodPut || entry.Status != http.StatusOK {
+		t.Fatalf("entry = %+v", entry)
+	}
+	if len(entry.ConfigChanges) != 1 || entry.ConfigChanges[0] != "debug: false -> true" {
+		t.Fatalf("config changes = %v, want [debug: false -> true]", entry.ConfigChanges)
+	}
+
+	rec = do(http.MethodGet, "/v0/management/audit?path=/auth-files")
+	body.Entries = nil
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode audit response: %v (%s)", err, rec.Body.String())
+	}
+	if len(body.Entries) != 1 || strings.Join(body.Entries[0].AuthIDs, ",") != "a.json,b.json" {
+		t.Fatalf("auth-files entries = %+v, want auth IDs [a.json b.json]", body.Entries)
+	}
+
+	rec = do(http.MethodGet, "/v0/management/audit?path=/usage")
+	if !strings.Contains(rec.Body.String(), `"entries":[]`) {
+		t.Fatalf("read-only request was audited: %s", rec.Body.String())
+	}
+	rec = do(http.MethodGet, "/v0/management/audit?from=2999-01-01T00:00:00Z")
+	if !strings.Contains(rec.Body.String(), `"entries":[]`) {
+		t.Fatalf("from filter ignored: %s", rec.Body.String())
+	}
+}
+
+func TestCloneConfigRoundTripsWithoutChanges(t *testing.T) {
+	cfg := &config.Config{
+		Port:             8317,
+		AuthDir:          "~/.cli-proxy-api",
+		RemoteManagement: config.RemoteManagement{SecretKey: "$2a$10$hash", Keys: []config.ManagementKey{{Name: "a", Key: "k", Role: "viewer"}}},
+		ModelFallbacks:   map[string][]string{"model-a": {"model-b"}},
+	}
+	cfg.APIKeys = []string{"key-1"}
+	cfg.APIKeyPolicies = []config.APIKeyPolicy{{APIKey: "key-1", BoundPrefixes: []string{"team-a"}, Budgets: []config.APIKeyBudget{{Period: "daily", MaxTokens: 10}}}}
+	if changes := diff.BuildConfigChangeDetails(cfg, cloneConfig(cfg)); len(changes) != 0 {
+		t.Fatalf("cloneConfig() introduced changes: %v", changes)
+	}
+}
@@ -396,6 +396,12 @@ func (h *Handler) buildAuthFileEntry(auth *coreauth.Auth) gin.H {
 			entry["account"] = account
 		}
 	}
+	if h.authManager != nil {
+		entry["in_flight"] = h.authManager.InFlight(auth.ID)
+	}
+	if maxConcurrency := auth.MaxConcurrency(); maxConcurrency > 0 {
+		entry["max_concurrency"] = maxConcurrency
+	}
 	if !auth.CreatedAt.IsZero() {
 		entry["created_at"] = auth.CreatedAt
 	}
@@ -559,6 +565,7 @@ func (h *Handler) UploadAuthFile(c *gin.Context) {
 			c.JSON(500, gin.H{"error": errReg.Error()})
 			return
 		}
+		recordAuditAuthIDs(c, h.authIDForPath(dst))
 		c.JSON(200, gin.H{"status": "ok"})
 		return
 	}
@@ -590,6 +597,7 @@ func (h *Handler) UploadAuthFile(c *gin.Context) {
 		c.JSON(500, gin.H{"error": err.Error()})
 		return
 	}
+	recordAuditAuthIDs(c, h.authIDForPath(dst))
 	c.JSON(200, gin.H{"status": "ok"})
 }
 
@@ -628,6 +636,7 @@ func (h *Handler) DeleteAuthFile(c *gin.Context) {
 				}
 				deleted++
 				h.disableAuth(ctx, full)
+				recordAuditAuthIDs(c, h.authIDForPath(full))
 			}
 		}
 		c.JSON(200, gin.H{"status": "ok", "deleted": deleted})
@@ -657,6 +666,7 @@ func (h *Handler) DeleteAuthFile(c *gin.Context) {
 		return
 	}
 	h.disableAuth(ctx, full)
+	recordAuditAuthIDs(c, h.authIDForPath(full))
 	c.JSON(200, gin.H{"status": "ok"})
 }
 
@@ -804,6 +814,7 @@ func (h *Handler) PatchAuthFileStatus(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to update auth: %v", err)})
 		return
 	}
+	recordAuditAuthIDs(c, targetAuth.ID)
 
 	c.JSON(http.StatusOK, gin.H{"status": "ok", "disabled": *req.Disabled})
 }
@@ -1882,6 +1893,7 @@ func (h *Handler) RequestIFlowCookieToken(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "error": "failed to save authentication tokens"})
 		return
 	}
+	recordAuditAuthIDs(c, record.ID)
 
 	fmt.Printf("iFlow cookie authentication successful. Token saved to %s\n", savedPath)
 	c.JSON(http.StatusOK, gin.H{
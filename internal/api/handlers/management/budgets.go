@@ -0,0 +1,25 @@
+package management
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/budget"
+)
+
+// GetBudgets returns the remaining budget of every client API key with budgets configured,
+// or of a single key when ?api-key= is given.
+func (h *Handler) GetBudgets(c *gin.Context) {
+	remaining := budget.Default().Remaining()
+	if key := strings.TrimSpace(c.Query("api-key")); key != "" {
+		statuses, ok := remaining[key]
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "no budgets configured for api key"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"api-key": key, "budgets": statuses})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"budgets": remaining})
+}
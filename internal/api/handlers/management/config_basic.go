@@ -13,6 +13,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
 	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
 	log "github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v3"
@@ -280,21 +281,9 @@ func (h *Handler) PutForceModelPrefix(c *gin.Context) {
 	h.updateBoolField(c, func(v bool) { h.cfg.ForceModelPrefix = v })
 }
 
-func normalizeRoutingStrategy(strategy string) (string, bool) {
-	normalized := strings.ToLower(strings.TrimSpace(strategy))
-	switch normalized {
-	case "", "round-robin", "roundrobin", "rr":
-		return "round-robin", true
-	case "fill-first", "fillfirst", "ff":
-		return "fill-first", true
-	default:
-		return "", false
-	}
-}
-
 // RoutingStrategy
 func (h *Handler) GetRoutingStrategy(c *gin.Context) {
-	strategy, ok := normalizeRoutingStrategy(h.cfg.Routing.Strategy)
+	strategy, ok := config.NormalizeRoutingStrategy(h.cfg.Routing.Strategy)
 	if !ok {
 		c.JSON(200, gin.H{"strategy": strings.TrimSpace(h.cfg.Routing.Strategy)})
 		return
@@ -309,7 +298,7 @@ func (h *Handler) PutRoutingStrategy(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
 		return
 	}
-	normalized, ok := normalizeRoutingStrategy(*body.Value)
+	normalized, ok := config.NormalizeRoutingStrategy(*body.Value)
 	if !ok {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid strategy"})
 		return
@@ -318,6 +307,42 @@ func (h *Handler) PutRoutingStrategy(c *gin.Context) {
 	h.persist(c)
 }
 
+// GetRoutingScores returns the per-auth scores learned by the adaptive routing strategy.
+func (h *Handler) GetRoutingScores(c *gin.Context) {
+	strategy, _ := config.NormalizeRoutingStrategy(h.cfg.Routing.Strategy)
+	if h.authManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "core auth manager unavailable"})
+		return
+	}
+	scores, ok := h.authManager.AdaptiveScores()
+	if !ok {
+		c.JSON(http.StatusOK, gin.H{"strategy": strategy, "active": false, "scores": []coreauth.AdaptiveScore{}})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"strategy": strategy, "active": true, "scores": scores})
+}
+
+// GetCircuitBreakers returns the current circuit breaker states and recent transitions.
+func (h *Handler) GetCircuitBreakers(c *gin.Context) {
+	if h.authManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "core auth manager unavailable"})
+		return
+	}
+	circuits := h.authManager.CircuitStates()
+	if circuits == nil {
+		circuits = []coreauth.CircuitSnapshot{}
+	}
+	events := h.authManager.CircuitEvents()
+	if events == nil {
+		events = []coreauth.Event{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":  h.authManager.CircuitBreakerEnabled(),
+		"circuits": circuits,
+		"events":   events,
+	})
+}
+
 // Proxy URL
 func (h *Handler) GetProxyURL(c *gin.Context) { c.JSON(200, gin.H{"proxy-url": h.cfg.ProxyURL}) }
 func (h *Handler) PutProxyURL(c *gin.Context) {
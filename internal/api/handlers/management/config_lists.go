@@ -118,6 +118,142 @@ func (h *Handler) DeleteAPIKeys(c *gin.Context) {
 	h.deleteFromStringList(c, &h.cfg.APIKeys, func() {})
 }
 
+// api-key-policies: []APIKeyPolicy
+func (h *Handler) GetAPIKeyPolicies(c *gin.Context) {
+	c.JSON(200, gin.H{"api-key-policies": h.cfg.APIKeyPolicies})
+}
+func (h *Handler) PutAPIKeyPolicies(c *gin.Context) {
+	data, err := c.GetRawData()
+	if err != nil {
+		c.JSON(400, gin.H{"error": "failed to read body"})
+		return
+	}
+	var arr []config.APIKeyPolicy
+	if err = json.Unmarshal(data, &arr); err != nil {
+		var obj struct {
+			Items []config.APIKeyPolicy `json:"items"`
+		}
+		if err2 := json.Unmarshal(data, &obj); err2 != nil || len(obj.Items) == 0 {
+			c.JSON(400, gin.H{"error": "invalid body"})
+			return
+		}
+		arr = obj.Items
+	}
+	h.cfg.APIKeyPolicies = append([]config.APIKeyPolicy(nil), arr...)
+	h.cfg.SanitizeAPIKeyPolicies()
+	h.persist(c)
+}
+func (h *Handler) PatchAPIKeyPolicy(c *gin.Context) {
+	type apiKeyPolicyPatch struct {
+		APIKey               *string                `json:"api-key"`
+		AllowedModels        *[]string              `json:"allowed-models"`
+		DeniedModels         *[]string              `json:"denied-models"`
+		AllowedProviders     *[]string              `json:"allowed-providers"`
+		AllowedPrefixes      *[]string              `json:"allowed-prefixes"`
+		BoundPrefixes        *[]string              `json:"bound-prefixes"`
+		RequestsPerMinute    *int                   `json:"requests-per-minute"`
+		TokensPerMinute      *int                   `json:"tokens-per-minute"`
+		MaxConcurrentStreams *int                   `json:"max-concurrent-streams"`
+		Budgets              *[]config.APIKeyBudget `json:"budgets"`
+	}
+	var body struct {
+		Index *int               `json:"index"`
+		Match *string            `json:"match"`
+		Value *apiKeyPolicyPatch `json:"value"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil || body.Value == nil {
+		c.JSON(400, gin.H{"error": "invalid body"})
+		return
+	}
+	targetIndex := -1
+	if body.Index != nil && *body.Index >= 0 && *body.Index < len(h.cfg.APIKeyPolicies) {
+		targetIndex = *body.Index
+	}
+	if targetIndex == -1 && body.Match != nil {
+		match := strings.TrimSpace(*body.Match)
+		if match != "" {
+			for i := range h.cfg.APIKeyPolicies {
+				if h.cfg.APIKeyPolicies[i].APIKey == match {
+					targetIndex = i
+					break
+				}
+			}
+		}
+	}
+	if targetIndex == -1 {
+		c.JSON(404, gin.H{"error": "item not found"})
+		return
+	}
+
+	entry := h.cfg.APIKeyPolicies[targetIndex]
+	if body.Value.APIKey != nil {
+		trimmed := strings.TrimSpace(*body.Value.APIKey)
+		if trimmed == "" {
+			h.cfg.APIKeyPolicies = append(h.cfg.APIKeyPolicies[:targetIndex], h.cfg.APIKeyPolicies[targetIndex+1:]...)
+			h.cfg.SanitizeAPIKeyPolicies()
+			h.persist(c)
+			return
+		}
+		entry.APIKey = trimmed
+	}
+	if body.Value.AllowedModels != nil {
+		entry.AllowedModels = *body.Value.AllowedModels
+	}
+	if body.Value.DeniedModels != nil {
+		entry.DeniedModels = *body.Value.DeniedModels
+	}
+	if body.Value.AllowedProviders != nil {
+		entry.AllowedProviders = *body.Value.AllowedProviders
+	}
+	if body.Value.AllowedPrefixes != nil {
+		entry.AllowedPrefixes = *body.Value.AllowedPrefixes
+	}
+	if body.Value.BoundPrefixes != nil {
+		entry.BoundPrefixes = *body.Value.BoundPrefixes
+	}
+	if body.Value.RequestsPerMinute != nil {
+		entry.RequestsPerMinute = *body.Value.RequestsPerMinute
+	}
+	if body.Value.TokensPerMinute != nil {
+		entry.TokensPerMinute = *body.Value.TokensPerMinute
+	}
+	if body.Value.MaxConcurrentStreams != nil {
+		entry.MaxConcurrentStreams = *body.Value.MaxConcurrentStreams
+	}
+	if body.Value.Budgets != nil {
+		entry.Budgets = *body.Value.Budgets
+	}
+	h.cfg.APIKeyPolicies[targetIndex] = entry
+	h.cfg.SanitizeAPIKeyPolicies()
+	h.persist(c)
+}
+func (h *Handler) DeleteAPIKeyPolicy(c *gin.Context) {
+	if val := strings.TrimSpace(c.Query("api-key")); val != "" {
+		out := make([]config.APIKeyPolicy, 0, len(h.cfg.APIKeyPolicies))
+		for _, v := range h.cfg.APIKeyPolicies {
+			if v.APIKey != val {
+				out = append(out, v)
+			}
+		}
+		if len(out) != len(h.cfg.APIKeyPolicies) {
+			h.cfg.APIKeyPolicies = out
+			h.persist(c)
+		} else {
+			c.JSON(404, gin.H{"error": "item not found"})
+		}
+		return
+	}
+	if idxStr := c.Query("index"); idxStr != "" {
+		var idx int
+		if _, err := fmt.Sscanf(idxStr, "%d", &idx); err == nil && idx >= 0 && idx < len(h.cfg.APIKeyPolicies) {
+			h.cfg.APIKeyPolicies = append(h.cfg.APIKeyPolicies[:idx], h.cfg.APIKeyPolicies[idx+1:]...)
+			h.persist(c)
+			return
+		}
+	}
+	c.JSON(400, gin.H{"error": "missing api-key or index"})
+}
+
 // gemini-api-key: []GeminiKey
 func (h *Handler) GetGeminiKeys(c *gin.Context) {
 	c.JSON(200, gin.H{"gemini-api-key": h.cfg.GeminiKey})
@@ -3,22 +3,23 @@
 package management
 
 import (
-	"crypto/subtle"
 	"fmt"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/audit"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/buildinfo"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/usage"
 	sdkAuth "github.com/router-for-me/CLIProxyAPI/v6/sdk/auth"
 	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
-	"golang.org/x/crypto/bcrypt"
+	log "github.com/sirupsen/logrus"
 )
 
 type attemptInfo struct {
@@ -47,6 +48,7 @@ type Handler struct {
 	allowRemoteOverride bool
 	envSecret           string
 	logDir              string
+	auditLog            atomic.Pointer[audit.Logger]
 }
 
 // NewHandler creates a new management handler instance.
@@ -104,7 +106,11 @@ func NewHandlerWithoutConfigFilePath(cfg *config.Config, manager *coreauth.Manag
 }
 
 // SetConfig updates the in-memory config reference when the server hot-reloads.
-func (h *Handler) SetConfig(cfg *config.Config) { h.cfg = cfg }
+func (h *Handler) SetConfig(cfg *config.Config) {
+	h.mu.Lock()
+	h.cfg = cfg
+	h.mu.Unlock()
+}
 
 // SetAuthManager updates the auth manager reference used by management endpoints.
 func (h *Handler) SetAuthManager(manager *coreauth.Manager) { h.authManager = manager }
@@ -116,6 +122,7 @@ func (h *Handler) SetUsageStatistics(stats *usage.RequestStatistics) { h.usageSt
 func (h *Handler) SetLocalPassword(password string) { h.localPassword = password }
 
 // SetLogDirectory updates the directory where main.log should be looked up.
+// The management audit log is written to the same directory.
 func (h *Handler) SetLogDirectory(dir string) {
 	if dir == "" {
 		return
@@ -126,6 +133,11 @@ func (h *Handler) SetLogDirectory(dir string) {
 		}
 	}
 	h.logDir = dir
+	if current := h.auditLog.Load(); current == nil || current.Dir() != dir {
+		if previous := h.auditLog.Swap(audit.NewLogger(dir)); previous != nil {
+			_ = previous.Close()
+		}
+	}
 }
 
 // Middleware enforces access control for management endpoints.
@@ -196,7 +208,7 @@ func (h *Handler) Middleware() gin.HandlerFunc {
 				h.attemptsMu.Unlock()
 			}
 		}
-		if secretHash == "" && envSecret == "" {
+		if secretHash == "" && envSecret == "" && (cfg == nil || len(cfg.RemoteManagement.Keys) == 0) {
 			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "remote management key not set"})
 			return
 		}
@@ -223,29 +235,8 @@ func (h *Handler) Middleware() gin.HandlerFunc {
 			return
 		}
 
-		if localClient {
-			if lp := h.localPassword; lp != "" {
-				if subtle.ConstantTimeCompare([]byte(provided), []byte(lp)) == 1 {
-					c.Next()
-					return
-				}
-			}
-		}
-
-		if envSecret != "" && subtle.ConstantTimeCompare([]byte(provided), []byte(envSecret)) == 1 {
-			if !localClient {
-				h.attemptsMu.Lock()
-				if ai := h.failedAttempts[clientIP]; ai != nil {
-					ai.count = 0
-					ai.blockedUntil = time.Time{}
-				}
-				h.attemptsMu.Unlock()
-			}
-			c.Next()
-			return
-		}
-
-		if secretHash == "" || bcrypt.CompareHashAndPassword([]byte(secretHash), []byte(provided)) != nil {
+		identity, role := h.managementCaller(provided, secretHash, localClient)
+		if identity == "" {
 			if !localClient {
 				fail()
 			}
@@ -262,6 +253,20 @@ func (h *Handler) Middleware() gin.HandlerFunc {
 			h.attemptsMu.Unlock()
 		}
 
+		entry := log.WithFields(log.Fields{"management_identity": identity, "management_role": role})
+		if required := requiredManagementRole(c.Request.Method, c.FullPath()); !config.ManagementRoleAllows(role, required) {
+			entry.Warnf("management %s %s denied: requires %s role", c.Request.Method, c.Request.URL.Path, required)
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("management role %s may not access this endpoint", role)})
+			return
+		}
+		c.Set(managementIdentityKey, identity)
+		c.Set(managementRoleKey, role)
+		if c.Request.Method == http.MethodGet {
+			entry.Debugf("management %s %s", c.Request.Method, c.Request.URL.Path)
+		} else {
+			entry.Infof("management %s %s", c.Request.Method, c.Request.URL.Path)
+		}
+
 		c.Next()
 	}
 }
@@ -0,0 +1,93 @@
+package management
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	managementIdentityKey = "managementIdentity"
+	managementRoleKey     = "managementRole"
+	managementRoutePrefix = "/v0/management"
+)
+
+// viewerRoutes are the read-only routes open to every management role.
+var viewerRoutes = map[string]struct{}{
+	"GET /usage":                      {},
+	"GET /usage/export":               {},
+	"GET /usage/query":                {},
+	"GET /logs":                       {},
+	"GET /request-error-logs":         {},
+	"GET /request-error-logs/:name":   {},
+	"GET /request-log-by-id/:id":      {},
+	"GET /auth-files":                 {},
+	"GET /auth-files/models":          {},
+	"GET /model-definitions/:channel": {},
+	"GET /budgets":                    {},
+	"GET /routing/scores":             {},
+	"GET /circuit-breakers":           {},
+	"GET /latest-version":             {},
+}
+
+// operatorRoutes toggle credentials and drive OAuth logins without changing configuration.
+var operatorRoutes = map[string]struct{}{
+	"PATCH /auth-files/status": {},
+	"POST /iflow-auth-url":     {},
+	"POST /oauth-callback":     {},
+	"GET /get-auth-status":     {},
+}
+
+// requiredManagementRole returns the least privileged role that may call method on the
+// management route pattern. Routes not listed require the admin role.
+func requiredManagementRole(method, route string) string {
+	key := method + " " + strings.TrimPrefix(route, managementRoutePrefix)
+	if _, ok := viewerRoutes[key]; ok {
+		return config.ManagementRoleViewer
+	}
+	if _, ok := operatorRoutes[key]; ok {
+		return config.ManagementRoleOperator
+	}
+	if method == http.MethodGet && strings.HasSuffix(route, "-auth-url") {
+		return config.ManagementRoleOperator
+	}
+	return config.ManagementRoleAdmin
+}
+
+// ManagementIdentity returns the name of the management key that authenticated c.
+func ManagementIdentity(c *gin.Context) string {
+	if c == nil {
+		return ""
+	}
+	return c.GetString(managementIdentityKey)
+}
+
+// managementCaller resolves the identity and role of a provided management key.
+// MANAGEMENT_PASSWORD, the local password and secret-key always act as admin.
+func (h *Handler) managementCaller(provided, secretHash string, localClient bool) (string, string) {
+	if localClient && h.localPassword != "" && constantTimeEqual(provided, h.localPassword) {
+		return "local-password", config.ManagementRoleAdmin
+	}
+	if h.envSecret != "" && constantTimeEqual(provided, h.envSecret) {
+		return "MANAGEMENT_PASSWORD", config.ManagementRoleAdmin
+	}
+	if secretHash != "" && bcrypt.CompareHashAndPassword([]byte(secretHash), []byte(provided)) == nil {
+		return "secret-key", config.ManagementRoleAdmin
+	}
+	if h.cfg != nil {
+		for _, key := range h.cfg.RemoteManagement.Keys {
+			if key.Matches(provided) {
+				return key.Name, key.Role
+			}
+		}
+	}
+	return "", ""
+}
+
+func constantTimeEqual(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
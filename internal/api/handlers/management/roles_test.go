@@ -0,0 +1,62 @@
+package management
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+)
+
+func TestMiddlewareEnforcesManagementRoles(t *testing.T) {
+	t.Setenv("MANAGEMENT_PASSWORD", "env-secret")
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{RemoteManagement: config.RemoteManagement{
+		AllowRemote: true,
+		Keys: []config.ManagementKey{
+			{Name: "dashboard", Key: "viewer-key", Role: config.ManagementRoleViewer},
+			{Name: "oncall", Key: "operator-key", Role: config.ManagementRoleOperator},
+		},
+	}}
+	h := NewHandler(cfg, "", nil)
+	engine := gin.New()
+	mgmt := engine.Group("/v0/management")
+	mgmt.Use(h.Middleware())
+	ok := func(c *gin.Context) { c.String(http.StatusOK, ManagementIdentity(c)) }
+	mgmt.GET("/usage", ok)
+	mgmt.PATCH("/auth-files/status", ok)
+	mgmt.GET("/codex-auth-url", ok)
+	mgmt.PUT("/config.yaml", ok)
+
+	tests := []struct {
+		key, method, path string
+		wantStatus        int
+	}{
+		{"viewer-key", http.MethodGet, "/usage", http.StatusOK},
+		{"viewer-key", http.MethodPatch, "/auth-files/status", http.StatusForbidden},
+		{"viewer-key", http.MethodGet, "/codex-auth-url", http.StatusForbidden},
+		{"operator-key", http.MethodPatch, "/auth-files/status", http.StatusOK},
+		{"operator-key", http.MethodGet, "/codex-auth-url", http.StatusOK},
+		{"operator-key", http.MethodPut, "/config.yaml", http.StatusForbidden},
+		{"env-secret", http.MethodPut, "/config.yaml", http.StatusOK},
+		{"wrong-key", http.MethodGet, "/usage", http.StatusUnauthorized},
+	}
+	for _, tc := range tests {
+		req := httptest.NewRequest(tc.method, "/v0/management"+tc.path, nil)
+		req.Header.Set("Authorization", "Bearer "+tc.key)
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, req)
+		if rec.Code != tc.wantStatus {
+			t.Fatalf("%s %s with %s: status = %d, want %d (%s)", tc.method, tc.path, tc.key, rec.Code, tc.wantStatus, rec.Body.String())
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/v0/management/usage", nil)
+	req.Header.Set("X-Management-Key", "operator-key")
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	if rec.Body.String() != "oncall" {
+		t.Fatalf("identity = %q, want oncall", rec.Body.String())
+	}
+}
@@ -1,8 +1,12 @@
 package management
 
 import (
+	"encoding/csv"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -77,3 +81,93 @@ func (h *Handler) ImportUsageStatistics(c *gin.Context) {
 		"failed_requests": snapshot.FailureCount,
 	})
 }
+
+// QueryUsageStatistics aggregates usage over a time range. Supports ?from= and ?to= (RFC 3339,
+// YYYY-MM-DD or Unix seconds; to is exclusive), ?group_by= (comma-separated dimensions),
+// one filter parameter per dimension (comma-separated values) and ?format=csv.
+func (h *Handler) QueryUsageStatistics(c *gin.Context) {
+	if h == nil || h.usageStats == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "usage statistics unavailable"})
+		return
+	}
+	var query usage.Query
+	var err error
+	if query.From, err = parseUsageTime(c.Query("from")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid from: %v", err)})
+		return
+	}
+	if query.To, err = parseUsageTime(c.Query("to")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid to: %v", err)})
+		return
+	}
+	query.GroupBy = splitQueryValues(c.QueryArray("group_by"))
+	for _, dim := range usage.QueryDimensions {
+		if values := splitQueryValues(c.QueryArray(dim)); len(values) > 0 {
+			if query.Filters == nil {
+				query.Filters = make(map[string][]string)
+			}
+			query.Filters[dim] = values
+		}
+	}
+	format := strings.ToLower(strings.TrimSpace(c.Query("format")))
+	if format == "" && strings.Contains(c.GetHeader("Accept"), "text/csv") {
+		format = "csv"
+	}
+	if format != "" && format != "csv" && format != "json" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv or json"})
+		return
+	}
+	if err = query.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.usageStats.Query(c.Request.Context(), query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if format != "csv" {
+		c.JSON(http.StatusOK, result)
+		return
+	}
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="usage.csv"`)
+	c.Status(http.StatusOK)
+	w := csv.NewWriter(c.Writer)
+	header := append(append([]string{}, result.GroupBy...),
+		"requests", "success_count", "failure_count", "input_tokens", "output_tokens", "reasoning_tokens", "cached_tokens", "total_tokens")
+	_ = w.Write(header)
+	for _, row := range result.Rows {
+		record := make([]string, 0, len(header))
+		for _, dim := range result.GroupBy {
+			record = append(record, row.Group[dim])
+		}
+		for _, v := range []int64{row.Requests, row.SuccessCount, row.FailureCount, row.InputTokens, row.OutputTokens, row.ReasoningTokens, row.CachedTokens, row.TotalTokens} {
+			record = append(record, strconv.FormatInt(v, 10))
+		}
+		_ = w.Write(record)
+	}
+	w.Flush()
+}
+
+func parseUsageTime(raw string) (time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	if t, err := time.Parse("2006-01-02", raw); err == nil {
+		return t, nil
+	}
+	return parseAuditTime(raw)
+}
+
+// splitQueryValues flattens repeated and comma-separated query values.
+func splitQueryValues(raw []string) []string {
+	var out []string
+	for _, item := range raw {
+		for _, value := range strings.Split(item, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				out = append(out, value)
+			}
+		}
+	}
+	return out
+}
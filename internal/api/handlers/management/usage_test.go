@@ -0,0 +1,44 @@
+package management
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/usage"
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+)
+
+func TestQueryUsageStatisticsCSV(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	stats := usage.NewRequestStatistics()
+	at := time.Date(2026, 3, 4, 15, 0, 0, 0, time.UTC)
+	stats.Record(context.Background(), coreusage.Record{APIKey: "team-a", Model: "gpt-5", RequestedAt: at,
+		Detail: coreusage.Detail{InputTokens: 10, OutputTokens: 5}})
+	stats.Record(context.Background(), coreusage.Record{APIKey: "team-b", Model: "gpt-5", RequestedAt: at.AddDate(0, 1, 0),
+		Detail: coreusage.Detail{InputTokens: 1}})
+	h := NewHandler(&config.Config{}, "", nil)
+	h.SetUsageStatistics(stats)
+
+	engine := gin.New()
+	engine.GET("/usage/query", h.QueryUsageStatistics)
+	do := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	rec := do("/usage/query?from=2026-03-01&to=2026-04-01&group_by=api_key,model&format=csv")
+	want := "api_key,model,requests,success_count,failure_count,input_tokens,output_tokens,reasoning_tokens,cached_tokens,total_tokens\n" +
+		"team-a,gpt-5,1,1,0,10,5,0,0,15\n"
+	if rec.Code != http.StatusOK || rec.Body.String() != want {
+		t.Fatalf("csv response %d:\n%s", rec.Code, rec.Body.String())
+	}
+	if rec = do("/usage/query?group_by=team"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("unknown dimension status = %d, want 400", rec.Code)
+	}
+}
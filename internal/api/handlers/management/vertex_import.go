@@ -106,6 +106,7 @@ func (h *Handler) ImportVertexCredential(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "save_failed", "message": err.Error()})
 		return
 	}
+	recordAuditAuthIDs(c, record.ID)
 
 	c.JSON(http.StatusOK, gin.H{
 		"status":     "ok",
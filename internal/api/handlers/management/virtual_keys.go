@@ -0,0 +1,136 @@
+package management
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	virtualkeys "github.com/router-for-me/CLIProxyAPI/v6/internal/access/virtual_keys"
+)
+
+// ListVirtualKeys returns every managed virtual key without secrets or hashes.
+func (h *Handler) ListVirtualKeys(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"virtual-keys": virtualkeys.Default().List()})
+}
+
+// CreateVirtualKey generates a new virtual key. The secret is only returned in this response.
+func (h *Handler) CreateVirtualKey(c *gin.Context) {
+	var body struct {
+		Name      string            `json:"name"`
+		Owner     string            `json:"owner"`
+		ExpiresAt *time.Time        `json:"expires-at"`
+		Metadata  map[string]string `json:"metadata"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil || strings.TrimSpace(body.Name) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body: name is required"})
+		return
+	}
+	if body.ExpiresAt != nil && !body.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expires-at must be in the future"})
+		return
+	}
+	if !virtualkeys.Default().Enabled() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "token store does not support virtual keys"})
+		return
+	}
+	key, secret, err := virtualkeys.Default().Create(c.Request.Context(), virtualkeys.Spec{
+		Name:      body.Name,
+		Owner:     body.Owner,
+		ExpiresAt: body.ExpiresAt,
+		Metadata:  body.Metadata,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"virtual-key": key, "secret": secret})
+}
+
+// RotateVirtualKey replaces the secret of the key given by ?id= or {"id"}. The previous
+// secret stops working immediately; the new one is only returned in this response.
+func (h *Handler) RotateVirtualKey(c *gin.Context) {
+	id := virtualKeyID(c)
+	if id == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing id"})
+		return
+	}
+	key, secret, err := virtualkeys.Default().Rotate(c.Request.Context(), id)
+	if err != nil {
+		writeVirtualKeyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"virtual-key": key, "secret": secret})
+}
+
+// PatchVirtualKey updates the name, owner, expiry, enabled flag or metadata of a key.
+// An empty expires-at string clears the expiry.
+func (h *Handler) PatchVirtualKey(c *gin.Context) {
+	var body struct {
+		ID        string             `json:"id"`
+		Name      *string            `json:"name"`
+		Owner     *string            `json:"owner"`
+		Enabled   *bool              `json:"enabled"`
+		ExpiresAt *string            `json:"expires-at"`
+		Metadata  *map[string]string `json:"metadata"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil || strings.TrimSpace(body.ID) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+	update := virtualkeys.Update{Name: body.Name, Owner: body.Owner, Enabled: body.Enabled, Metadata: body.Metadata}
+	if body.ExpiresAt != nil {
+		var expiresAt time.Time
+		if value := strings.TrimSpace(*body.ExpiresAt); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "expires-at must be RFC 3339"})
+				return
+			}
+			expiresAt = parsed
+		}
+		update.ExpiresAt = &expiresAt
+	}
+	key, err := virtualkeys.Default().Modify(c.Request.Context(), strings.TrimSpace(body.ID), update)
+	if err != nil {
+		writeVirtualKeyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"virtual-key": key})
+}
+
+// RevokeVirtualKey permanently deletes the key given by ?id=.
+func (h *Handler) RevokeVirtualKey(c *gin.Context) {
+	id := strings.TrimSpace(c.Query("id"))
+	if id == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing id"})
+		return
+	}
+	if err := virtualkeys.Default().Revoke(c.Request.Context(), id); err != nil {
+		writeVirtualKeyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+func virtualKeyID(c *gin.Context) string {
+	if id := strings.TrimSpace(c.Query("id")); id != "" {
+		return id
+	}
+	var body struct {
+		ID string `json:"id"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		return ""
+	}
+	return strings.TrimSpace(body.ID)
+}
+
+func writeVirtualKeyError(c *gin.Context, err error) {
+	if errors.Is(err, virtualkeys.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "item not found"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
@@ -0,0 +1,240 @@
+// Package middleware provides HTTP middleware components for the CLI Proxy API server.
+// This file contains the configurable CORS middleware that applies per-route-group
+// policies and answers browser preflight requests.
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+)
+
+// defaultCORSMethods mirrors the historical hardcoded method list.
+var defaultCORSMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+
+// corsPolicy is the compiled, request-time form of config.CORSPolicy.
+type corsPolicy struct {
+	disabled         bool
+	allowAll         bool
+	origins          map[string]struct{}
+	patterns         []string
+	methods          string
+	headers          string
+	echoHeaders      bool
+	exposed          string
+	allowCredentials bool
+	maxAge           string
+}
+
+type corsPolicySet struct {
+	fallback *corsPolicy
+	routes   map[string]*corsPolicy
+}
+
+// CORS applies configurable cross-origin rules and supports hot reloads.
+type CORS struct {
+	policies atomic.Pointer[corsPolicySet]
+}
+
+// NewCORS builds a CORS middleware holder from the provided configuration.
+func NewCORS(cfg config.CORSConfig) *CORS {
+	c := &CORS{}
+	c.Update(cfg)
+	return c
+}
+
+// Update swaps the active policies with the ones derived from cfg.
+func (c *CORS) Update(cfg config.CORSConfig) {
+	if c == nil {
+		return
+	}
+	set := &corsPolicySet{
+		fallback: compileCORSPolicy(cfg.CORSPolicy, true),
+		routes:   make(map[string]*corsPolicy, len(cfg.Routes)),
+	}
+	for group, policy := range cfg.Routes {
+		set.routes[group] = compileCORSPolicy(policy, false)
+	}
+	for _, group := range []string{config.CORSRouteManagement, config.CORSRouteAmp} {
+		if _, ok := set.routes[group]; !ok {
+			// Management and Amp routes only answer cross-origin requests when listed explicitly.
+			set.routes[group] = &corsPolicy{disabled: true}
+		}
+	}
+	c.policies.Store(set)
+}
+
+// Handler returns the Gin middleware that enforces the active CORS policies.
+func (c *CORS) Handler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		policy := c.policyFor(ctx.Request.URL.Path)
+		origin := ctx.GetHeader("Origin")
+		preflight := ctx.Request.Method == http.MethodOptions
+
+		if origin == "" || policy == nil || policy.disabled {
+			if preflight {
+				if origin != "" {
+					ctx.AbortWithStatus(http.StatusForbidden)
+					return
+				}
+				ctx.AbortWithStatus(http.StatusNoContent)
+				return
+			}
+			ctx.Next()
+			return
+		}
+
+		header := ctx.Writer.Header()
+		header.Add("Vary", "Origin")
+		if !policy.allowsOrigin(origin) {
+			if preflight {
+				ctx.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			ctx.Next()
+			return
+		}
+
+		header.Set("Access-Control-Allow-Origin", origin)
+		if policy.allowCredentials {
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if preflight {
+			header.Add("Vary", "Access-Control-Request-Method")
+			header.Add("Vary", "Access-Control-Request-Headers")
+			header.Set("Access-Control-Allow-Methods", policy.methods)
+			allowedHeaders := policy.headers
+			if policy.echoHeaders {
+				allowedHeaders = ctx.GetHeader("Access-Control-Request-Headers")
+			}
+			if allowedHeaders != "" {
+				header.Set("Access-Control-Allow-Headers", allowedHeaders)
+			}
+			if policy.maxAge != "" {
+				header.Set("Access-Control-Max-Age", policy.maxAge)
+			}
+			ctx.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		if policy.exposed != "" {
+			header.Set("Access-Control-Expose-Headers", policy.exposed)
+		}
+		ctx.Next()
+	}
+}
+
+func (c *CORS) policyFor(path string) *corsPolicy {
+	if c == nil {
+		return nil
+	}
+	set := c.policies.Load()
+	if set == nil {
+		return nil
+	}
+	if policy, ok := set.routes[CORSRouteGroup(path)]; ok {
+		return policy
+	}
+	return set.fallback
+}
+
+// CORSRouteGroup classifies a request path into a cors.routes group identifier.
+// It returns an empty string for paths outside the known groups.
+func CORSRouteGroup(path string) string {
+	switch {
+	case hasPathPrefix(path, "/v0/management"):
+		return config.CORSRouteManagement
+	case hasPathPrefix(path, "/v1beta"):
+		return config.CORSRouteGemini
+	case hasPathPrefix(path, "/v1"):
+		return config.CORSRouteOpenAI
+	case hasPathPrefix(path, "/api"),
+		hasPathPrefix(path, "/threads"),
+		hasPathPrefix(path, "/docs"),
+		hasPathPrefix(path, "/settings"),
+		hasPathPrefix(path, "/auth"),
+		path == "/threads.rss",
+		path == "/news.rss":
+		return config.CORSRouteAmp
+	default:
+		return ""
+	}
+}
+
+func hasPathPrefix(path, prefix string) bool {
+	return strings.HasPrefix(path, prefix) && (len(path) == len(prefix) || path[len(prefix)] == '/')
+}
+
+func compileCORSPolicy(policy config.CORSPolicy, legacyDefaults bool) *corsPolicy {
+	compiled := &corsPolicy{
+		disabled:         policy.Disable,
+		origins:          make(map[string]struct{}),
+		allowCredentials: policy.AllowCredentials,
+	}
+
+	origins := policy.AllowedOrigins
+	headers := policy.AllowedHeaders
+	if legacyDefaults && len(origins) == 0 {
+		// Preserve the historical allow-all behaviour when no origins are configured.
+		origins = []string{"*"}
+		if len(headers) == 0 {
+			headers = []string{"*"}
+		}
+	}
+	for _, origin := range origins {
+		lower := strings.ToLower(origin)
+		switch {
+		case lower == "*":
+			compiled.allowAll = true
+		case strings.Contains(lower, "*"):
+			compiled.patterns = append(compiled.patterns, lower)
+		default:
+			compiled.origins[lower] = struct{}{}
+		}
+	}
+
+	methods := policy.AllowedMethods
+	if len(methods) == 0 {
+		methods = defaultCORSMethods
+	}
+	compiled.methods = strings.Join(methods, ", ")
+
+	for _, h := range headers {
+		if h == "*" {
+			compiled.echoHeaders = true
+			break
+		}
+	}
+	if !compiled.echoHeaders {
+		compiled.headers = strings.Join(headers, ", ")
+	}
+	compiled.exposed = strings.Join(policy.ExposedHeaders, ", ")
+	if policy.MaxAge > 0 {
+		compiled.maxAge = strconv.Itoa(policy.MaxAge)
+	}
+	return compiled
+}
+
+func (p *corsPolicy) allowsOrigin(origin string) bool {
+	if p == nil {
+		return false
+	}
+	if p.allowAll {
+		return true
+	}
+	lower := strings.ToLower(strings.TrimRight(strings.TrimSpace(origin), "/"))
+	if _, ok := p.origins[lower]; ok {
+		return true
+	}
+	for _, pattern := range p.patterns {
+		if config.MatchWildcard(pattern, lower) {
+			return true
+		}
+	}
+	return false
+}
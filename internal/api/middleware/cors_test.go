@@ -0,0 +1,161 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+)
+
+func newCORSTestEngine(cfg config.CORSConfig) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(NewCORS(cfg).Handler())
+	engine.GET("/v1/models", func(c *gin.Context) { c.Status(http.StatusOK) })
+	engine.GET("/v0/management/config", func(c *gin.Context) { c.Status(http.StatusOK) })
+	engine.GET("/api/user", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return engine
+}
+
+func TestCORSPreflightEchoesAllowedOrigin(t *testing.T) {
+	engine := newCORSTestEngine(config.CORSConfig{
+		CORSPolicy: config.CORSPolicy{
+			AllowedOrigins: []string{"https://*.example.com"},
+			AllowedHeaders: []string{"*"},
+			MaxAge:         600,
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodOptions, "/v1/models", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	req.Header.Set("Access-Control-Request-Headers", "Authorization, Content-Type")
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Fatalf("Access-Control-Allow-Origin = %q", got)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Headers"); got != "Authorization, Content-Type" {
+		t.Fatalf("Access-Control-Allow-Headers = %q", got)
+	}
+	if got := rec.Header().Get("Access-Control-Max-Age"); got != "600" {
+		t.Fatalf("Access-Control-Max-Age = %q", got)
+	}
+}
+
+func TestCORSRejectsDisallowedOrigin(t *testing.T) {
+	engine := newCORSTestEngine(config.CORSConfig{
+		CORSPolicy: config.CORSPolicy{AllowedOrigins: []string{"https://app.example.com"}},
+	})
+
+	req := httptest.NewRequest(http.MethodOptions, "/v1/models", nil)
+	req.Header.Set("Origin", "https://evil.example.org")
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("preflight status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+	req.Header.Set("Origin", "https://evil.example.org")
+	rec = httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Fatalf("Access-Control-Allow-Origin = %q, want empty", got)
+	}
+}
+
+func TestCORSRouteOverride(t *testing.T) {
+	engine := newCORSTestEngine(config.CORSConfig{
+		Routes: map[string]config.CORSPolicy{
+			config.CORSRouteManagement: {AllowedOrigins: []string{"https://admin.example.com"}},
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+	req.Header.Set("Origin", "https://anything.example.net")
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://anything.example.net" {
+		t.Fatalf("default Access-Control-Allow-Origin = %q", got)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/v0/management/config", nil)
+	req.Header.Set("Origin", "https://anything.example.net")
+	rec = httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Fatalf("management Access-Control-Allow-Origin = %q, want empty", got)
+	}
+}
+
+func TestCORSManagementRequiresExplicitRoute(t *testing.T) {
+	engine := newCORSTestEngine(config.CORSConfig{})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+	req.Header.Set("Origin", "https://anything.example.net")
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://anything.example.net" {
+		t.Fatalf("default Access-Control-Allow-Origin = %q", got)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/v0/management/config", nil)
+	req.Header.Set("Origin", "https://anything.example.net")
+	rec = httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Fatalf("management Access-Control-Allow-Origin = %q, want empty", got)
+	}
+
+	req = httptest.NewRequest(http.MethodOptions, "/v0/management/config", nil)
+	req.Header.Set("Origin", "https://anything.example.net")
+	rec = httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("management preflight status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
+
+func TestCORSAmpRoutesFollowRoutePolicy(t *testing.T) {
+	engine := newCORSTestEngine(config.CORSConfig{})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/user", nil)
+	req.Header.Set("Origin", "https://anything.example.net")
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Fatalf("default amp Access-Control-Allow-Origin = %q, want empty", got)
+	}
+
+	engine = newCORSTestEngine(config.CORSConfig{
+		Routes: map[string]config.CORSPolicy{
+			config.CORSRouteAmp: {AllowedOrigins: []string{"https://ampcode.com"}},
+		},
+	})
+
+	req = httptest.NewRequest(http.MethodOptions, "/api/user", nil)
+	req.Header.Set("Origin", "https://ampcode.com")
+	rec = httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("amp preflight status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/user", nil)
+	req.Header.Set("Origin", "https://ampcode.com")
+	rec = httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://ampcode.com" {
+		t.Fatalf("amp Access-Control-Allow-Origin = %q", got)
+	}
+}
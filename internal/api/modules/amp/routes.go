@@ -92,26 +92,6 @@ func (m *AmpModule) localhostOnlyMiddleware() gin.HandlerFunc {
 	}
 }
 
-// noCORSMiddleware disables CORS for management routes to prevent browser-based attacks.
-// This overwrites any global CORS headers set by the server.
-func noCORSMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		// Remove CORS headers to prevent cross-origin access from browsers
-		c.Header("Access-Control-Allow-Origin", "")
-		c.Header("Access-Control-Allow-Methods", "")
-		c.Header("Access-Control-Allow-Headers", "")
-		c.Header("Access-Control-Allow-Credentials", "")
-
-		// For OPTIONS preflight, deny with 403
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(403)
-			return
-		}
-
-		c.Next()
-	}
-}
-
 // managementAvailabilityMiddleware short-circuits management routes when the upstream
 // proxy is disabled, preventing noisy localhost warnings and accidental exposure.
 func (m *AmpModule) managementAvailabilityMiddleware() gin.HandlerFunc {
@@ -148,8 +128,9 @@ func wrapManagementAuth(auth gin.HandlerFunc, prefixes ...string) gin.HandlerFun
 func (m *AmpModule) registerManagementRoutes(engine *gin.Engine, baseHandler *handlers.BaseAPIHandler, auth gin.HandlerFunc) {
 	ampAPI := engine.Group("/api")
 
-	// Always disable CORS for management routes to prevent browser-based attacks
-	ampAPI.Use(m.managementAvailabilityMiddleware(), noCORSMiddleware())
+	// CORS is decided by the global middleware's amp route group, which is disabled unless
+	// cors.routes.amp is configured, to prevent browser-based attacks.
+	ampAPI.Use(m.managementAvailabilityMiddleware())
 
 	// Apply dynamic localhost-only restriction (hot-reloadable via m.IsRestrictedToLocalhost())
 	ampAPI.Use(m.localhostOnlyMiddleware())
@@ -206,7 +187,7 @@ func (m *AmpModule) registerManagementRoutes(engine *gin.Engine, baseHandler *ha
 
 	// Root-level routes that AMP CLI expects without /api prefix
 	// These need the same security middleware as the /api/* routes (dynamic for hot-reload)
-	rootMiddleware := []gin.HandlerFunc{m.managementAvailabilityMiddleware(), noCORSMiddleware(), m.localhostOnlyMiddleware()}
+	rootMiddleware := []gin.HandlerFunc{m.managementAvailabilityMiddleware(), m.localhostOnlyMiddleware()}
 	if authWithBypass != nil {
 		rootMiddleware = append(rootMiddleware, authWithBypass)
 	}
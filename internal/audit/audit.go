@@ -0,0 +1,160 @@
+// Package audit records management API mutations as an append-only, rotating JSONL log.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+const (
+	// FileName is the active audit log inside the log directory. Rotated files keep the
+	// "audit-" prefix and ".jsonl" extension so the log directory cleaner leaves them alone.
+	FileName = "audit.jsonl"
+
+	maxFileSizeMB = 10
+)
+
+// Entry is a single audited management request.
+type Entry struct {
+	Timestamp     time.Time `json:"timestamp"`
+	IP            string    `json:"ip"`
+	Identity      string    `json:"identity"`
+	Role          string    `json:"role,omitempty"`
+	Method        string    `json:"method"`
+	Path          string    `json:"path"`
+	Status        int       `json:"status"`
+	ConfigChanges []string  `json:"config-changes,omitempty"`
+	AuthIDs       []string  `json:"auth-ids,omitempty"`
+}
+
+// Filter selects entries returned by Query. Zero values match everything.
+type Filter struct {
+	From     time.Time
+	To       time.Time
+	Path     string
+	Identity string
+	Limit    int
+}
+
+func (f Filter) matches(entry Entry) bool {
+	if !f.From.IsZero() && entry.Timestamp.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && entry.Timestamp.After(f.To) {
+		return false
+	}
+	if f.Path != "" && !strings.HasPrefix(entry.Path, f.Path) {
+		return false
+	}
+	return f.Identity == "" || entry.Identity == f.Identity
+}
+
+// Logger appends entries to FileName in a directory, rotating it by size.
+type Logger struct {
+	mu     sync.Mutex
+	dir    string
+	writer *lumberjack.Logger
+}
+
+// NewLogger returns a logger writing to FileName inside dir.
+func NewLogger(dir string) *Logger {
+	return &Logger{
+		dir: dir,
+		writer: &lumberjack.Logger{
+			Filename: filepath.Join(dir, FileName),
+			MaxSize:  maxFileSizeMB,
+		},
+	}
+}
+
+// Dir returns the directory the logger writes to.
+func (l *Logger) Dir() string {
+	if l == nil {
+		return ""
+	}
+	return l.dir
+}
+
+// Append writes entry as one JSON line.
+func (l *Logger) Append(entry Entry) error {
+	if l == nil {
+		return errors.New("audit: logger not configured")
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err = os.MkdirAll(l.dir, 0o755); err != nil {
+		return err
+	}
+	_, err = l.writer.Write(append(data, '\n'))
+	return err
+}
+
+// Query returns entries matching filter across the active and rotated files, newest first.
+func (l *Logger) Query(filter Filter) ([]Entry, error) {
+	if l == nil {
+		return nil, errors.New("audit: logger not configured")
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	files, err := filepath.Glob(filepath.Join(l.dir, "audit*.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]Entry, 0)
+	for _, file := range files {
+		if entries, err = readEntries(file, filter, entries); err != nil {
+			return nil, err
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Timestamp.After(entries[j].Timestamp) })
+	if filter.Limit > 0 && len(entries) > filter.Limit {
+		entries = entries[:filter.Limit]
+	}
+	return entries, nil
+}
+
+// Close closes the active file.
+func (l *Logger) Close() error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.writer.Close()
+}
+
+func readEntries(path string, filter Filter, out []Entry) ([]Entry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return out, nil
+		}
+		return out, err
+	}
+	defer func() { _ = file.Close() }()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var entry Entry
+		if json.Unmarshal(scanner.Bytes(), &entry) != nil {
+			continue
+		}
+		if filter.matches(entry) {
+			out = append(out, entry)
+		}
+	}
+	return out, scanner.Err()
+}
@@ -0,0 +1,530 @@
+// Package budget enforces per-client-key usage budgets. It accumulates requests, tokens and
+// estimated cost from usage records over daily, weekly and monthly periods, persists the
+// totals so they survive restarts, and notifies a webhook when budgets run low.
+package budget
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultSoftLimitPercent = 80
+	stateSaveDelay          = 2 * time.Second
+	webhookTimeout          = 10 * time.Second
+)
+
+// defaultStateFileName is the state file kept in the auth directory when no path is
+// configured. It has no .json suffix so token stores and the auth watcher ignore it.
+const defaultStateFileName = ".budget-state"
+
+// Budget metrics reported in Status.Metric.
+const (
+	MetricRequests = "requests"
+	MetricTokens   = "tokens"
+	MetricCost     = "cost"
+)
+
+// Webhook events.
+const (
+	EventSoftLimit = "budget.soft-limit"
+	EventExhausted = "budget.exhausted"
+)
+
+var defaultTracker = NewTracker()
+
+func init() {
+	coreusage.RegisterPlugin(defaultTracker)
+}
+
+// Default returns the process-wide tracker fed by usage records.
+func Default() *Tracker { return defaultTracker }
+
+// Status describes a key's usage against one budget cap in the current period.
+type Status struct {
+	Period    string    `json:"period"`
+	Metric    string    `json:"metric"`
+	Limit     float64   `json:"limit"`
+	Used      float64   `json:"used"`
+	Remaining float64   `json:"remaining"`
+	ResetsAt  time.Time `json:"resets-at"`
+}
+
+// Exhausted reports whether the cap has been reached.
+func (s Status) Exhausted() bool { return s.Used >= s.Limit }
+
+// periodUsage accumulates usage for one key within one period.
+type periodUsage struct {
+	Start    time.Time       `json:"start"`
+	Requests int64           `json:"requests"`
+	Tokens   int64           `json:"tokens"`
+	Cost     float64         `json:"cost"`
+	Notified map[string]bool `json:"notified,omitempty"`
+}
+
+// stateVersion 2 keys the state by stateKey; version 1 stored plaintext client keys.
+const stateVersion = 2
+
+type stateDocument struct {
+	Version int                                `json:"version"`
+	SavedAt time.Time                          `json:"saved-at"`
+	Keys    map[string]map[string]*periodUsage `json:"keys"`
+}
+
+// stateKey identifies a client key in memory and in the state file without storing it, since
+// the auth directory holding the file may be synced off-host.
+func stateKey(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// Tracker accumulates usage per client API key and evaluates it against configured budgets.
+type Tracker struct {
+	mu     sync.Mutex
+	cfg    *config.Config
+	path   string
+	usage  map[string]map[string]*periodUsage // by stateKey, then period
+	now    func() time.Time
+	client *http.Client
+
+	saveMu    sync.Mutex
+	saveTimer *time.Timer
+	writeMu   sync.Mutex
+}
+
+// NewTracker returns an empty tracker. It enforces nothing until SetConfig is called.
+func NewTracker() *Tracker {
+	return &Tracker{
+		usage:  make(map[string]map[string]*periodUsage),
+		now:    time.Now,
+		client: &http.Client{Timeout: webhookTimeout},
+	}
+}
+
+// SetConfig applies a new configuration. The persisted state is loaded the first time a
+// state file path is known, and again whenever the path changes.
+func (t *Tracker) SetConfig(cfg *config.Config) {
+	if t == nil {
+		return
+	}
+	path := statePath(cfg)
+	t.mu.Lock()
+	t.cfg = cfg
+	pathChanged := path != t.path
+	t.path = path
+	t.mu.Unlock()
+	if pathChanged && path != "" {
+		if err := t.load(path); err != nil {
+			log.Warnf("budget: failed to load state from %s: %v", path, err)
+		}
+	}
+}
+
+func statePath(cfg *config.Config) string {
+	if cfg == nil {
+		return ""
+	}
+	if cfg.Budget.StateFile != "" {
+		return cfg.Budget.StateFile
+	}
+	dir, err := util.ResolveAuthDir(cfg.AuthDir)
+	if err != nil || dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, defaultStateFileName)
+}
+
+// Check evaluates key against its budgets without recording anything. It returns the
+// statuses that passed the soft limit and, when a budget is used up, the exhausted status
+// with the latest reset time.
+func (t *Tracker) Check(key string) (warnings []Status, exhausted *Status) {
+	if t == nil {
+		return nil, nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	soft := t.softLimitLocked()
+	for _, status := range t.statusesLocked(key) {
+		switch {
+		case status.Exhausted():
+			if exhausted == nil || status.ResetsAt.After(exhausted.ResetsAt) {
+				current := status
+				exhausted = &current
+			}
+		case status.Used >= status.Limit*soft:
+			warnings = append(warnings, status)
+		}
+	}
+	return warnings, exhausted
+}
+
+// Remaining returns the current budget statuses of every key with budgets configured.
+func (t *Tracker) Remaining() map[string][]Status {
+	out := make(map[string][]Status)
+	if t == nil {
+		return out
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.cfg == nil {
+		return out
+	}
+	for _, policy := range t.cfg.APIKeyPolicies {
+		if statuses := t.statusesLocked(policy.APIKey); len(statuses) > 0 {
+			out[policy.APIKey] = statuses
+		}
+	}
+	return out
+}
+
+type requestMarkerKey struct{}
+
+// WithRequest marks ctx as a single inbound client request. Usage records published under it
+// count once against max-requests, however many retries, hedges or fallbacks it took.
+func WithRequest(ctx context.Context) context.Context {
+	return context.WithValue(ctx, requestMarkerKey{}, &atomic.Bool{})
+}
+
+// claimRequest reports whether a usage record under ctx should count as a new request.
+// Records without a request marker always count.
+func claimRequest(ctx context.Context) bool {
+	if ctx == nil {
+		return true
+	}
+	marker, ok := ctx.Value(requestMarkerKey{}).(*atomic.Bool)
+	if !ok || marker == nil {
+		return true
+	}
+	return marker.CompareAndSwap(false, true)
+}
+
+// HandleUsage implements coreusage.Plugin by charging the record to its client key's budgets.
+func (t *Tracker) HandleUsage(ctx context.Context, record coreusage.Record) {
+	if t == nil || record.APIKey == "" {
+		return
+	}
+	t.mu.Lock()
+	if t.cfg == nil {
+		t.mu.Unlock()
+		return
+	}
+	policy := t.cfg.APIKeyPolicy(record.APIKey)
+	if policy == nil || len(policy.Budgets) == 0 {
+		t.mu.Unlock()
+		return
+	}
+	tokens := record.Detail.TotalTokens
+	if tokens == 0 {
+		tokens = record.Detail.InputTokens + record.Detail.OutputTokens + record.Detail.ReasoningTokens
+	}
+	var cost float64
+	if price, ok := t.cfg.LookupModelPrice(record.Provider, record.Model); ok {
+		uncached := max(record.Detail.InputTokens-record.Detail.CachedTokens, 0)
+		cost = price.Cost(uncached, record.Detail.OutputTokens, record.Detail.CachedTokens, record.Detail.ReasoningTokens)
+	}
+	var requests int64
+	if claimRequest(ctx) {
+		requests = 1
+	}
+	now := t.now()
+	charged := make(map[string]bool, len(policy.Budgets))
+	for _, budget := range policy.Budgets {
+		if charged[budget.Period] {
+			continue
+		}
+		charged[budget.Period] = true
+		usage := t.periodLocked(record.APIKey, budget.Period, now)
+		usage.Requests += requests
+		usage.Tokens += tokens
+		usage.Cost += cost
+	}
+	events := t.pendingEventsLocked(record.APIKey, policy, now)
+	webhookURL := t.cfg.Budget.WebhookURL
+	t.mu.Unlock()
+
+	for _, event := range events {
+		go t.notify(webhookURL, event)
+	}
+	t.scheduleSave()
+}
+
+// softLimitLocked returns the soft limit as a fraction of a budget.
+func (t *Tracker) softLimitLocked() float64 {
+	percent := defaultSoftLimitPercent
+	if t.cfg != nil && t.cfg.Budget.SoftLimitPercent > 0 {
+		percent = t.cfg.Budget.SoftLimitPercent
+	}
+	return float64(percent) / 100
+}
+
+// periodLocked returns the usage bucket of key for the period containing now, resetting
+// it when the stored period has ended.
+func (t *Tracker) periodLocked(key, period string, now time.Time) *periodUsage {
+	id := stateKey(key)
+	periods := t.usage[id]
+	if periods == nil {
+		periods = make(map[string]*periodUsage)
+		t.usage[id] = periods
+	}
+	start := periodStart(period, now)
+	usage := periods[period]
+	if usage == nil || !usage.Start.Equal(start) {
+		usage = &periodUsage{Start: start}
+		periods[period] = usage
+	}
+	return usage
+}
+
+// statusesLocked lists one status per configured cap of key, in configuration order.
+func (t *Tracker) statusesLocked(key string) []Status {
+	if t.cfg == nil {
+		return nil
+	}
+	policy := t.cfg.APIKeyPolicy(key)
+	if policy == nil || len(policy.Budgets) == 0 {
+		return nil
+	}
+	now := t.now()
+	var out []Status
+	for _, budget := range policy.Budgets {
+		usage := t.periodLocked(key, budget.Period, now)
+		resetsAt := periodEnd(budget.Period, usage.Start)
+		add := func(metric string, limit, used float64) {
+			if limit <= 0 {
+				return
+			}
+			out = append(out, Status{
+				Period:    budget.Period,
+				Metric:    metric,
+				Limit:     limit,
+				Used:      used,
+				Remaining: max(limit-used, 0),
+				ResetsAt:  resetsAt,
+			})
+		}
+		add(MetricRequests, float64(budget.MaxRequests), float64(usage.Requests))
+		add(MetricTokens, float64(budget.MaxTokens), float64(usage.Tokens))
+		add(MetricCost, budget.MaxCost, usage.Cost)
+	}
+	return out
+}
+
+// webhookEvent is the JSON payload posted to the budget webhook.
+type webhookEvent struct {
+	Event     string    `json:"event"`
+	APIKey    string    `json:"api-key"`
+	Period    string    `json:"period"`
+	Metric    string    `json:"metric"`
+	Limit     float64   `json:"limit"`
+	Used      float64   `json:"used"`
+	ResetsAt  time.Time `json:"resets-at"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// pendingEventsLocked returns the soft-limit and exhaustion events key has not yet been
+// notified about in the current periods, marking them as delivered.
+func (t *Tracker) pendingEventsLocked(key string, policy *config.APIKeyPolicy, now time.Time) []webhookEvent {
+	if t.cfg.Budget.WebhookURL == "" {
+		return nil
+	}
+	soft := t.softLimitLocked()
+	var events []webhookEvent
+	for _, status := range t.statusesLocked(key) {
+		event := ""
+		switch {
+		case status.Exhausted():
+			event = EventExhausted
+		case status.Used >= status.Limit*soft:
+			event = EventSoftLimit
+		default:
+			continue
+		}
+		usage := t.periodLocked(key, status.Period, now)
+		marker := event + ":" + status.Metric
+		if usage.Notified[marker] {
+			continue
+		}
+		if usage.Notified == nil {
+			usage.Notified = make(map[string]bool)
+		}
+		usage.Notified[marker] = true
+		events = append(events, webhookEvent{
+			Event:     event,
+			APIKey:    util.HideAPIKey(policy.APIKey),
+			Period:    status.Period,
+			Metric:    status.Metric,
+			Limit:     status.Limit,
+			Used:      status.Used,
+			ResetsAt:  status.ResetsAt,
+			Timestamp: now.UTC(),
+		})
+	}
+	return events
+}
+
+func (t *Tracker) notify(url string, event webhookEvent) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		log.Warnf("budget: invalid webhook url: %v", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := t.client.Do(req)
+	if err != nil {
+		log.Warnf("budget: webhook delivery failed: %v", err)
+		return
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		log.Warnf("budget: webhook returned status %d", resp.StatusCode)
+	}
+}
+
+// periodStart returns the UTC start of the period containing now.
+func periodStart(period string, now time.Time) time.Time {
+	now = now.UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	switch period {
+	case config.BudgetPeriodWeekly:
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case config.BudgetPeriodMonthly:
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return day
+	}
+}
+
+// periodEnd returns when the period beginning at start resets.
+func periodEnd(period string, start time.Time) time.Time {
+	switch period {
+	case config.BudgetPeriodWeekly:
+		return start.AddDate(0, 0, 7)
+	case config.BudgetPeriodMonthly:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+func (t *Tracker) load(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var doc stateDocument
+	if err = json.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("decode budget state: %w", err)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for key, periods := range doc.Keys {
+		if doc.Version < stateVersion && !strings.HasPrefix(key, "sha256:") {
+			key = stateKey(key)
+		}
+		for period, usage := range periods {
+			if usage == nil {
+				continue
+			}
+			if t.usage[key] == nil {
+				t.usage[key] = make(map[string]*periodUsage)
+			}
+			// Usage recorded since startup is newer than the file; keep it.
+			if _, exists := t.usage[key][period]; !exists {
+				t.usage[key][period] = usage
+			}
+		}
+	}
+	return nil
+}
+
+// scheduleSave persists the state shortly after the latest change, coalescing bursts.
+func (t *Tracker) scheduleSave() {
+	t.saveMu.Lock()
+	defer t.saveMu.Unlock()
+	if t.saveTimer != nil {
+		return
+	}
+	t.saveTimer = time.AfterFunc(stateSaveDelay, func() {
+		t.saveMu.Lock()
+		t.saveTimer = nil
+		t.saveMu.Unlock()
+		if err := t.Save(); err != nil {
+			log.Warnf("budget: failed to save state: %v", err)
+		}
+	})
+}
+
+// Save writes the accumulated usage to the state file.
+func (t *Tracker) Save() error {
+	if t == nil {
+		return nil
+	}
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	t.mu.Lock()
+	path := t.path
+	doc := stateDocument{Version: stateVersion, SavedAt: t.now().UTC(), Keys: make(map[string]map[string]*periodUsage, len(t.usage))}
+	keys := make([]string, 0, len(t.usage))
+	for key := range t.usage {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		periods := make(map[string]*periodUsage, len(t.usage[key]))
+		for period, usage := range t.usage[key] {
+			clone := *usage
+			if len(usage.Notified) > 0 {
+				clone.Notified = make(map[string]bool, len(usage.Notified))
+				for marker, sent := range usage.Notified {
+					clone.Notified[marker] = sent
+				}
+			}
+			periods[period] = &clone
+		}
+		doc.Keys[key] = periods
+	}
+	t.mu.Unlock()
+	if path == "" {
+		return nil
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("encode budget state: %w", err)
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("create budget state dir: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err = os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("write budget state: %w", err)
+	}
+	if err = os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("replace budget state: %w", err)
+	}
+	return nil
+}
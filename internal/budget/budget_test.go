@@ -0,0 +1,171 @@
+package budget
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+)
+
+func newTestConfig(dir string, budgets ...config.APIKeyBudget) *config.Config {
+	cfg := &config.Config{}
+	cfg.Budget.StateFile = filepath.Join(dir, "budget-state.json")
+	cfg.Pricing = []config.ModelPrice{{Provider: "claude", Model: "claude-*", Input: 3, Output: 15}}
+	cfg.APIKeyPolicies = []config.APIKeyPolicy{{APIKey: "team-key", Budgets: budgets}}
+	return cfg
+}
+
+func TestPeriodBounds(t *testing.T) {
+	now := time.Date(2026, time.October, 16, 15, 30, 0, 0, time.UTC) // a Friday
+	tests := []struct {
+		period    string
+		wantStart time.Time
+		wantEnd   time.Time
+	}{
+		{config.BudgetPeriodDaily, time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC), time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)},
+		{config.BudgetPeriodWeekly, time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC), time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)},
+		{config.BudgetPeriodMonthly, time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tc := range tests {
+		start := periodStart(tc.period, now)
+		if !start.Equal(tc.wantStart) || !periodEnd(tc.period, start).Equal(tc.wantEnd) {
+			t.Fatalf("%s: period = [%v, %v), want [%v, %v)", tc.period, start, periodEnd(tc.period, start), tc.wantStart, tc.wantEnd)
+		}
+	}
+}
+
+func TestTrackerEnforcesBudgetsAndResets(t *testing.T) {
+	tracker := NewTracker()
+	now := time.Date(2026, time.October, 16, 12, 0, 0, 0, time.UTC)
+	tracker.now = func() time.Time { return now }
+	tracker.SetConfig(newTestConfig(t.TempDir(), config.APIKeyBudget{Period: "daily", MaxTokens: 1000, MaxCost: 1}))
+
+	record := coreusage.Record{APIKey: "team-key", Provider: "claude", Model: "claude-sonnet-4-5",
+		Detail: coreusage.Detail{InputTokens: 600, OutputTokens: 250}}
+	tracker.HandleUsage(context.Background(), record)
+
+	warnings, exhausted := tracker.Check("team-key")
+	if exhausted != nil || len(warnings) != 1 || warnings[0].Metric != MetricTokens {
+		t.Fatalf("Check() warnings = %+v, exhausted = %+v, want one tokens warning", warnings, exhausted)
+	}
+
+	tracker.HandleUsage(context.Background(), record)
+	if _, exhausted = tracker.Check("team-key"); exhausted == nil || exhausted.Metric != MetricTokens {
+		t.Fatalf("Check() exhausted = %+v, want tokens budget exhausted", exhausted)
+	}
+	if statuses := tracker.Remaining()["team-key"]; len(statuses) != 2 || statuses[1].Used != 0.0111 {
+		t.Fatalf("Remaining() = %+v, want cost used 0.0111", statuses)
+	}
+	if _, exhausted = tracker.Check("other-key"); exhausted != nil {
+		t.Fatalf("Check() for unbudgeted key = %+v, want nil", exhausted)
+	}
+
+	now = now.Add(24 * time.Hour)
+	if warnings, exhausted = tracker.Check("team-key"); exhausted != nil || len(warnings) != 0 {
+		t.Fatalf("Check() after reset: warnings = %+v, exhausted = %+v", warnings, exhausted)
+	}
+}
+
+func TestTrackerPersistsUsage(t *testing.T) {
+	dir := t.TempDir()
+	cfg := newTestConfig(dir, config.APIKeyBudget{Period: "monthly", MaxRequests: 2})
+
+	tracker := NewTracker()
+	tracker.SetConfig(cfg)
+	tracker.HandleUsage(context.Background(), coreusage.Record{APIKey: "team-key"})
+	tracker.HandleUsage(context.Background(), coreusage.Record{APIKey: "team-key"})
+	if err := tracker.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	data, err := os.ReadFile(cfg.Budget.StateFile)
+	if err != nil {
+		t.Fatalf("read state: %v", err)
+	}
+	if strings.Contains(string(data), "team-key") || !strings.Contains(string(data), stateKey("team-key")) {
+		t.Fatalf("state file does not key usage by the hashed client key: %s", data)
+	}
+
+	restored := NewTracker()
+	restored.SetConfig(cfg)
+	if _, exhausted := restored.Check("team-key"); exhausted == nil || exhausted.Metric != MetricRequests {
+		t.Fatalf("Check() after restore = %+v, want requests budget exhausted", exhausted)
+	}
+}
+
+func TestTrackerNotifiesWebhookOncePerPeriod(t *testing.T) {
+	events := make(chan webhookEvent, 4)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event webhookEvent
+		_ = json.NewDecoder(r.Body).Decode(&event)
+		events <- event
+	}))
+	defer server.Close()
+
+	cfg := newTestConfig(t.TempDir(), config.APIKeyBudget{Period: "weekly", MaxRequests: 10})
+	cfg.Budget.WebhookURL = server.URL
+	cfg.Budget.SoftLimitPercent = 50
+	tracker := NewTracker()
+	tracker.SetConfig(cfg)
+
+	for i := 0; i < 6; i++ {
+		tracker.HandleUsage(context.Background(), coreusage.Record{APIKey: "team-key"})
+	}
+	select {
+	case event := <-events:
+		if event.Event != EventSoftLimit || event.Metric != MetricRequests || event.Used != 5 || event.APIKey == "team-key" {
+			t.Fatalf("webhook event = %+v, want masked soft-limit event at 5 requests", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook not called")
+	}
+	select {
+	case event := <-events:
+		t.Fatalf("unexpected second webhook event %+v", event)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestTrackerCountsMarkedRequestOnce(t *testing.T) {
+	tracker := NewTracker()
+	tracker.SetConfig(newTestConfig(t.TempDir(), config.APIKeyBudget{Period: "daily", MaxRequests: 2}))
+
+	record := coreusage.Record{APIKey: "team-key", Provider: "claude", Model: "claude-sonnet-4-5"}
+	ctx := WithRequest(context.Background())
+	for range 3 {
+		tracker.HandleUsage(ctx, record)
+	}
+	if statuses := tracker.Remaining()["team-key"]; len(statuses) != 1 || statuses[0].Used != 1 {
+		t.Fatalf("Remaining() = %+v, want one request used for the marked request", statuses)
+	}
+
+	tracker.HandleUsage(context.Background(), record)
+	if _, exhausted := tracker.Check("team-key"); exhausted == nil || exhausted.Metric != MetricRequests {
+		t.Fatalf("Check() exhausted = %+v, want requests budget exhausted", exhausted)
+	}
+}
+
+func TestTrackerMigratesPlaintextState(t *testing.T) {
+	dir := t.TempDir()
+	cfg := newTestConfig(dir, config.APIKeyBudget{Period: "monthly", MaxRequests: 1})
+	start := periodStart(config.BudgetPeriodMonthly, time.Now())
+	legacy, _ := json.Marshal(stateDocument{Version: 1, Keys: map[string]map[string]*periodUsage{
+		"team-key": {config.BudgetPeriodMonthly: {Start: start, Requests: 1}},
+	}})
+	if err := os.WriteFile(cfg.Budget.StateFile, legacy, 0o600); err != nil {
+		t.Fatalf("write legacy state: %v", err)
+	}
+
+	tracker := NewTracker()
+	tracker.SetConfig(cfg)
+	if _, exhausted := tracker.Check("team-key"); exhausted == nil {
+		t.Fatal("Check() ignored usage from a version 1 state file")
+	}
+}
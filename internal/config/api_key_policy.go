@@ -0,0 +1,170 @@
+package config
+
+import "strings"
+
+// APIKeyPolicy restricts what a single client API key may access. Keys listed here are
+// accepted for authentication even when they are not also present in api-keys.
+type APIKeyPolicy struct {
+	// APIKey is the client API key the policy applies to.
+	APIKey string `yaml:"api-key" json:"api-key"`
+
+	// AllowedModels lists model names or wildcard patterns the key may use.
+	// Empty allows every model that is not denied.
+	AllowedModels []string `yaml:"allowed-models,omitempty" json:"allowed-models,omitempty"`
+
+	// DeniedModels lists model names or wildcard patterns the key may not use.
+	// Denials take precedence over AllowedModels.
+	DeniedModels []string `yaml:"denied-models,omitempty" json:"denied-models,omitempty"`
+
+	// AllowedProviders limits which credential providers (e.g., "claude", "gemini-cli" or an
+	// openai-compatibility name) may serve the key's requests. Empty allows all providers.
+	AllowedProviders []string `yaml:"allowed-providers,omitempty" json:"allowed-providers,omitempty"`
+
+	// AllowedPrefixes limits which credential prefixes the key may target with
+	// "prefix/model" requests. Empty allows all prefixes.
+	AllowedPrefixes []string `yaml:"allowed-prefixes,omitempty" json:"allowed-prefixes,omitempty"`
+
+	// BoundPrefixes binds the key to credentials carrying one of these prefixes. Unprefixed
+	// requests are routed to the first bound prefix serving the model, and any other prefix,
+	// or credential without a prefix, is refused. Empty leaves the key unbound.
+	BoundPrefixes []string `yaml:"bound-prefixes,omitempty" json:"bound-prefixes,omitempty"`
+
+	// RequestsPerMinute limits how many requests the key may start per minute. 0 means unlimited.
+	RequestsPerMinute int `yaml:"requests-per-minute,omitempty" json:"requests-per-minute,omitempty"`
+
+	// TokensPerMinute limits the key's token throughput. Requests are charged an estimate up
+	// front, corrected once usage is reported. 0 means unlimited.
+	TokensPerMinute int `yaml:"tokens-per-minute,omitempty" json:"tokens-per-minute,omitempty"`
+
+	// MaxConcurrentStreams limits how many streaming responses the key may have open. 0 means unlimited.
+	MaxConcurrentStreams int `yaml:"max-concurrent-streams,omitempty" json:"max-concurrent-streams,omitempty"`
+
+	// Budgets caps the key's requests, tokens or estimated cost per day, week or month.
+	// Requests are rejected once any budget is used up until its period resets.
+	Budgets []APIKeyBudget `yaml:"budgets,omitempty" json:"budgets,omitempty"`
+}
+
+// HasRateLimits reports whether any request, token or stream limit is configured.
+func (p *APIKeyPolicy) HasRateLimits() bool {
+	return p != nil && (p.RequestsPerMinute > 0 || p.TokensPerMinute > 0 || p.MaxConcurrentStreams > 0)
+}
+
+// APIKeyPolicy returns the policy configured for key, or nil when the key is unrestricted.
+func (cfg *SDKConfig) APIKeyPolicy(key string) *APIKeyPolicy {
+	if cfg == nil || key == "" {
+		return nil
+	}
+	for i := range cfg.APIKeyPolicies {
+		if cfg.APIKeyPolicies[i].APIKey == key {
+			return &cfg.APIKeyPolicies[i]
+		}
+	}
+	return nil
+}
+
+// AllowsModel reports whether the policy permits model. Patterns are matched
+// case-insensitively against the model name without thinking suffix or credential prefix.
+func (p *APIKeyPolicy) AllowsModel(model string) bool {
+	if p == nil {
+		return true
+	}
+	model = strings.ToLower(strings.TrimSpace(model))
+	for _, pattern := range p.DeniedModels {
+		if MatchWildcard(pattern, model) {
+			return false
+		}
+	}
+	if len(p.AllowedModels) == 0 {
+		return true
+	}
+	for _, pattern := range p.AllowedModels {
+		if MatchWildcard(pattern, model) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsProvider reports whether the policy permits credentials of provider.
+func (p *APIKeyPolicy) AllowsProvider(provider string) bool {
+	if p == nil || len(p.AllowedProviders) == 0 {
+		return true
+	}
+	provider = strings.ToLower(strings.TrimSpace(provider))
+	for _, allowed := range p.AllowedProviders {
+		if allowed == provider {
+			return true
+		}
+	}
+	return false
+}
+
+// IsBound reports whether the key is bound to credential prefixes.
+func (p *APIKeyPolicy) IsBound() bool {
+	return p != nil && len(p.BoundPrefixes) > 0
+}
+
+// AllowsPrefix reports whether the policy permits targeting credentials with prefix.
+// A bound key may only target its bound prefixes.
+func (p *APIKeyPolicy) AllowsPrefix(prefix string) bool {
+	if p == nil {
+		return true
+	}
+	prefix = strings.Trim(strings.TrimSpace(prefix), "/")
+	if p.IsBound() && !containsPrefix(p.BoundPrefixes, prefix) {
+		return false
+	}
+	return len(p.AllowedPrefixes) == 0 || containsPrefix(p.AllowedPrefixes, prefix)
+}
+
+func containsPrefix(prefixes []string, prefix string) bool {
+	for _, candidate := range prefixes {
+		if candidate == prefix {
+			return true
+		}
+	}
+	return false
+}
+
+// SanitizeAPIKeyPolicies trims keys, normalizes model patterns, provider names and prefixes,
+// clamps negative limits to unlimited, drops invalid budgets and policies without an API key.
+// When a key has several policies the first one wins.
+func (cfg *Config) SanitizeAPIKeyPolicies() {
+	if cfg == nil || len(cfg.APIKeyPolicies) == 0 {
+		return
+	}
+	seen := make(map[string]struct{}, len(cfg.APIKeyPolicies))
+	out := make([]APIKeyPolicy, 0, len(cfg.APIKeyPolicies))
+	for _, policy := range cfg.APIKeyPolicies {
+		policy.APIKey = strings.TrimSpace(policy.APIKey)
+		if policy.APIKey == "" {
+			continue
+		}
+		if _, exists := seen[policy.APIKey]; exists {
+			continue
+		}
+		seen[policy.APIKey] = struct{}{}
+		policy.AllowedModels = NormalizeExcludedModels(policy.AllowedModels)
+		policy.DeniedModels = NormalizeExcludedModels(policy.DeniedModels)
+		policy.AllowedProviders = NormalizeExcludedModels(policy.AllowedProviders)
+		policy.AllowedPrefixes = normalizePrefixes(policy.AllowedPrefixes)
+		policy.BoundPrefixes = normalizePrefixes(policy.BoundPrefixes)
+		policy.RequestsPerMinute = max(policy.RequestsPerMinute, 0)
+		policy.TokensPerMinute = max(policy.TokensPerMinute, 0)
+		policy.MaxConcurrentStreams = max(policy.MaxConcurrentStreams, 0)
+		policy.Budgets = sanitizeBudgets(policy.Budgets)
+		out = append(out, policy)
+	}
+	cfg.APIKeyPolicies = out
+}
+
+// normalizePrefixes trims whitespace and slashes, dropping empty and duplicate prefixes.
+func normalizePrefixes(prefixes []string) []string {
+	var out []string
+	for _, prefix := range prefixes {
+		if prefix = strings.Trim(strings.TrimSpace(prefix), "/"); prefix != "" && !containsPrefix(out, prefix) {
+			out = append(out, prefix)
+		}
+	}
+	return out
+}
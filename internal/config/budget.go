@@ -0,0 +1,90 @@
+package config
+
+import "strings"
+
+// Budget periods supported by APIKeyBudget. Periods start at midnight UTC; weeks start on Monday.
+const (
+	BudgetPeriodDaily   = "daily"
+	BudgetPeriodWeekly  = "weekly"
+	BudgetPeriodMonthly = "monthly"
+)
+
+// BudgetConfig configures how per-key budgets are persisted and how warnings are delivered.
+type BudgetConfig struct {
+	// StateFile stores accumulated usage so budgets survive restarts. Defaults to
+	// .budget-state inside auth-dir, which the remote token stores never mirror.
+	StateFile string `yaml:"state-file,omitempty" json:"state-file,omitempty"`
+
+	// SoftLimitPercent is the share of a budget after which responses carry an
+	// X-Budget-Warning header and the webhook is notified. Defaults to 80 when <= 0.
+	SoftLimitPercent int `yaml:"soft-limit-percent,omitempty" json:"soft-limit-percent,omitempty"`
+
+	// WebhookURL receives a JSON POST when a key crosses the soft limit or exhausts a
+	// budget, at most once per budget and period.
+	WebhookURL string `yaml:"webhook-url,omitempty" json:"webhook-url,omitempty"`
+}
+
+// APIKeyBudget caps a client API key's usage over a calendar period. Zero caps are unlimited.
+type APIKeyBudget struct {
+	// Period is "daily", "weekly" or "monthly".
+	Period string `yaml:"period" json:"period"`
+
+	// MaxRequests caps the number of client requests; retries, hedges and fallbacks of one
+	// request count once.
+	MaxRequests int64 `yaml:"max-requests,omitempty" json:"max-requests,omitempty"`
+
+	// MaxTokens caps total tokens (input, output and reasoning).
+	MaxTokens int64 `yaml:"max-tokens,omitempty" json:"max-tokens,omitempty"`
+
+	// MaxCost caps estimated cost, computed from the pricing table in the same currency units.
+	MaxCost float64 `yaml:"max-cost,omitempty" json:"max-cost,omitempty"`
+}
+
+// SanitizeBudget clamps the soft limit to 100 percent and trims the webhook URL.
+func (cfg *Config) SanitizeBudget() {
+	if cfg == nil {
+		return
+	}
+	cfg.Budget.StateFile = strings.TrimSpace(cfg.Budget.StateFile)
+	cfg.Budget.WebhookURL = strings.TrimSpace(cfg.Budget.WebhookURL)
+	cfg.Budget.SoftLimitPercent = min(cfg.Budget.SoftLimitPercent, 100)
+}
+
+// LookupModelPrice returns the first pricing entry matching provider and model.
+func (cfg *Config) LookupModelPrice(provider, model string) (ModelPrice, bool) {
+	if cfg == nil {
+		return ModelPrice{}, false
+	}
+	provider = strings.ToLower(strings.TrimSpace(provider))
+	model = strings.ToLower(strings.TrimSpace(model))
+	if provider == "" || model == "" {
+		return ModelPrice{}, false
+	}
+	for _, price := range cfg.Pricing {
+		if price.Provider == provider && MatchWildcard(price.Model, model) {
+			return price, true
+		}
+	}
+	return ModelPrice{}, false
+}
+
+// sanitizeBudgets normalizes periods and drops budgets with an unknown period or no caps.
+func sanitizeBudgets(budgets []APIKeyBudget) []APIKeyBudget {
+	var out []APIKeyBudget
+	for _, budget := range budgets {
+		budget.Period = strings.ToLower(strings.TrimSpace(budget.Period))
+		switch budget.Period {
+		case BudgetPeriodDaily, BudgetPeriodWeekly, BudgetPeriodMonthly:
+		default:
+			continue
+		}
+		budget.MaxRequests = max(budget.MaxRequests, 0)
+		budget.MaxTokens = max(budget.MaxTokens, 0)
+		budget.MaxCost = max(budget.MaxCost, 0)
+		if budget.MaxRequests == 0 && budget.MaxTokens == 0 && budget.MaxCost == 0 {
+			continue
+		}
+		out = append(out, budget)
+	}
+	return out
+}
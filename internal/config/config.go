@@ -35,6 +35,9 @@ type Config struct {
 	// TLS config controls HTTPS server settings.
 	TLS TLSConfig `yaml:"tls" json:"tls"`
 
+	// CORS configures cross-origin access for browser-based clients.
+	CORS CORSConfig `yaml:"cors,omitempty" json:"cors,omitempty"`
+
 	// RemoteManagement nests management-related options under 'remote-management'.
 	RemoteManagement RemoteManagement `yaml:"remote-management" json:"-"`
 
@@ -47,6 +50,12 @@ type Config struct {
 	// Pprof config controls the optional pprof HTTP debug server.
 	Pprof PprofConfig `yaml:"pprof" json:"pprof"`
 
+	// Metrics controls the Prometheus /metrics endpoint on the main port.
+	Metrics MetricsConfig `yaml:"metrics" json:"metrics"`
+
+	// Tracing controls OpenTelemetry span export.
+	Tracing TracingConfig `yaml:"tracing" json:"tracing"`
+
 	// CommercialMode disables high-overhead HTTP middleware features to minimize per-request memory usage.
 	CommercialMode bool `yaml:"commercial-mode" json:"commercial-mode"`
 
@@ -64,6 +73,16 @@ type Config struct {
 	// UsageStatisticsEnabled toggles in-memory usage aggregation; when false, usage data is discarded.
 	UsageStatisticsEnabled bool `yaml:"usage-statistics-enabled" json:"usage-statistics-enabled"`
 
+	// UsageStore persists usage statistics in SQLite or Postgres with retention and rollups.
+	UsageStore UsageStoreConfig `yaml:"usage-store" json:"usage-store"`
+
+	// UsageSinks forward every usage record to JSONL files, HTTP webhooks or stdout.
+	UsageSinks []UsageSink `yaml:"usage-sinks,omitempty" json:"usage-sinks,omitempty"`
+
+	// Notifications alert webhooks about cooldowns, failing refreshes, invalid credentials,
+	// unavailable models and failed config reloads.
+	Notifications NotificationsConfig `yaml:"notifications" json:"notifications"`
+
 	// DisableCooling disables quota cooldown scheduling when true.
 	DisableCooling bool `yaml:"disable-cooling" json:"disable-cooling"`
 
@@ -78,6 +97,32 @@ type Config struct {
 	// Routing controls credential selection behavior.
 	Routing RoutingConfig `yaml:"routing" json:"routing"`
 
+	// CircuitBreaker configures failure-driven circuit breaking per credential and per upstream.
+	CircuitBreaker CircuitBreakerConfig `yaml:"circuit-breaker,omitempty" json:"circuit-breaker,omitempty"`
+
+	// MaxConcurrencyWaitSeconds is how long a request waits for a free slot when every matching
+	// credential is at its max-concurrency limit. 0 uses the default (5); negative fails at once.
+	MaxConcurrencyWaitSeconds int `yaml:"max-concurrency-wait-seconds,omitempty" json:"max-concurrency-wait-seconds,omitempty"`
+
+	// CooldownQueue makes requests wait for credentials to recover instead of failing fast
+	// when every credential for a model is cooling down.
+	CooldownQueue CooldownQueueConfig `yaml:"cooldown-queue,omitempty" json:"cooldown-queue,omitempty"`
+
+	// ModelFallbacks maps a requested model to an ordered chain of alternative models.
+	// When every credential for the requested model is cooling down or failing with a
+	// retryable error, the next model in the chain is tried.
+	ModelFallbacks map[string][]string `yaml:"model-fallbacks,omitempty" json:"model-fallbacks,omitempty"`
+
+	// Hedging lists model patterns whose non-streaming requests are duplicated to a second
+	// credential when the first one is slow to send response headers.
+	Hedging []HedgingRule `yaml:"hedging,omitempty" json:"hedging,omitempty"`
+
+	// Pricing lists token prices per provider and model, used by the "cheapest" routing strategy.
+	Pricing []ModelPrice `yaml:"pricing,omitempty" json:"pricing,omitempty"`
+
+	// Budget configures persistence and warnings for per-key budgets (api-key-policies[].budgets).
+	Budget BudgetConfig `yaml:"budget,omitempty" json:"budget,omitempty"`
+
 	// WebsocketAuth enables or disables authentication for the WebSocket API.
 	WebsocketAuth bool `yaml:"ws-auth" json:"ws-auth"`
 
@@ -135,12 +180,22 @@ type PprofConfig struct {
 	Addr string `yaml:"addr" json:"addr"`
 }
 
+// MetricsConfig holds Prometheus metrics endpoint settings.
+type MetricsConfig struct {
+	// Enable serves Prometheus metrics at /metrics on the main port.
+	Enable bool `yaml:"enable" json:"enable"`
+	// BearerToken, when set, must be presented as "Authorization: Bearer <token>" to scrape.
+	BearerToken string `yaml:"bearer-token,omitempty" json:"-"`
+}
+
 // RemoteManagement holds management API configuration under 'remote-management'.
 type RemoteManagement struct {
 	// AllowRemote toggles remote (non-localhost) access to management API.
 	AllowRemote bool `yaml:"allow-remote"`
 	// SecretKey is the management key (plaintext or bcrypt hashed). YAML key intentionally 'secret-key'.
 	SecretKey string `yaml:"secret-key"`
+	// Keys lists additional named management keys, each limited to a role.
+	Keys []ManagementKey `yaml:"keys,omitempty"`
 	// DisableControlPanel skips serving and syncing the bundled management UI when true.
 	DisableControlPanel bool `yaml:"disable-control-panel"`
 	// PanelGitHubRepository overrides the GitHub repository used to fetch the management panel asset.
@@ -161,8 +216,130 @@ type QuotaExceeded struct {
 // RoutingConfig configures how credentials are selected for requests.
 type RoutingConfig struct {
 	// Strategy selects the credential selection strategy.
-	// Supported values: "round-robin" (default), "fill-first".
+	// Supported values: "round-robin" (default), "fill-first", "session-affinity", "adaptive", "cheapest".
 	Strategy string `yaml:"strategy,omitempty" json:"strategy,omitempty"`
+
+	// SessionAffinityTTL is how long, in seconds, a session stays pinned to a credential
+	// after its last request when using the session-affinity strategy. 0 uses the default (3600).
+	SessionAffinityTTL int `yaml:"session-affinity-ttl,omitempty" json:"session-affinity-ttl,omitempty"`
+
+	// SessionAffinityHeader names the request header carrying an explicit session key.
+	// Defaults to "X-Session-Id" when empty.
+	SessionAffinityHeader string `yaml:"session-affinity-header,omitempty" json:"session-affinity-header,omitempty"`
+
+	// AdaptiveExploration is the share of requests (0-1) the adaptive strategy routes to a
+	// random available credential to keep its scores fresh. 0 uses the default (0.1);
+	// a negative value disables exploration.
+	AdaptiveExploration float64 `yaml:"adaptive-exploration,omitempty" json:"adaptive-exploration,omitempty"`
+}
+
+// NormalizeRoutingStrategy maps a routing.strategy value or alias to its canonical name.
+// It reports false for unknown strategies; an empty value is the default round-robin.
+func NormalizeRoutingStrategy(strategy string) (string, bool) {
+	switch strings.ToLower(strings.TrimSpace(strategy)) {
+	case "", "round-robin", "roundrobin", "rr":
+		return "round-robin", true
+	case "fill-first", "fillfirst", "ff":
+		return "fill-first", true
+	case "session-affinity", "sessionaffinity", "sticky", "session":
+		return "session-affinity", true
+	case "adaptive", "ewma":
+		return "adaptive", true
+	case "cheapest", "cost":
+		return "cheapest", true
+	default:
+		return "", false
+	}
+}
+
+// CircuitBreakerConfig configures circuit breaking for repeated upstream failures
+// (5xx responses, timeouts and network errors).
+type CircuitBreakerConfig struct {
+	// Enable turns circuit breaking on.
+	Enable bool `yaml:"enable" json:"enable"`
+
+	// FailureThreshold is the number of consecutive failures that opens a credential's circuit.
+	// Defaults to 5 when <= 0.
+	FailureThreshold int `yaml:"failure-threshold,omitempty" json:"failure-threshold,omitempty"`
+
+	// ProviderFailureThreshold is the number of consecutive failures that opens the circuit
+	// shared by every credential targeting the same provider base URL. Defaults to 10 when <= 0.
+	ProviderFailureThreshold int `yaml:"provider-failure-threshold,omitempty" json:"provider-failure-threshold,omitempty"`
+
+	// OpenSeconds is how long an open circuit rejects traffic before allowing a half-open probe.
+	// Defaults to 30 when <= 0.
+	OpenSeconds int `yaml:"open-seconds,omitempty" json:"open-seconds,omitempty"`
+}
+
+// CooldownQueueConfig configures the bounded wait queue used when all credentials for a
+// model are cooling down or at their concurrency limit.
+type CooldownQueueConfig struct {
+	// Enable turns the wait queue on.
+	Enable bool `yaml:"enable" json:"enable"`
+
+	// MaxWaitSeconds bounds how long a request may wait in the queue. Defaults to 60 when <= 0.
+	MaxWaitSeconds int `yaml:"max-wait-seconds,omitempty" json:"max-wait-seconds,omitempty"`
+
+	// MaxDepth bounds the number of waiting requests per model. Defaults to 100 when <= 0.
+	MaxDepth int `yaml:"max-depth,omitempty" json:"max-depth,omitempty"`
+
+	// Priorities maps client API keys to a priority class ("high", "normal" or "low").
+	// Higher classes are served first; keys not listed use "normal".
+	Priorities map[string]string `yaml:"priorities,omitempty" json:"priorities,omitempty"`
+}
+
+// HedgingRule enables hedged requests for matching models.
+type HedgingRule struct {
+	// Models lists model names or wildcard patterns (e.g., "gpt-*-mini", "gemini-*-flash").
+	Models []string `yaml:"models" json:"models"`
+
+	// DelayMs is how long the first attempt may wait for its first response before a hedge
+	// request is sent.
+	DelayMs int `yaml:"delay-ms" json:"delay-ms"`
+}
+
+// ModelPrice defines token prices, in currency units per million tokens, for a model served
+// by a provider (e.g., "claude", "antigravity" or an openai-compatibility provider name).
+type ModelPrice struct {
+	// Provider is the credential provider the price applies to.
+	Provider string `yaml:"provider" json:"provider"`
+
+	// Model is the client-visible model name or a wildcard pattern (e.g., "claude-sonnet-*").
+	Model string `yaml:"model" json:"model"`
+
+	// Input is the price of uncached prompt tokens.
+	Input float64 `yaml:"input" json:"input"`
+
+	// Output is the price of completion tokens.
+	Output float64 `yaml:"output" json:"output"`
+
+	// Cached is the price of cached prompt tokens. Defaults to Input when zero.
+	Cached float64 `yaml:"cached,omitempty" json:"cached,omitempty"`
+
+	// Reasoning is the price of reasoning tokens. Defaults to Output when zero.
+	Reasoning float64 `yaml:"reasoning,omitempty" json:"reasoning,omitempty"`
+}
+
+// EffectiveCost returns the blended price used to rank channels: the sum of the input and
+// output prices, which weights prompt and completion tokens equally.
+func (p ModelPrice) EffectiveCost() float64 {
+	return p.Input + p.Output
+}
+
+// Cost returns the price of a request with the given token counts. Cached and reasoning
+// tokens are billed at their own rates; inputTokens should exclude cached tokens.
+func (p ModelPrice) Cost(inputTokens, outputTokens, cachedTokens, reasoningTokens int64) float64 {
+	cached := p.Cached
+	if cached == 0 {
+		cached = p.Input
+	}
+	reasoning := p.Reasoning
+	if reasoning == 0 {
+		reasoning = p.Output
+	}
+	total := float64(inputTokens)*p.Input + float64(outputTokens)*p.Output +
+		float64(cachedTokens)*cached + float64(reasoningTokens)*reasoning
+	return total / 1_000_000
 }
 
 // OAuthModelAlias defines a model ID alias for a specific channel.
@@ -300,6 +477,9 @@ type ClaudeKey struct {
 	// Higher values are preferred; defaults to 0.
 	Priority int `yaml:"priority,omitempty" json:"priority,omitempty"`
 
+	// MaxConcurrency caps concurrent in-flight requests for this credential; 0 means unlimited.
+	MaxConcurrency int `yaml:"max-concurrency,omitempty" json:"max-concurrency,omitempty"`
+
 	// Prefix optionally namespaces models for this credential (e.g., "teamA/claude-sonnet-4").
 	Prefix string `yaml:"prefix,omitempty" json:"prefix,omitempty"`
 
@@ -348,6 +528,9 @@ type CodexKey struct {
 	// Higher values are preferred; defaults to 0.
 	Priority int `yaml:"priority,omitempty" json:"priority,omitempty"`
 
+	// MaxConcurrency caps concurrent in-flight requests for this credential; 0 means unlimited.
+	MaxConcurrency int `yaml:"max-concurrency,omitempty" json:"max-concurrency,omitempty"`
+
 	// Prefix optionally namespaces models for this credential (e.g., "teamA/gpt-5-codex").
 	Prefix string `yaml:"prefix,omitempty" json:"prefix,omitempty"`
 
@@ -393,6 +576,9 @@ type GeminiKey struct {
 	// Higher values are preferred; defaults to 0.
 	Priority int `yaml:"priority,omitempty" json:"priority,omitempty"`
 
+	// MaxConcurrency caps concurrent in-flight requests for this credential; 0 means unlimited.
+	MaxConcurrency int `yaml:"max-concurrency,omitempty" json:"max-concurrency,omitempty"`
+
 	// Prefix optionally namespaces models for this credential (e.g., "teamA/gemini-3-pro-preview").
 	Prefix string `yaml:"prefix,omitempty" json:"prefix,omitempty"`
 
@@ -437,6 +623,9 @@ type OpenAICompatibility struct {
 	// Higher values are preferred; defaults to 0.
 	Priority int `yaml:"priority,omitempty" json:"priority,omitempty"`
 
+	// MaxConcurrency caps concurrent in-flight requests for each API key of this provider; 0 means unlimited.
+	MaxConcurrency int `yaml:"max-concurrency,omitempty" json:"max-concurrency,omitempty"`
+
 	// Prefix optionally namespaces model aliases for this provider (e.g., "teamA/kimi-k2").
 	Prefix string `yaml:"prefix,omitempty" json:"prefix,omitempty"`
 
@@ -576,6 +765,9 @@ func LoadConfigOptional(configFile string, optional bool) (*Config, error) {
 		cfg.RemoteManagement.PanelGitHubRepository = DefaultPanelGitHubRepository
 	}
 
+	// Normalize role-based management keys and drop entries without a key or valid role.
+	cfg.SanitizeManagementKeys()
+
 	cfg.Pprof.Addr = strings.TrimSpace(cfg.Pprof.Addr)
 	if cfg.Pprof.Addr == "" {
 		cfg.Pprof.Addr = DefaultPprofAddr
@@ -613,6 +805,39 @@ func LoadConfigOptional(configFile string, optional bool) (*Config, error) {
 	// Validate raw payload rules and drop invalid entries.
 	cfg.SanitizePayloadRules()
 
+	// Normalize model fallback chains.
+	cfg.SanitizeModelFallbacks()
+
+	// Normalize hedging rules and drop entries that can never apply.
+	cfg.SanitizeHedging()
+
+	// Normalize cooldown queue priority classes.
+	cfg.SanitizeCooldownQueue()
+
+	// Normalize pricing entries and drop incomplete ones.
+	cfg.SanitizePricing()
+
+	// Normalize per-key access policies.
+	cfg.SanitizeAPIKeyPolicies()
+
+	// Normalize budget persistence and warning settings.
+	cfg.SanitizeBudget()
+
+	// Normalize CORS origins, methods and route groups.
+	cfg.SanitizeCORS()
+
+	// Fill tracing exporter defaults.
+	cfg.SanitizeTracing()
+
+	// Normalize the usage store driver and retention windows.
+	cfg.SanitizeUsageStore()
+
+	// Normalize usage sinks and drop incomplete ones.
+	cfg.SanitizeUsageSinks()
+
+	// Fill notification defaults and drop webhooks without a URL.
+	cfg.SanitizeNotifications()
+
 	// NOTE: Legacy migration persistence is intentionally disabled together with
 	// startup legacy migration to keep startup read-only for config.yaml.
 	// Re-enable the block below if automatic startup migration is needed again.
@@ -725,6 +950,110 @@ func (cfg *Config) SanitizeOAuthModelAlias() {
 	cfg.OAuthModelAlias = out
 }
 
+// SanitizeModelFallbacks normalizes model fallback chains.
+// It trims whitespace, lower-cases source model keys, drops empty or self-referencing
+// entries, and deduplicates each chain while preserving order.
+func (cfg *Config) SanitizeModelFallbacks() {
+	if cfg == nil || len(cfg.ModelFallbacks) == 0 {
+		return
+	}
+	out := make(map[string][]string, len(cfg.ModelFallbacks))
+	for rawModel, chain := range cfg.ModelFallbacks {
+		model := strings.ToLower(strings.TrimSpace(rawModel))
+		if model == "" || len(chain) == 0 {
+			continue
+		}
+		seen := map[string]struct{}{model: {}}
+		clean := make([]string, 0, len(chain))
+		for _, entry := range chain {
+			trimmed := strings.TrimSpace(entry)
+			key := strings.ToLower(trimmed)
+			if trimmed == "" {
+				continue
+			}
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			clean = append(clean, trimmed)
+		}
+		if len(clean) > 0 {
+			out[model] = clean
+		}
+	}
+	if len(out) == 0 {
+		out = nil
+	}
+	cfg.ModelFallbacks = out
+}
+
+// SanitizeCooldownQueue lower-cases priority classes and drops entries with an empty key
+// or an unknown class.
+func (cfg *Config) SanitizeCooldownQueue() {
+	if cfg == nil || len(cfg.CooldownQueue.Priorities) == 0 {
+		return
+	}
+	out := make(map[string]string, len(cfg.CooldownQueue.Priorities))
+	for key, class := range cfg.CooldownQueue.Priorities {
+		key = strings.TrimSpace(key)
+		class = strings.ToLower(strings.TrimSpace(class))
+		if key == "" {
+			continue
+		}
+		switch class {
+		case "high", "normal", "low":
+			out[key] = class
+		}
+	}
+	cfg.CooldownQueue.Priorities = out
+}
+
+// SanitizeHedging trims and lower-cases hedging model patterns and drops rules
+// without models or with a non-positive delay.
+func (cfg *Config) SanitizeHedging() {
+	if cfg == nil || len(cfg.Hedging) == 0 {
+		return
+	}
+	out := make([]HedgingRule, 0, len(cfg.Hedging))
+	for _, rule := range cfg.Hedging {
+		if rule.DelayMs <= 0 {
+			continue
+		}
+		models := make([]string, 0, len(rule.Models))
+		for _, model := range rule.Models {
+			if model = strings.ToLower(strings.TrimSpace(model)); model != "" {
+				models = append(models, model)
+			}
+		}
+		if len(models) == 0 {
+			continue
+		}
+		out = append(out, HedgingRule{Models: models, DelayMs: rule.DelayMs})
+	}
+	cfg.Hedging = out
+}
+
+// SanitizePricing lower-cases provider and model names and drops entries without a
+// provider or model, or with negative prices.
+func (cfg *Config) SanitizePricing() {
+	if cfg == nil || len(cfg.Pricing) == 0 {
+		return
+	}
+	out := make([]ModelPrice, 0, len(cfg.Pricing))
+	for _, price := range cfg.Pricing {
+		price.Provider = strings.ToLower(strings.TrimSpace(price.Provider))
+		price.Model = strings.ToLower(strings.TrimSpace(price.Model))
+		if price.Provider == "" || price.Model == "" {
+			continue
+		}
+		if price.Input < 0 || price.Output < 0 || price.Cached < 0 || price.Reasoning < 0 {
+			continue
+		}
+		out = append(out, price)
+	}
+	cfg.Pricing = out
+}
+
 // SanitizeOpenAICompatibility removes OpenAI-compatibility provider entries that are
 // not actionable, specifically those missing a BaseURL. It trims whitespace before
 // evaluation and preserves the relative order of remaining entries.
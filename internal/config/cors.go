@@ -0,0 +1,114 @@
+package config
+
+import "strings"
+
+// CORS route group identifiers accepted under cors.routes.
+const (
+	CORSRouteOpenAI     = "v1"
+	CORSRouteGemini     = "v1beta"
+	CORSRouteManagement = "management"
+	CORSRouteAmp        = "amp"
+)
+
+// CORSPolicy describes the cross-origin rules applied to a group of routes.
+type CORSPolicy struct {
+	// Disable suppresses all CORS headers for the matching routes and rejects preflights.
+	Disable bool `yaml:"disable,omitempty" json:"disable,omitempty"`
+
+	// AllowedOrigins lists origins permitted to call the API.
+	// Entries may be exact ("https://app.example.com"), wildcard patterns
+	// ("https://*.example.com"), or "*" to allow any origin.
+	AllowedOrigins []string `yaml:"allowed-origins,omitempty" json:"allowed-origins,omitempty"`
+
+	// AllowedMethods lists HTTP methods returned for preflight requests.
+	AllowedMethods []string `yaml:"allowed-methods,omitempty" json:"allowed-methods,omitempty"`
+
+	// AllowedHeaders lists request headers returned for preflight requests.
+	// "*" echoes whatever the browser requested via Access-Control-Request-Headers.
+	AllowedHeaders []string `yaml:"allowed-headers,omitempty" json:"allowed-headers,omitempty"`
+
+	// ExposedHeaders lists response headers the browser may read.
+	ExposedHeaders []string `yaml:"exposed-headers,omitempty" json:"exposed-headers,omitempty"`
+
+	// AllowCredentials sets Access-Control-Allow-Credentials: true when enabled.
+	AllowCredentials bool `yaml:"allow-credentials,omitempty" json:"allow-credentials,omitempty"`
+
+	// MaxAge controls how long (in seconds) browsers may cache preflight results. 0 omits the header.
+	MaxAge int `yaml:"max-age,omitempty" json:"max-age,omitempty"`
+}
+
+// CORSConfig configures cross-origin resource sharing for the HTTP API.
+// The inline policy is the default; Routes overrides it for specific route groups
+// (v1, v1beta, management, amp). A route policy replaces the default entirely.
+// Management and Amp routes never send CORS headers unless routes.management or
+// routes.amp is configured.
+type CORSConfig struct {
+	CORSPolicy `yaml:",inline"`
+
+	// Routes maps a route group identifier to its policy.
+	Routes map[string]CORSPolicy `yaml:"routes,omitempty" json:"routes,omitempty"`
+}
+
+// SanitizeCORS trims CORS entries, lower-cases route keys and drops unknown route groups.
+func (cfg *Config) SanitizeCORS() {
+	if cfg == nil {
+		return
+	}
+	cfg.CORS.CORSPolicy = normalizeCORSPolicy(cfg.CORS.CORSPolicy)
+	if len(cfg.CORS.Routes) == 0 {
+		cfg.CORS.Routes = nil
+		return
+	}
+	routes := make(map[string]CORSPolicy, len(cfg.CORS.Routes))
+	for rawKey, policy := range cfg.CORS.Routes {
+		key := strings.ToLower(strings.TrimSpace(rawKey))
+		switch key {
+		case CORSRouteOpenAI, CORSRouteGemini, CORSRouteManagement, CORSRouteAmp:
+		default:
+			continue
+		}
+		routes[key] = normalizeCORSPolicy(policy)
+	}
+	if len(routes) == 0 {
+		routes = nil
+	}
+	cfg.CORS.Routes = routes
+}
+
+func normalizeCORSPolicy(policy CORSPolicy) CORSPolicy {
+	policy.AllowedOrigins = normalizeCORSList(policy.AllowedOrigins, false)
+	policy.AllowedMethods = normalizeCORSList(policy.AllowedMethods, true)
+	policy.AllowedHeaders = normalizeCORSList(policy.AllowedHeaders, false)
+	policy.ExposedHeaders = normalizeCORSList(policy.ExposedHeaders, false)
+	if policy.MaxAge < 0 {
+		policy.MaxAge = 0
+	}
+	return policy
+}
+
+func normalizeCORSList(values []string, upper bool) []string {
+	if len(values) == 0 {
+		return nil
+	}
+	seen := make(map[string]struct{}, len(values))
+	out := make([]string, 0, len(values))
+	for _, raw := range values {
+		trimmed := strings.TrimRight(strings.TrimSpace(raw), "/")
+		if trimmed == "" {
+			continue
+		}
+		if upper {
+			trimmed = strings.ToUpper(trimmed)
+		}
+		key := strings.ToLower(trimmed)
+		if _, exists := seen[key]; exists {
+			continue
+		}
+		seen[key] = struct{}{}
+		out = append(out, trimmed)
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
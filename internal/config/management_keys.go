@@ -0,0 +1,85 @@
+package config
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Management roles, from least to most privileged. Each role includes the permissions of
+// the roles before it.
+const (
+	// ManagementRoleViewer may read usage, logs and auth lists.
+	ManagementRoleViewer = "viewer"
+	// ManagementRoleOperator may additionally toggle auth status and start OAuth logins.
+	ManagementRoleOperator = "operator"
+	// ManagementRoleAdmin may use every management endpoint, including config and key edits.
+	ManagementRoleAdmin = "admin"
+)
+
+// ManagementKey is a named management API key limited to a role.
+type ManagementKey struct {
+	// Name identifies the caller in logs and the audit trail.
+	Name string `yaml:"name"`
+	// Key is the secret, either plaintext or a bcrypt hash.
+	Key string `yaml:"key"`
+	// Role is "viewer", "operator" or "admin".
+	Role string `yaml:"role"`
+}
+
+// Matches reports whether provided is this key's secret.
+func (k ManagementKey) Matches(provided string) bool {
+	if k.Key == "" || provided == "" {
+		return false
+	}
+	if looksLikeBcrypt(k.Key) {
+		return bcrypt.CompareHashAndPassword([]byte(k.Key), []byte(provided)) == nil
+	}
+	return subtle.ConstantTimeCompare([]byte(k.Key), []byte(provided)) == 1
+}
+
+// HasKeys reports whether the secret key or any role-based key is configured.
+func (r RemoteManagement) HasKeys() bool {
+	return r.SecretKey != "" || len(r.Keys) > 0
+}
+
+// ManagementRoleAllows reports whether role grants the permissions of required.
+func ManagementRoleAllows(role, required string) bool {
+	return managementRoleRank(role) >= managementRoleRank(required) && managementRoleRank(required) > 0
+}
+
+func managementRoleRank(role string) int {
+	switch role {
+	case ManagementRoleViewer:
+		return 1
+	case ManagementRoleOperator:
+		return 2
+	case ManagementRoleAdmin:
+		return 3
+	default:
+		return 0
+	}
+}
+
+// SanitizeManagementKeys trims names and keys, lowercases roles, drops entries without a key
+// or with an unknown role, and names unnamed keys after their position.
+func (cfg *Config) SanitizeManagementKeys() {
+	if cfg == nil || len(cfg.RemoteManagement.Keys) == 0 {
+		return
+	}
+	out := make([]ManagementKey, 0, len(cfg.RemoteManagement.Keys))
+	for i, key := range cfg.RemoteManagement.Keys {
+		key.Key = strings.TrimSpace(key.Key)
+		key.Role = strings.ToLower(strings.TrimSpace(key.Role))
+		if key.Key == "" || managementRoleRank(key.Role) == 0 {
+			continue
+		}
+		if key.Name = strings.TrimSpace(key.Name); key.Name == "" {
+			key.Name = fmt.Sprintf("management-key-%d", i+1)
+		}
+		out = append(out, key)
+	}
+	cfg.RemoteManagement.Keys = out
+}
@@ -0,0 +1,138 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Notification event types.
+const (
+	NotifyAuthCooldown       = "auth_cooldown"
+	NotifyAuthQuotaExceeded  = "auth_quota_exceeded"
+	NotifyAuthRefreshFailing = "auth_refresh_failing"
+	NotifyAuthInvalid        = "auth_invalid"
+	NotifyModelUnavailable   = "model_unavailable"
+	NotifyConfigReloadFailed = "config_reload_failed"
+)
+
+// NotifyEvents lists every notification event type.
+var NotifyEvents = []string{
+	NotifyAuthCooldown,
+	NotifyAuthQuotaExceeded,
+	NotifyAuthRefreshFailing,
+	NotifyAuthInvalid,
+	NotifyModelUnavailable,
+	NotifyConfigReloadFailed,
+}
+
+// Notification webhook payload formats.
+const (
+	NotifyFormatJSON  = "json"
+	NotifyFormatSlack = "slack"
+)
+
+// NotificationsConfig sends alerts about credential lifecycle events to webhooks.
+type NotificationsConfig struct {
+	// Enable turns notifications on.
+	Enable bool `yaml:"enable" json:"enable"`
+
+	// DedupWindowSeconds suppresses repeats of the same event for the same auth or model
+	// within this window. Defaults to 600.
+	DedupWindowSeconds int `yaml:"dedup-window-seconds,omitempty" json:"dedup-window-seconds,omitempty"`
+
+	// MaxPerMinute caps the notifications sent per minute across all events; the number of
+	// suppressed notifications is reported with the next one sent. Defaults to 20.
+	MaxPerMinute int `yaml:"max-per-minute,omitempty" json:"max-per-minute,omitempty"`
+
+	// RefreshFailureThreshold is the number of consecutive refresh failures that raises
+	// auth_refresh_failing. Defaults to 3.
+	RefreshFailureThreshold int `yaml:"refresh-failure-threshold,omitempty" json:"refresh-failure-threshold,omitempty"`
+
+	// Webhooks receive the notifications.
+	Webhooks []NotificationWebhook `yaml:"webhooks,omitempty" json:"webhooks,omitempty"`
+}
+
+// NotificationWebhook is one notification endpoint.
+type NotificationWebhook struct {
+	// Name identifies the webhook in logs. Defaults to the format.
+	Name string `yaml:"name,omitempty" json:"name,omitempty"`
+
+	// URL receives a POST per notification.
+	URL string `yaml:"url" json:"url"`
+
+	// Format is "json" (default) for the raw event or "slack" for a Slack-compatible
+	// {"text": ...} body, which most chat webhooks accept.
+	Format string `yaml:"format,omitempty" json:"format,omitempty"`
+
+	// Headers are added to every request.
+	Headers map[string]string `yaml:"headers,omitempty" json:"-"`
+
+	// Events limits the webhook to these event types. Empty sends every event.
+	Events []string `yaml:"events,omitempty" json:"events,omitempty"`
+
+	// TimeoutSeconds bounds each request. Defaults to 10.
+	TimeoutSeconds int `yaml:"timeout-seconds,omitempty" json:"timeout-seconds,omitempty"`
+}
+
+// SanitizeNotifications fills defaults, drops webhooks without a URL and unknown event
+// filters, and normalizes formats.
+func (cfg *Config) SanitizeNotifications() {
+	if cfg == nil {
+		return
+	}
+	n := &cfg.Notifications
+	if n.DedupWindowSeconds <= 0 {
+		n.DedupWindowSeconds = 600
+	}
+	if n.MaxPerMinute <= 0 {
+		n.MaxPerMinute = 20
+	}
+	if n.RefreshFailureThreshold <= 0 {
+		n.RefreshFailureThreshold = 3
+	}
+	if len(n.Webhooks) == 0 {
+		return
+	}
+	out := make([]NotificationWebhook, 0, len(n.Webhooks))
+	names := make(map[string]struct{}, len(n.Webhooks))
+	for _, hook := range n.Webhooks {
+		hook.URL = strings.TrimSpace(hook.URL)
+		if hook.URL == "" {
+			continue
+		}
+		hook.Format = strings.ToLower(strings.TrimSpace(hook.Format))
+		if hook.Format != NotifyFormatSlack {
+			hook.Format = NotifyFormatJSON
+		}
+		hook.Name = strings.TrimSpace(hook.Name)
+		if hook.Name == "" {
+			hook.Name = hook.Format
+		}
+		if _, dup := names[hook.Name]; dup {
+			hook.Name = fmt.Sprintf("%s-%d", hook.Name, len(out)+1)
+		}
+		names[hook.Name] = struct{}{}
+		events := make([]string, 0, len(hook.Events))
+		for _, event := range hook.Events {
+			event = strings.ToLower(strings.TrimSpace(event))
+			if knownNotifyEvent(event) {
+				events = append(events, event)
+			}
+		}
+		hook.Events = events
+		if hook.TimeoutSeconds <= 0 {
+			hook.TimeoutSeconds = 10
+		}
+		out = append(out, hook)
+	}
+	n.Webhooks = out
+}
+
+func knownNotifyEvent(event string) bool {
+	for _, known := range NotifyEvents {
+		if known == event {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package config
+
+import "strings"
+
+// Supported tracing exporters.
+const (
+	TracingExporterOTLPHTTP = "otlp-http"
+	TracingExporterOTLPGRPC = "otlp-grpc"
+	TracingExporterStdout   = "stdout"
+)
+
+// DefaultTracingServiceName is reported as service.name when none is configured.
+const DefaultTracingServiceName = "cli-proxy-api"
+
+// TracingConfig controls OpenTelemetry span export.
+type TracingConfig struct {
+	// Enable turns on request tracing.
+	Enable bool `yaml:"enable" json:"enable"`
+	// Exporter selects where spans are sent: "otlp-http" (default), "otlp-grpc" or "stdout".
+	Exporter string `yaml:"exporter,omitempty" json:"exporter,omitempty"`
+	// Endpoint is the collector address, e.g. "http://localhost:4318" for otlp-http or
+	// "localhost:4317" for otlp-grpc. Defaults to the standard local collector port.
+	Endpoint string `yaml:"endpoint,omitempty" json:"endpoint,omitempty"`
+	// Insecure disables TLS for otlp-grpc endpoints given without a scheme.
+	Insecure bool `yaml:"insecure,omitempty" json:"insecure,omitempty"`
+	// Headers are sent with every export request (e.g. collector API keys).
+	Headers map[string]string `yaml:"headers,omitempty" json:"-"`
+	// ServiceName is reported as the service.name resource attribute.
+	ServiceName string `yaml:"service-name,omitempty" json:"service-name,omitempty"`
+	// SampleRatio is the fraction of new traces to record (0 < ratio <= 1). Requests that
+	// arrive with a W3C traceparent follow the caller's sampling decision instead.
+	SampleRatio float64 `yaml:"sample-ratio,omitempty" json:"sample-ratio,omitempty"`
+}
+
+// SanitizeTracing normalizes the exporter name and fills tracing defaults.
+func (cfg *Config) SanitizeTracing() {
+	if cfg == nil {
+		return
+	}
+	t := &cfg.Tracing
+	t.Exporter = strings.ToLower(strings.TrimSpace(t.Exporter))
+	switch t.Exporter {
+	case TracingExporterOTLPHTTP, TracingExporterOTLPGRPC, TracingExporterStdout:
+	case "otlp", "http":
+		t.Exporter = TracingExporterOTLPHTTP
+	case "grpc":
+		t.Exporter = TracingExporterOTLPGRPC
+	default:
+		t.Exporter = TracingExporterOTLPHTTP
+	}
+	t.Endpoint = strings.TrimSpace(t.Endpoint)
+	if t.Endpoint == "" {
+		switch t.Exporter {
+		case TracingExporterOTLPHTTP:
+			t.Endpoint = "http://localhost:4318"
+		case TracingExporterOTLPGRPC:
+			t.Endpoint = "localhost:4317"
+		}
+	}
+	t.ServiceName = strings.TrimSpace(t.ServiceName)
+	if t.ServiceName == "" {
+		t.ServiceName = DefaultTracingServiceName
+	}
+	if t.SampleRatio <= 0 || t.SampleRatio > 1 {
+		t.SampleRatio = 1
+	}
+	if len(t.Headers) == 0 {
+		t.Headers = nil
+	}
+}
@@ -0,0 +1,136 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Usage sink types.
+const (
+	UsageSinkFile    = "file"
+	UsageSinkWebhook = "webhook"
+	UsageSinkStdout  = "stdout"
+)
+
+// API key redaction modes for usage sinks.
+const (
+	UsageSinkRedactNone = "none"
+	UsageSinkRedactMask = "mask"
+	UsageSinkRedactHash = "hash"
+	UsageSinkRedactDrop = "drop"
+)
+
+// UsageSink forwards every usage record to a file, an HTTP endpoint or stdout.
+type UsageSink struct {
+	// Name identifies the sink in logs and default file names. Defaults to the type.
+	Name string `yaml:"name,omitempty" json:"name,omitempty"`
+
+	// Type is "file", "webhook" or "stdout".
+	Type string `yaml:"type" json:"type"`
+
+	// Fields lists the record fields to emit, in order. Empty emits every field.
+	Fields []string `yaml:"fields,omitempty" json:"fields,omitempty"`
+
+	// APIKeyRedaction controls how the client API key is written: "mask" (default) keeps the
+	// first and last characters, "hash" writes a SHA-256 digest, "drop" omits it and "none"
+	// writes it as is.
+	APIKeyRedaction string `yaml:"api-key-redaction,omitempty" json:"api-key-redaction,omitempty"`
+
+	// Path is the JSONL file of a file sink. Defaults to usage-<name>.jsonl in the logs directory.
+	Path string `yaml:"path,omitempty" json:"path,omitempty"`
+	// MaxSizeMB rotates the file once it reaches this size. Defaults to 100.
+	MaxSizeMB int `yaml:"max-size-mb,omitempty" json:"max-size-mb,omitempty"`
+	// MaxBackups limits the rotated files kept; zero keeps them all.
+	MaxBackups int `yaml:"max-backups,omitempty" json:"max-backups,omitempty"`
+	// MaxAgeDays removes rotated files older than this; zero keeps them regardless of age.
+	MaxAgeDays int `yaml:"max-age-days,omitempty" json:"max-age-days,omitempty"`
+	// Compress gzips rotated files.
+	Compress bool `yaml:"compress,omitempty" json:"compress,omitempty"`
+
+	// URL receives webhook batches as a JSON array in a POST body.
+	URL string `yaml:"url,omitempty" json:"url,omitempty"`
+	// Headers are added to every webhook request.
+	Headers map[string]string `yaml:"headers,omitempty" json:"-"`
+	// BatchSize sends a batch once it holds this many records. Defaults to 100.
+	BatchSize int `yaml:"batch-size,omitempty" json:"batch-size,omitempty"`
+	// FlushIntervalSeconds sends a partial batch after this long. Defaults to 5.
+	FlushIntervalSeconds int `yaml:"flush-interval-seconds,omitempty" json:"flush-interval-seconds,omitempty"`
+	// MaxRetries retries a failed batch with exponential backoff before spooling it. Defaults to 5.
+	MaxRetries int `yaml:"max-retries,omitempty" json:"max-retries,omitempty"`
+	// TimeoutSeconds bounds each webhook request. Defaults to 10.
+	TimeoutSeconds int `yaml:"timeout-seconds,omitempty" json:"timeout-seconds,omitempty"`
+	// SpoolDir keeps undelivered batches until the endpoint recovers. Defaults to
+	// usage-spool/<name> in the logs directory.
+	SpoolDir string `yaml:"spool-dir,omitempty" json:"spool-dir,omitempty"`
+	// MaxSpoolMB drops the oldest spooled batches beyond this size. Defaults to 100.
+	MaxSpoolMB int `yaml:"max-spool-mb,omitempty" json:"max-spool-mb,omitempty"`
+}
+
+// SanitizeUsageSinks normalizes sink types and names, fills defaults and drops sinks
+// with an unknown type or a webhook without a URL.
+func (cfg *Config) SanitizeUsageSinks() {
+	if cfg == nil || len(cfg.UsageSinks) == 0 {
+		return
+	}
+	out := make([]UsageSink, 0, len(cfg.UsageSinks))
+	names := make(map[string]struct{}, len(cfg.UsageSinks))
+	for _, sink := range cfg.UsageSinks {
+		sink.Type = strings.ToLower(strings.TrimSpace(sink.Type))
+		sink.URL = strings.TrimSpace(sink.URL)
+		sink.Path = strings.TrimSpace(sink.Path)
+		sink.SpoolDir = strings.TrimSpace(sink.SpoolDir)
+		switch sink.Type {
+		case UsageSinkFile, UsageSinkStdout:
+		case UsageSinkWebhook:
+			if sink.URL == "" {
+				continue
+			}
+		default:
+			continue
+		}
+		sink.Name = strings.TrimSpace(sink.Name)
+		if sink.Name == "" {
+			sink.Name = sink.Type
+		}
+		if _, dup := names[sink.Name]; dup {
+			sink.Name = fmt.Sprintf("%s-%d", sink.Name, len(out)+1)
+		}
+		names[sink.Name] = struct{}{}
+
+		fields := make([]string, 0, len(sink.Fields))
+		for _, field := range sink.Fields {
+			if field = strings.ToLower(strings.TrimSpace(field)); field != "" {
+				fields = append(fields, field)
+			}
+		}
+		sink.Fields = fields
+		sink.APIKeyRedaction = strings.ToLower(strings.TrimSpace(sink.APIKeyRedaction))
+		switch sink.APIKeyRedaction {
+		case UsageSinkRedactNone, UsageSinkRedactHash, UsageSinkRedactDrop:
+		default:
+			sink.APIKeyRedaction = UsageSinkRedactMask
+		}
+		if sink.MaxSizeMB <= 0 {
+			sink.MaxSizeMB = 100
+		}
+		if sink.BatchSize <= 0 {
+			sink.BatchSize = 100
+		}
+		if sink.FlushIntervalSeconds <= 0 {
+			sink.FlushIntervalSeconds = 5
+		}
+		if sink.MaxRetries < 0 {
+			sink.MaxRetries = 0
+		} else if sink.MaxRetries == 0 {
+			sink.MaxRetries = 5
+		}
+		if sink.TimeoutSeconds <= 0 {
+			sink.TimeoutSeconds = 10
+		}
+		if sink.MaxSpoolMB <= 0 {
+			sink.MaxSpoolMB = 100
+		}
+		out = append(out, sink)
+	}
+	cfg.UsageSinks = out
+}
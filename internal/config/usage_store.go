@@ -0,0 +1,82 @@
+package config
+
+import "strings"
+
+// Usage store drivers.
+const (
+	UsageStoreDriverSQLite   = "sqlite"
+	UsageStoreDriverPostgres = "postgres"
+)
+
+// Default retention windows for the persistent usage store, in days.
+const (
+	DefaultUsageRawRetentionDays    = 30
+	DefaultUsageHourlyRetentionDays = 180
+	DefaultUsageDailyRetentionDays  = 730
+)
+
+// DefaultUsageSnapshotDetails is how many recent raw records a usage snapshot lists.
+const DefaultUsageSnapshotDetails = 1000
+
+// UsageStoreConfig persists usage statistics in SQLite or Postgres instead of memory.
+type UsageStoreConfig struct {
+	// Enable switches usage statistics to the persistent store.
+	Enable bool `yaml:"enable" json:"enable"`
+
+	// Driver is "sqlite" or "postgres". When empty, postgres is used if PGSTORE_DSN (or DSN)
+	// is set and sqlite otherwise.
+	Driver string `yaml:"driver,omitempty" json:"driver,omitempty"`
+
+	// Path is the SQLite database file. Defaults to .usage.db inside auth-dir, which the
+	// remote token stores never mirror.
+	Path string `yaml:"path,omitempty" json:"path,omitempty"`
+
+	// DSN is the Postgres connection string. Defaults to PGSTORE_DSN.
+	DSN string `yaml:"dsn,omitempty" json:"-"`
+
+	// RawRetentionDays keeps individual request records this long. Zero uses the default
+	// of 30 days; a negative value keeps them forever.
+	RawRetentionDays int `yaml:"raw-retention-days,omitempty" json:"raw-retention-days,omitempty"`
+
+	// HourlyRetentionDays keeps hourly aggregates this long. Zero uses the default of 180
+	// days; a negative value keeps them forever.
+	HourlyRetentionDays int `yaml:"hourly-retention-days,omitempty" json:"hourly-retention-days,omitempty"`
+
+	// DailyRetentionDays keeps daily aggregates this long. Zero uses the default of 730
+	// days; a negative value keeps them forever.
+	DailyRetentionDays int `yaml:"daily-retention-days,omitempty" json:"daily-retention-days,omitempty"`
+
+	// SnapshotDetails caps the per-request details returned by the usage snapshot to the
+	// most recent records. Totals always come from the rollups. Zero uses the default of
+	// 1000; a negative value returns every raw record still in retention.
+	SnapshotDetails int `yaml:"snapshot-details,omitempty" json:"snapshot-details,omitempty"`
+}
+
+// SanitizeUsageStore normalizes the driver name and fills retention defaults.
+func (cfg *Config) SanitizeUsageStore() {
+	if cfg == nil {
+		return
+	}
+	s := &cfg.UsageStore
+	s.Driver = strings.ToLower(strings.TrimSpace(s.Driver))
+	switch s.Driver {
+	case "sqlite3":
+		s.Driver = UsageStoreDriverSQLite
+	case "postgresql", "pg", "pgx":
+		s.Driver = UsageStoreDriverPostgres
+	}
+	s.Path = strings.TrimSpace(s.Path)
+	s.DSN = strings.TrimSpace(s.DSN)
+	if s.RawRetentionDays == 0 {
+		s.RawRetentionDays = DefaultUsageRawRetentionDays
+	}
+	if s.HourlyRetentionDays == 0 {
+		s.HourlyRetentionDays = DefaultUsageHourlyRetentionDays
+	}
+	if s.DailyRetentionDays == 0 {
+		s.DailyRetentionDays = DefaultUsageDailyRetentionDays
+	}
+	if s.SnapshotDetails == 0 {
+		s.SnapshotDetails = DefaultUsageSnapshotDetails
+	}
+}
@@ -0,0 +1,27 @@
+package config
+
+import "testing"
+
+func TestMatchWildcard(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		pattern, value string
+		want           bool
+	}{
+		{"gpt-5", "gpt-5", true},
+		{"gpt-5", "gpt-5-mini", false},
+		{"gpt-*", "gpt-5", true},
+		{"*-mini", "gpt-5-mini", true},
+		{"gemini-*-pro", "gemini-2.5-pro", true},
+		{"gemini-*-pro", "gemini-2.5-flash", false},
+		{"*", "anything", true},
+		{"a*a", "a", false},
+		{"", "", false},
+	}
+	for _, tc := range cases {
+		if got := MatchWildcard(tc.pattern, tc.value); got != tc.want {
+			t.Errorf("MatchWildcard(%q, %q) = %v, want %v", tc.pattern, tc.value, got, tc.want)
+		}
+	}
+}
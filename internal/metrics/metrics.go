@@ -0,0 +1,213 @@
+// Package metrics collects request, token and credential metrics and exposes them to
+// Prometheus.
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/thinking"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+)
+
+// unknownModel labels requests whose model is not registered, so client-supplied model names
+// cannot grow the number of series.
+const unknownModel = "unknown"
+
+var (
+	durationBuckets  = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300}
+	firstByteBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2, 5, 10, 30}
+
+	metricsRegistry = prometheus.NewRegistry()
+
+	requestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cliproxy_requests_total",
+		Help: "Inbound API requests by inbound format, model, serving provider and HTTP status.",
+	}, []string{"format", "model", "provider", "status"})
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "cliproxy_request_duration_seconds",
+		Help:    "Inbound API request latency, including the full stream for streaming requests.",
+		Buckets: durationBuckets,
+	}, []string{"format", "model", "provider", "status"})
+	streamFirstByte = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "cliproxy_stream_first_byte_seconds",
+		Help:    "Time until the first upstream chunk of a streaming request.",
+		Buckets: firstByteBuckets,
+	}, []string{"format", "model", "provider"})
+	tokensTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cliproxy_tokens_total",
+		Help: "Tokens reported by upstream providers by type (input, output, reasoning, cached).",
+	}, []string{"provider", "model", "type"})
+	retriesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cliproxy_upstream_retries_total",
+		Help: "Upstream attempts retried on another credential, by the failed attempt's provider and status.",
+	}, []string{"provider", "status"})
+	upstreamErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cliproxy_upstream_errors_total",
+		Help: "Failed upstream attempts by provider and HTTP status (0 when no status was received).",
+	}, []string{"provider", "status"})
+	refreshTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cliproxy_auth_refresh_total",
+		Help: "Credential refreshes by provider and result (success or failure).",
+	}, []string{"provider", "result"})
+
+	authLabels        = []string{"auth_id", "provider"}
+	authAvailableDesc = prometheus.NewDesc("cliproxy_auth_available",
+		"1 when the credential is enabled and not cooling down.", authLabels, nil)
+	authCooldownDesc = prometheus.NewDesc("cliproxy_auth_cooldown_seconds",
+		"Seconds until the credential's longest cooldown ends.", authLabels, nil)
+	authCoolingModelsDesc = prometheus.NewDesc("cliproxy_auth_cooling_models",
+		"Models currently cooling down on the credential.", authLabels, nil)
+	authInFlightDesc = prometheus.NewDesc("cliproxy_auth_in_flight",
+		"Requests currently executing on the credential.", authLabels, nil)
+)
+
+func init() {
+	metricsRegistry.MustRegister(requestsTotal, requestDuration, streamFirstByte, tokensTotal,
+		retriesTotal, upstreamErrorsTotal, refreshTotal)
+	coreusage.RegisterPlugin(usagePlugin{})
+}
+
+// Middleware attaches request stats to every request and records request metrics for
+// requests that reached an API handler.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		stats := coreauth.NewRequestStats()
+		c.Request = c.Request.WithContext(coreauth.WithRequestStats(c.Request.Context(), stats))
+		start := time.Now()
+
+		c.Next()
+
+		observeRequest(stats.Summary(), c.Writer.Status(), time.Since(start))
+	}
+}
+
+func observeRequest(summary coreauth.RequestSummary, status int, elapsed time.Duration) {
+	if summary.Format == "" {
+		return
+	}
+	model := modelLabel(summary.Model)
+	provider := ""
+	var firstByte time.Duration
+	for i, attempt := range summary.Attempts {
+		provider = attempt.Provider
+		if !attempt.Success {
+			upstreamErrorsTotal.WithLabelValues(attempt.Provider, strconv.Itoa(attempt.Status)).Inc()
+			if i < len(summary.Attempts)-1 {
+				retriesTotal.WithLabelValues(attempt.Provider, strconv.Itoa(attempt.Status)).Inc()
+			}
+		} else if firstByte == 0 {
+			firstByte = attempt.FirstByte
+		}
+	}
+	statusLabel := strconv.Itoa(status)
+	requestsTotal.WithLabelValues(summary.Format, model, provider, statusLabel).Inc()
+	requestDuration.WithLabelValues(summary.Format, model, provider, statusLabel).Observe(elapsed.Seconds())
+	if summary.Stream && firstByte > 0 {
+		streamFirstByte.WithLabelValues(summary.Format, model, provider).Observe(firstByte.Seconds())
+	}
+}
+
+// modelLabel returns model without its thinking suffix, or unknownModel when no registered
+// credential serves it, so clients cannot mint new series per model name or suffix value.
+func modelLabel(model string) string {
+	models := registry.GetGlobalRegistry()
+	if base := thinking.ParseSuffix(model).ModelName; base != "" && len(models.GetModelProviders(base)) > 0 {
+		return base
+	} else if model != base && len(models.GetModelProviders(model)) > 0 {
+		return model
+	}
+	return unknownModel
+}
+
+// ObserveAuthEvent counts credential refresh outcomes. Register it with
+// coreauth.Manager.AddEventListener.
+func ObserveAuthEvent(_ context.Context, event coreauth.Event) {
+	switch event.Type {
+	case coreauth.EventRefreshSucceeded:
+		refreshTotal.WithLabelValues(event.Provider, "success").Inc()
+	case coreauth.EventRefreshFailed:
+		refreshTotal.WithLabelValues(event.Provider, "failure").Inc()
+	}
+}
+
+type usagePlugin struct{}
+
+// HandleUsage implements coreusage.Plugin.
+func (usagePlugin) HandleUsage(_ context.Context, record coreusage.Record) {
+	model := modelLabel(record.Model)
+	for _, tokens := range []struct {
+		kind  string
+		count int64
+	}{
+		{"input", record.Detail.InputTokens},
+		{"output", record.Detail.OutputTokens},
+		{"reasoning", record.Detail.ReasoningTokens},
+		{"cached", record.Detail.CachedTokens},
+	} {
+		if tokens.count > 0 {
+			tokensTotal.WithLabelValues(record.Provider, model, tokens.kind).Add(float64(tokens.count))
+		}
+	}
+}
+
+// Handler serves every metric in a Prometheus exposition format, including per-auth gauges
+// read from manager at scrape time.
+func Handler(manager *coreauth.Manager) http.Handler {
+	gatherers := prometheus.Gatherers{metricsRegistry}
+	if manager != nil {
+		auths := prometheus.NewRegistry()
+		auths.MustRegister(authCollector{manager: manager})
+		gatherers = append(gatherers, auths)
+	}
+	return promhttp.HandlerFor(gatherers, promhttp.HandlerOpts{})
+}
+
+// authCollector reports credential availability gauges from the auth manager.
+type authCollector struct {
+	manager *coreauth.Manager
+}
+
+// Describe implements prometheus.Collector.
+func (c authCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- authAvailableDesc
+	ch <- authCooldownDesc
+	ch <- authCoolingModelsDesc
+	ch <- authInFlightDesc
+}
+
+// Collect implements prometheus.Collector.
+func (c authCollector) Collect(ch chan<- prometheus.Metric) {
+	now := time.Now()
+	for _, auth := range c.manager.List() {
+		if auth == nil {
+			continue
+		}
+		remaining := time.Duration(0)
+		if auth.Unavailable && auth.NextRetryAfter.After(now) {
+			remaining = auth.NextRetryAfter.Sub(now)
+		}
+		cooling := 0
+		for _, state := range auth.ModelStates {
+			if state != nil && state.Unavailable && state.NextRetryAfter.After(now) {
+				cooling++
+				remaining = max(remaining, state.NextRetryAfter.Sub(now))
+			}
+		}
+		up := 0.0
+		if !auth.Disabled && !(auth.Unavailable && auth.NextRetryAfter.After(now)) {
+			up = 1
+		}
+		ch <- prometheus.MustNewConstMetric(authAvailableDesc, prometheus.GaugeValue, up, auth.ID, auth.Provider)
+		ch <- prometheus.MustNewConstMetric(authCooldownDesc, prometheus.GaugeValue, remaining.Seconds(), auth.ID, auth.Provider)
+		ch <- prometheus.MustNewConstMetric(authCoolingModelsDesc, prometheus.GaugeValue, float64(cooling), auth.ID, auth.Provider)
+		ch <- prometheus.MustNewConstMetric(authInFlightDesc, prometheus.GaugeValue, float64(c.manager.InFlight(auth.ID)), auth.ID, auth.Provider)
+	}
+}
@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+)
+
+func TestWriteExposesObservedMetrics(t *testing.T) {
+	registry.GetGlobalRegistry().RegisterClient("metrics-test", "claude", []*registry.ModelInfo{{ID: "test-model"}})
+	defer registry.GetGlobalRegistry().UnregisterClient("metrics-test")
+
+	observeRequest(coreauth.RequestSummary{
+		Format: "openai",
+		Model:  "test-model(8192)",
+		Stream: true,
+		Attempts: []coreauth.Attempt{
+			{AuthID: "a1", Provider: "claude", Status: 429},
+			{AuthID: "a2", Provider: "claude", Success: true, FirstByte: 300 * time.Millisecond},
+		},
+	}, 200, 2*time.Second)
+	observeRequest(coreauth.RequestSummary{}, 404, time.Second)
+	observeRequest(coreauth.RequestSummary{Format: "openai", Model: "client-garbage-model"}, 502, time.Second)
+	usagePlugin{}.HandleUsage(context.Background(), coreusage.Record{
+		Provider: "claude",
+		Model:    "test-model(high)",
+		Detail:   coreusage.Detail{InputTokens: 10, OutputTokens: 5},
+	})
+	ObserveAuthEvent(context.Background(), coreauth.Event{Type: coreauth.EventRefreshFailed, Provider: "claude"})
+
+	manager := coreauth.NewManager(nil, nil, nil)
+	if _, err := manager.Register(context.Background(), &coreauth.Auth{ID: "a1", Provider: "claude"}); err != nil {
+		t.Fatalf("register auth: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	Handler(manager).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Handler() status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	out := rec.Body.String()
+	for _, want := range []string{
+		`cliproxy_requests_total{format="openai",model="test-model",provider="claude",status="200"} 1`,
+		`cliproxy_request_duration_seconds_bucket{format="openai",model="test-model",provider="claude",status="200",le="2.5"} 1`,
+		`cliproxy_stream_first_byte_seconds_count{format="openai",model="test-model",provider="claude"} 1`,
+		`cliproxy_upstream_errors_total{provider="claude",status="429"} 1`,
+		`cliproxy_upstream_retries_total{provider="claude",status="429"} 1`,
+		`cliproxy_tokens_total{model="test-model",provider="claude",type="input"} 10`,
+		`cliproxy_auth_refresh_total{provider="claude",result="failure"} 1`,
+		`cliproxy_auth_available{auth_id="a1",provider="claude"} 1`,
+		`cliproxy_auth_in_flight{auth_id="a1",provider="claude"} 0`,
+		`cliproxy_requests_total{format="openai",model="unknown",provider="",status="502"} 1`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q", want)
+		}
+	}
+	if strings.Contains(out, `status="404"`) {
+		t.Errorf("request without an API handler was counted:\n%s", out)
+	}
+	if strings.Contains(out, "client-garbage-model") {
+		t.Errorf("unregistered model became a label value:\n%s", out)
+	}
+	if strings.Contains(out, "test-model(") {
+		t.Errorf("model label kept its thinking suffix:\n%s", out)
+	}
+}
@@ -0,0 +1,388 @@
+// Package notify alerts webhooks about credential lifecycle events: auths entering cooldown
+// or exceeding quota, failing refreshes, credentials rejected as invalid, models left without
+// an available credential and failed config reloads. Repeated alerts are deduplicated and the
+// overall rate is capped so an outage does not flood the receiving channel.
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	queueSize      = 64
+	maxDedupKeys   = 4096
+	rateLimitReset = time.Minute
+)
+
+// Notification is the JSON body posted to webhooks in the json format.
+type Notification struct {
+	Event      string     `json:"event"`
+	Time       time.Time  `json:"time"`
+	AuthID     string     `json:"auth_id,omitempty"`
+	Provider   string     `json:"provider,omitempty"`
+	Model      string     `json:"model,omitempty"`
+	Message    string     `json:"message,omitempty"`
+	StatusCode int        `json:"status_code,omitempty"`
+	RetryAt    *time.Time `json:"retry_at,omitempty"`
+	Failures   int        `json:"failures,omitempty"`
+	// Suppressed counts the notifications dropped by the rate limit since the previous one.
+	Suppressed int `json:"suppressed,omitempty"`
+}
+
+func (n Notification) dedupKey() string {
+	return n.Event + "|" + n.AuthID + "|" + n.Provider + "|" + n.Model
+}
+
+type delivery struct {
+	notification Notification
+	webhooks     []*webhook
+}
+
+var defaultNotifier = NewNotifier()
+
+// Default returns the process-wide notifier.
+func Default() *Notifier { return defaultNotifier }
+
+// Notifier turns auth results, updates and events into webhook notifications. It implements
+// coreauth.Hook and its HandleEvent method is a coreauth.EventListener.
+type Notifier struct {
+	mu       sync.Mutex
+	cfg      config.NotificationsConfig
+	webhooks []*webhook
+	lookup   func(id string) (*coreauth.Auth, bool)
+
+	lastSent    map[string]time.Time
+	windowStart time.Time
+	sent        int
+	suppressed  int
+
+	queue chan delivery
+	stop  chan struct{}
+	done  chan struct{}
+
+	now func() time.Time
+}
+
+// NewNotifier returns a disabled notifier.
+func NewNotifier() *Notifier {
+	return &Notifier{lastSent: make(map[string]time.Time), now: time.Now}
+}
+
+// SetConfig applies cfg.Notifications and starts the delivery worker when notifications
+// are enabled.
+func (n *Notifier) SetConfig(cfg *config.Config) {
+	if n == nil {
+		return
+	}
+	var next config.NotificationsConfig
+	if cfg != nil {
+		next = cfg.Notifications
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if reflect.DeepEqual(next, n.cfg) && (n.queue != nil || !next.Enable) {
+		return
+	}
+	n.cfg = next
+	n.webhooks = n.webhooks[:0:0]
+	for _, hookCfg := range next.Webhooks {
+		n.webhooks = append(n.webhooks, newWebhook(hookCfg))
+	}
+	if next.Enable && len(n.webhooks) > 0 && n.queue == nil {
+		n.queue = make(chan delivery, queueSize)
+		n.stop = make(chan struct{})
+		n.done = make(chan struct{})
+		go n.run(n.queue, n.stop, n.done)
+	}
+}
+
+// SetAuthLookup lets the notifier read the cooldown an auth result produced, so alerts carry
+// the retry time and skip failures that did not cool the auth down.
+func (n *Notifier) SetAuthLookup(lookup func(id string) (*coreauth.Auth, bool)) {
+	if n == nil {
+		return
+	}
+	n.mu.Lock()
+	n.lookup = lookup
+	n.mu.Unlock()
+}
+
+// Close delivers the queued notifications and stops the worker.
+func (n *Notifier) Close() {
+	if n == nil {
+		return
+	}
+	n.mu.Lock()
+	stop, done := n.stop, n.done
+	n.queue, n.stop, n.done = nil, nil, nil
+	n.mu.Unlock()
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+}
+
+func (n *Notifier) run(queue chan delivery, stop, done chan struct{}) {
+	defer close(done)
+	for {
+		select {
+		case d := <-queue:
+			d.send()
+		case <-stop:
+			for {
+				select {
+				case d := <-queue:
+					d.send()
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (d delivery) send() {
+	for _, hook := range d.webhooks {
+		if hook.accepts(d.notification.Event) {
+			hook.send(d.notification)
+		}
+	}
+}
+
+// Notify queues notification for delivery unless notifications are disabled, the same
+// event was sent for the same auth or model within the dedup window, or the per-minute
+// limit is reached.
+func (n *Notifier) Notify(notification Notification) {
+	if n == nil {
+		return
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if !n.cfg.Enable || n.queue == nil {
+		return
+	}
+	now := n.now()
+	if notification.Time.IsZero() {
+		notification.Time = now
+	}
+	key := notification.dedupKey()
+	window := time.Duration(n.cfg.DedupWindowSeconds) * time.Second
+	if last, ok := n.lastSent[key]; ok && now.Sub(last) < window {
+		return
+	}
+	if now.Sub(n.windowStart) >= rateLimitReset {
+		n.windowStart = now
+		n.sent = 0
+	}
+	if n.sent >= n.cfg.MaxPerMinute {
+		n.suppressed++
+		return
+	}
+	n.sent++
+	notification.Suppressed = n.suppressed
+	n.suppressed = 0
+	n.remember(key, now, window)
+
+	select {
+	case n.queue <- delivery{notification: notification, webhooks: n.webhooks}:
+	default:
+		log.Warnf("notify: queue full, dropping %s notification", notification.Event)
+	}
+}
+
+// remember records key as sent and forgets expired keys once the table grows large.
+func (n *Notifier) remember(key string, now time.Time, window time.Duration) {
+	if len(n.lastSent) >= maxDedupKeys {
+		for k, sent := range n.lastSent {
+			if now.Sub(sent) >= window {
+				delete(n.lastSent, k)
+			}
+		}
+	}
+	n.lastSent[key] = now
+}
+
+// OnAuthRegistered implements coreauth.Hook.
+func (n *Notifier) OnAuthRegistered(context.Context, *coreauth.Auth) {}
+
+// OnAuthUpdated raises auth_invalid when an update leaves the auth failing with an
+// invalid_grant error, which refresh and management paths record on the auth itself.
+func (n *Notifier) OnAuthUpdated(_ context.Context, auth *coreauth.Auth) {
+	if auth == nil || (auth.Status != coreauth.StatusError && !auth.Unavailable) {
+		return
+	}
+	message := auth.StatusMessage
+	if auth.LastError != nil {
+		message = auth.LastError.Message
+		if isInvalidGrant(auth.LastError.Code) {
+			message = auth.LastError.Code + ": " + message
+		}
+	}
+	if !isInvalidGrant(message) {
+		return
+	}
+	n.Notify(Notification{Event: config.NotifyAuthInvalid, AuthID: auth.ID, Provider: auth.Provider, Message: message})
+}
+
+// OnResult raises auth_invalid for 401 responses and auth_quota_exceeded or auth_cooldown
+// when a failed request put the auth, or one of its models, into cooldown.
+func (n *Notifier) OnResult(_ context.Context, result coreauth.Result) {
+	if n == nil || result.Success || result.Error == nil {
+		return
+	}
+	status := result.Error.HTTPStatus
+	notification := Notification{
+		AuthID:     result.AuthID,
+		Provider:   result.Provider,
+		Model:      result.Model,
+		Message:    result.Error.Message,
+		StatusCode: status,
+	}
+	if status == http.StatusUnauthorized || isInvalidGrant(result.Error.Code) || isInvalidGrant(result.Error.Message) {
+		notification.Event = config.NotifyAuthInvalid
+		notification.Model = ""
+		n.Notify(notification)
+		return
+	}
+
+	n.mu.Lock()
+	lookup := n.lookup
+	n.mu.Unlock()
+	var retryAt time.Time
+	quota := status == http.StatusTooManyRequests
+	if lookup != nil {
+		auth, ok := lookup(result.AuthID)
+		if !ok || auth == nil {
+			return
+		}
+		retryAt = auth.NextRetryAfter
+		if result.Model != "" {
+			state := auth.ModelStates[result.Model]
+			if state == nil {
+				return
+			}
+			retryAt = state.NextRetryAfter
+		}
+		if !retryAt.After(n.now()) {
+			// The failure did not cool the auth down, e.g. cooling is disabled.
+			return
+		}
+	} else {
+		switch {
+		case quota:
+			if result.RetryAfter != nil {
+				retryAt = n.now().Add(*result.RetryAfter)
+			}
+		case status == http.StatusPaymentRequired || status == http.StatusForbidden || status == http.StatusNotFound,
+			status == http.StatusRequestTimeout || status >= http.StatusInternalServerError:
+		default:
+			return
+		}
+	}
+	notification.Event = config.NotifyAuthCooldown
+	if quota {
+		notification.Event = config.NotifyAuthQuotaExceeded
+	}
+	if !retryAt.IsZero() {
+		notification.RetryAt = &retryAt
+	}
+	n.Notify(notification)
+}
+
+// HandleEvent raises auth_refresh_failing once an auth's refresh failed the configured
+// number of times in a row, auth_invalid when a refresh was rejected with invalid_grant,
+// and model_unavailable when no credential can serve a model.
+func (n *Notifier) HandleEvent(_ context.Context, event coreauth.Event) {
+	if n == nil {
+		return
+	}
+	switch event.Type {
+	case coreauth.EventRefreshFailed:
+		notification := Notification{
+			Time:     event.Time,
+			AuthID:   event.AuthID,
+			Provider: event.Provider,
+			Message:  event.LastError,
+			Failures: event.Failures,
+		}
+		if isInvalidGrant(event.LastError) {
+			notification.Event = config.NotifyAuthInvalid
+			n.Notify(notification)
+			return
+		}
+		n.mu.Lock()
+		threshold := n.cfg.RefreshFailureThreshold
+		n.mu.Unlock()
+		if event.Failures < threshold {
+			return
+		}
+		notification.Event = config.NotifyAuthRefreshFailing
+		n.Notify(notification)
+	case coreauth.EventModelUnavailable:
+		notification := Notification{
+			Event:    config.NotifyModelUnavailable,
+			Time:     event.Time,
+			Provider: event.Provider,
+			Model:    event.Key,
+			Message:  event.Message,
+		}
+		if !event.RetryAt.IsZero() {
+			retryAt := event.RetryAt
+			notification.RetryAt = &retryAt
+		}
+		n.Notify(notification)
+	}
+}
+
+// ConfigReloadFailed raises config_reload_failed.
+func (n *Notifier) ConfigReloadFailed(err error) {
+	if err == nil {
+		return
+	}
+	n.Notify(Notification{Event: config.NotifyConfigReloadFailed, Message: err.Error()})
+}
+
+func isInvalidGrant(text string) bool {
+	return strings.Contains(strings.ToLower(text), "invalid_grant")
+}
+
+// summary renders notification as one line of text for chat webhooks.
+func (n Notification) summary() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[CLIProxyAPI] %s", n.Event)
+	if n.AuthID != "" {
+		fmt.Fprintf(&b, " auth=%s", n.AuthID)
+	}
+	if n.Provider != "" {
+		fmt.Fprintf(&b, " provider=%s", n.Provider)
+	}
+	if n.Model != "" {
+		fmt.Fprintf(&b, " model=%s", n.Model)
+	}
+	if n.StatusCode != 0 {
+		fmt.Fprintf(&b, " status=%d", n.StatusCode)
+	}
+	if n.Failures > 0 {
+		fmt.Fprintf(&b, " failures=%d", n.Failures)
+	}
+	if n.RetryAt != nil {
+		fmt.Fprintf(&b, " retry_at=%s", n.RetryAt.UTC().Format(time.RFC3339))
+	}
+	if n.Message != "" {
+		fmt.Fprintf(&b, ": %s", n.Message)
+	}
+	if n.Suppressed > 0 {
+		fmt.Fprintf(&b, " (%d more notifications suppressed by rate limit)", n.Suppressed)
+	}
+	return b.String()
+}
@@ -0,0 +1,183 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+)
+
+type recorder struct {
+	mu     sync.Mutex
+	bodies []map[string]any
+	auth   string
+}
+
+func (r *recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	var decoded map[string]any
+	_ = json.Unmarshal(body, &decoded)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.bodies = append(r.bodies, decoded)
+	r.auth = req.Header.Get("Authorization")
+}
+
+func (r *recorder) received() []map[string]any {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]map[string]any(nil), r.bodies...)
+}
+
+func newTestNotifier(t *testing.T, notifications config.NotificationsConfig) (*Notifier, *time.Time) {
+	t.Helper()
+	cfg := &config.Config{Notifications: notifications}
+	cfg.SanitizeNotifications()
+	n := NewNotifier()
+	now := time.Date(2026, 3, 4, 15, 0, 0, 0, time.UTC)
+	n.now = func() time.Time { return now }
+	n.SetConfig(cfg)
+	t.Cleanup(n.Close)
+	return n, &now
+}
+
+func TestNotifierDedupsAndRateLimits(t *testing.T) {
+	rec := &recorder{}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+	n, now := newTestNotifier(t, config.NotificationsConfig{
+		Enable:             true,
+		DedupWindowSeconds: 60,
+		MaxPerMinute:       2,
+		Webhooks:           []config.NotificationWebhook{{URL: srv.URL, Headers: map[string]string{"Authorization": "Bearer alert"}}},
+	})
+
+	n.ConfigReloadFailed(errors.New("bad yaml"))
+	n.ConfigReloadFailed(errors.New("bad yaml")) // deduplicated
+	n.Notify(Notification{Event: config.NotifyAuthCooldown, AuthID: "a"})
+	n.Notify(Notification{Event: config.NotifyAuthCooldown, AuthID: "b"}) // rate limited
+	n.Notify(Notification{Event: config.NotifyAuthCooldown, AuthID: "c"}) // rate limited
+	*now = now.Add(61 * time.Second)
+	n.ConfigReloadFailed(errors.New("bad yaml"))
+	n.Close()
+
+	got := rec.received()
+	if len(got) != 3 {
+		t.Fatalf("deliveries = %d, want 3: %v", len(got), got)
+	}
+	if got[0]["event"] != config.NotifyConfigReloadFailed || got[0]["message"] != "bad yaml" {
+		t.Fatalf("first notification = %v", got[0])
+	}
+	if got[1]["auth_id"] != "a" {
+		t.Fatalf("second notification = %v", got[1])
+	}
+	if got[2]["suppressed"] != float64(2) {
+		t.Fatalf("suppressed = %v, want 2", got[2]["suppressed"])
+	}
+	if rec.auth != "Bearer alert" {
+		t.Fatalf("Authorization = %q", rec.auth)
+	}
+}
+
+func TestNotifierSlackFormatAndEventFilter(t *testing.T) {
+	slack := &recorder{}
+	slackSrv := httptest.NewServer(slack)
+	defer slackSrv.Close()
+	filtered := &recorder{}
+	filteredSrv := httptest.NewServer(filtered)
+	defer filteredSrv.Close()
+	n, _ := newTestNotifier(t, config.NotificationsConfig{
+		Enable: true,
+		Webhooks: []config.NotificationWebhook{
+			{URL: slackSrv.URL, Format: "Slack"},
+			{URL: filteredSrv.URL, Events: []string{config.NotifyConfigReloadFailed}},
+		},
+	})
+
+	n.HandleEvent(context.Background(), coreauth.Event{
+		Type:     coreauth.EventModelUnavailable,
+		Provider: "claude",
+		Key:      "claude-sonnet-4",
+		Message:  "all credentials are cooling down",
+	})
+	n.Close()
+
+	got := slack.received()
+	if len(got) != 1 {
+		t.Fatalf("slack deliveries = %d, want 1", len(got))
+	}
+	text, _ := got[0]["text"].(string)
+	if !strings.Contains(text, "model_unavailable") || !strings.Contains(text, "model=claude-sonnet-4") {
+		t.Fatalf("slack text = %q", text)
+	}
+	if len(filtered.received()) != 0 {
+		t.Fatal("filtered webhook received a model_unavailable notification")
+	}
+}
+
+func TestNotifierClassifiesAuthSignals(t *testing.T) {
+	rec := &recorder{}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+	n, now := newTestNotifier(t, config.NotificationsConfig{
+		Enable:                  true,
+		RefreshFailureThreshold: 2,
+		Webhooks:                []config.NotificationWebhook{{URL: srv.URL}},
+	})
+	retryAt := now.Add(time.Hour)
+	auths := map[string]*coreauth.Auth{
+		"quota": {ID: "quota", ModelStates: map[string]*coreauth.ModelState{"m": {NextRetryAfter: retryAt}}},
+		"flaky": {ID: "flaky", ModelStates: map[string]*coreauth.ModelState{"m": {}}},
+	}
+	n.SetAuthLookup(func(id string) (*coreauth.Auth, bool) {
+		auth, ok := auths[id]
+		return auth, ok
+	})
+	ctx := context.Background()
+
+	n.OnResult(ctx, coreauth.Result{AuthID: "quota", Model: "m", Error: &coreauth.Error{HTTPStatus: 429, Message: "quota"}})
+	n.OnResult(ctx, coreauth.Result{AuthID: "flaky", Model: "m", Error: &coreauth.Error{HTTPStatus: 500}}) // no cooldown
+	n.OnResult(ctx, coreauth.Result{AuthID: "revoked", Model: "m", Error: &coreauth.Error{HTTPStatus: 401}})
+	n.HandleEvent(ctx, coreauth.Event{Type: coreauth.EventRefreshFailed, AuthID: "r", Failures: 1, LastError: "timeout"})
+	n.HandleEvent(ctx, coreauth.Event{Type: coreauth.EventRefreshFailed, AuthID: "r", Failures: 2, LastError: "timeout"})
+	n.OnAuthUpdated(ctx, &coreauth.Auth{ID: "g", Status: coreauth.StatusError, StatusMessage: "invalid_grant: token revoked"})
+	n.Close()
+
+	var events []string
+	for _, body := range rec.received() {
+		events = append(events, body["event"].(string)+":"+body["auth_id"].(string))
+	}
+	want := []string{
+		config.NotifyAuthQuotaExceeded + ":quota",
+		config.NotifyAuthInvalid + ":revoked",
+		config.NotifyAuthRefreshFailing + ":r",
+		config.NotifyAuthInvalid + ":g",
+	}
+	if strings.Join(events, ",") != strings.Join(want, ",") {
+		t.Fatalf("events = %v, want %v", events, want)
+	}
+	if got := rec.received()[0]["retry_at"]; got != retryAt.Format(time.RFC3339) {
+		t.Fatalf("retry_at = %v, want %s", got, retryAt.Format(time.RFC3339))
+	}
+}
+
+func TestNotifierDisabledSendsNothing(t *testing.T) {
+	rec := &recorder{}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+	n, _ := newTestNotifier(t, config.NotificationsConfig{Webhooks: []config.NotificationWebhook{{URL: srv.URL}}})
+	n.ConfigReloadFailed(errors.New("bad yaml"))
+	n.Close()
+	if len(rec.received()) != 0 {
+		t.Fatal("disabled notifier delivered a notification")
+	}
+}
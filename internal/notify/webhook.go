@@ -0,0 +1,73 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// webhook posts notifications to one configured endpoint.
+type webhook struct {
+	cfg    config.NotificationWebhook
+	events map[string]struct{}
+	client *http.Client
+}
+
+func newWebhook(cfg config.NotificationWebhook) *webhook {
+	w := &webhook{cfg: cfg, client: &http.Client{Timeout: time.Duration(cfg.TimeoutSeconds) * time.Second}}
+	if len(cfg.Events) > 0 {
+		w.events = make(map[string]struct{}, len(cfg.Events))
+		for _, event := range cfg.Events {
+			w.events[event] = struct{}{}
+		}
+	}
+	return w
+}
+
+func (w *webhook) accepts(event string) bool {
+	if w.events == nil {
+		return true
+	}
+	_, ok := w.events[event]
+	return ok
+}
+
+// payload encodes notification in the webhook's format.
+func (w *webhook) payload(notification Notification) ([]byte, error) {
+	if w.cfg.Format == config.NotifyFormatSlack {
+		return json.Marshal(map[string]string{"text": notification.summary()})
+	}
+	return json.Marshal(notification)
+}
+
+func (w *webhook) send(notification Notification) {
+	body, err := w.payload(notification)
+	if err != nil {
+		log.Warnf("notify %s: encode %s notification: %v", w.cfg.Name, notification.Event, err)
+		return
+	}
+	req, err := http.NewRequest(http.MethodPost, w.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		log.Warnf("notify %s: build request: %v", w.cfg.Name, err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range w.cfg.Headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		log.Warnf("notify %s: webhook delivery failed: %v", w.cfg.Name, err)
+		return
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	_ = resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		log.Warnf("notify %s: webhook returned status %d for %s notification", w.cfg.Name, resp.StatusCode, notification.Event)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
+	"net/http"
 	"strings"
 	"sync"
 	"time"
@@ -73,17 +75,7 @@ func (r *usageReporter) publishWithOutcome(ctx context.Context, detail usage.Det
 		return
 	}
 	r.once.Do(func() {
-		usage.PublishRecord(ctx, usage.Record{
-			Provider:    r.provider,
-			Model:       r.model,
-			Source:      r.source,
-			APIKey:      r.apiKey,
-			AuthID:      r.authID,
-			AuthIndex:   r.authIndex,
-			RequestedAt: r.requestedAt,
-			Failed:      failed,
-			Detail:      detail,
-		})
+		usage.PublishRecord(ctx, r.buildRecord(ctx, detail, failed))
 	})
 }
 
@@ -96,20 +88,83 @@ func (r *usageReporter) ensurePublished(ctx context.Context) {
 		return
 	}
 	r.once.Do(func() {
-		usage.PublishRecord(ctx, usage.Record{
-			Provider:    r.provider,
-			Model:       r.model,
-			Source:      r.source,
-			APIKey:      r.apiKey,
-			AuthID:      r.authID,
-			AuthIndex:   r.authIndex,
-			RequestedAt: r.requestedAt,
-			Failed:      false,
-			Detail:      usage.Detail{},
-		})
+		usage.PublishRecord(ctx, r.buildRecord(ctx, usage.Detail{}, false))
 	})
 }
 
+// buildRecord assembles the usage record, adding upstream timing from the attempt context
+// and retry information from the request stats collected by the auth manager.
+func (r *usageReporter) buildRecord(ctx context.Context, detail usage.Detail, failed bool) usage.Record {
+	record := usage.Record{
+		Provider:    r.provider,
+		Model:       r.model,
+		Source:      r.source,
+		APIKey:      r.apiKey,
+		AuthID:      r.authID,
+		AuthIndex:   r.authIndex,
+		RequestedAt: r.requestedAt,
+		Failed:      failed,
+		Hedged:      usage.HedgedFromContext(ctx),
+		Detail:      detail,
+	}
+	timing := usage.TimingFromContext(ctx)
+	record.StatusCode = timing.StatusCode()
+	record.Latency = timing.Latency()
+	record.FirstToken = timing.FirstByte()
+	summary := cliproxyauth.RequestStatsFromContext(ctx).Summary()
+	record.Format = summary.Format
+	if summary.Stream && record.FirstToken > 0 {
+		record.StreamDuration = timing.Elapsed() - record.FirstToken
+	}
+	// Attempts only lists finished attempts, so the current one is counted separately.
+	record.Retries = len(summary.Attempts)
+	tried := map[string]struct{}{r.authID: {}}
+	for _, attempt := range summary.Attempts {
+		tried[attempt.AuthID] = struct{}{}
+	}
+	record.AuthsTried = len(tried)
+	return record
+}
+
+// wrapUsageTiming records upstream response timing on the usage.Timing of each request
+// context.
+func wrapUsageTiming(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &usageTimingTransport{base: base}
+}
+
+type usageTimingTransport struct {
+	base http.RoundTripper
+}
+
+func (t *usageTimingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	timing := usage.TimingFromContext(req.Context())
+	if timing == nil || err != nil || resp == nil {
+		return resp, err
+	}
+	timing.MarkResponse(resp.StatusCode)
+	if resp.Body != nil {
+		resp.Body = &usageTimingBody{ReadCloser: resp.Body, timing: timing}
+	}
+	return resp, nil
+}
+
+type usageTimingBody struct {
+	io.ReadCloser
+	timing *usage.Timing
+}
+
+func (b *usageTimingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.timing.MarkFirstByte()
+	}
+	return n, err
+}
+
 func apiKeyFromContext(ctx context.Context) string {
 	if ctx == nil {
 		return ""
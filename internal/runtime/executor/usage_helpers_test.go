@@ -1,6 +1,16 @@
 package executor
 
-import "testing"
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+)
 
 func TestParseOpenAIUsageChatCompletions(t *testing.T) {
 	data := []byte(`{"usage":{"prompt_tokens":1,"completion_tokens":2,"total_tokens":3,"prompt_tokens_details":{"cached_tokens":4},"completion_tokens_details":{"reasoning_tokens":5}}}`)
@@ -41,3 +51,36 @@ func TestParseOpenAIUsageResponses(t *testing.T) {
 		t.Fatalf("reasoning tokens = %d, want %d", detail.ReasoningTokens, 9)
 	}
 }
+
+func TestUsageRecordCarriesUpstreamTiming(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		w.(http.Flusher).Flush()
+		time.Sleep(5 * time.Millisecond)
+		_, _ = w.Write([]byte("data: {}\n\n"))
+	}))
+	defer srv.Close()
+
+	stats := cliproxyauth.NewRequestStats()
+	stats.SetRequest("claude", "claude-sonnet-4", true)
+	ctx := usage.WithTiming(cliproxyauth.WithRequestStats(context.Background(), stats))
+	auth := &cliproxyauth.Auth{ID: "auth-1", Provider: "claude"}
+	reporter := newUsageReporter(ctx, "claude", "claude-sonnet-4", auth)
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, srv.URL, nil)
+	resp, err := newProxyAwareHTTPClient(ctx, nil, auth, 0).Do(req)
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	_, _ = io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	time.Sleep(2 * time.Millisecond)
+
+	record := reporter.buildRecord(ctx, usage.Detail{InputTokens: 1}, false)
+	if record.Format != "claude" || record.StatusCode != http.StatusAccepted || record.Retries != 0 || record.AuthsTried != 1 {
+		t.Fatalf("record = %+v", record)
+	}
+	if record.Latency <= 0 || record.FirstToken < record.Latency || record.StreamDuration <= 0 {
+		t.Fatalf("timing latency=%v first=%v stream=%v", record.Latency, record.FirstToken, record.StreamDuration)
+	}
+}
@@ -19,11 +19,15 @@ import (
 	"github.com/go-git/go-git/v6/plumbing/transport"
 	"github.com/go-git/go-git/v6/plumbing/transport/http"
 	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	log "github.com/sirupsen/logrus"
 )
 
 // gcInterval defines minimum time between garbage collection runs.
 const gcInterval = 5 * time.Minute
 
+// stateSyncInterval defines minimum time between commits of the runtime state file.
+const stateSyncInterval = time.Minute
+
 // GitTokenStore persists token records and auth metadata using git as the backing storage.
 type GitTokenStore struct {
 	mu        sync.Mutex
@@ -35,6 +39,11 @@ type GitTokenStore struct {
 	username  string
 	password  string
 	lastGC    time.Time
+	lastState time.Time
+	// stateTimer commits a state write that arrived within stateSyncInterval of the last
+	// commit; statePending reports whether such a write is still uncommitted.
+	stateTimer   *time.Timer
+	statePending bool
 }
 
 // NewGitTokenStore creates a token store that saves credentials to disk through the
@@ -376,7 +385,7 @@ func (s *GitTokenStore) PersistAuthFiles(_ context.Context, message string, path
 	filtered := make([]string, 0, len(paths))
 	for _, p := range paths {
 		trimmed := strings.TrimSpace(p)
-		if trimmed == "" {
+		if trimmed == "" || isLocalStateFile(trimmed) {
 			continue
 		}
 		rel, err := s.relativeToRepo(trimmed)
@@ -568,7 +577,7 @@ func (s *GitTokenStore) commitAndPushLocked(message string, relPaths ...string)
 	}
 	added := false
 	for _, rel := range relPaths {
-		if strings.TrimSpace(rel) == "" {
+		if strings.TrimSpace(rel) == "" || isLocalStateFile(rel) {
 			continue
 		}
 		if _, err = worktree.Add(rel); err != nil {
@@ -674,6 +683,149 @@ func (s *GitTokenStore) maybeRunGC(repo *git.Repository) {
 	_ = repo.RepackObjects(&git.RepackConfig{})
 }
 
+// statePath returns the location of the runtime state file inside the repository.
+func (s *GitTokenStore) statePath() string {
+	repoDir := s.repoDirSnapshot()
+	if repoDir == "" {
+		return ""
+	}
+	return filepath.Join(repoDir, "state", "auth-state.json")
+}
+
+// LoadState reads the runtime availability state saved by SaveState.
+func (s *GitTokenStore) LoadState(_ context.Context) ([]byte, error) {
+	if err := s.EnsureRepository(); err != nil {
+		return nil, err
+	}
+	path := s.statePath()
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("git token store: read state: %w", err)
+	}
+	return data, nil
+}
+
+// SaveState writes the runtime availability state file. The file is always updated
+// locally but committed and pushed at most once per stateSyncInterval; a write inside the
+// interval is committed when it elapses, or earlier by FlushState.
+func (s *GitTokenStore) SaveState(_ context.Context, data []byte) error {
+	if err := s.EnsureRepository(); err != nil {
+		return err
+	}
+	path := s.statePath()
+	if path == "" {
+		return fmt.Errorf("git token store: repository path not configured")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("git token store: create state dir: %w", err)
+	}
+	if existing, errRead := os.ReadFile(path); errRead == nil && jsonEqual(existing, data) {
+		return nil
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("git token store: write state: %w", err)
+	}
+	if wait := stateSyncInterval - time.Since(s.lastState); wait > 0 {
+		s.statePending = true
+		if s.stateTimer == nil {
+			s.stateTimer = time.AfterFunc(wait, s.syncPendingState)
+		}
+		return nil
+	}
+	return s.commitStateLocked()
+}
+
+// FlushState commits and pushes a state write still waiting for stateSyncInterval.
+func (s *GitTokenStore) FlushState(_ context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stateTimer != nil {
+		s.stateTimer.Stop()
+		s.stateTimer = nil
+	}
+	if !s.statePending {
+		return nil
+	}
+	return s.commitStateLocked()
+}
+
+func (s *GitTokenStore) syncPendingState() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stateTimer = nil
+	if !s.statePending {
+		return
+	}
+	if err := s.commitStateLocked(); err != nil {
+		log.Warnf("git token store: sync auth state: %v", err)
+	}
+}
+
+func (s *GitTokenStore) commitStateLocked() error {
+	s.lastState = time.Now()
+	s.statePending = false
+	rel, err := s.relativeToRepo(s.statePath())
+	if err != nil {
+		return err
+	}
+	return s.commitAndPushLocked("Update auth state", rel)
+}
+
+// LoadVirtualKeys reads the virtual key document saved by SaveVirtualKeys.
+func (s *GitTokenStore) LoadVirtualKeys(_ context.Context) ([]byte, error) {
+	if err := s.EnsureRepository(); err != nil {
+		return nil, err
+	}
+	repoDir := s.repoDirSnapshot()
+	if repoDir == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(filepath.Join(repoDir, "state", "virtual-keys.json"))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("git token store: read virtual keys: %w", err)
+	}
+	return data, nil
+}
+
+// SaveVirtualKeys writes, commits and pushes the virtual key document.
+func (s *GitTokenStore) SaveVirtualKeys(_ context.Context, data []byte) error {
+	if err := s.EnsureRepository(); err != nil {
+		return err
+	}
+	repoDir := s.repoDirSnapshot()
+	if repoDir == "" {
+		return fmt.Errorf("git token store: repository path not configured")
+	}
+	path := filepath.Join(repoDir, "state", "virtual-keys.json")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("git token store: create state dir: %w", err)
+	}
+	if existing, errRead := os.ReadFile(path); errRead == nil && jsonEqual(existing, data) {
+		return nil
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("git token store: write virtual keys: %w", err)
+	}
+	rel, err := s.relativeToRepo(path)
+	if err != nil {
+		return err
+	}
+	return s.commitAndPushLocked("Update virtual keys", rel)
+}
+
 // PersistConfig commits and pushes configuration changes to git.
 func (s *GitTokenStore) PersistConfig(_ context.Context) error {
 	if err := s.EnsureRepository(); err != nil {
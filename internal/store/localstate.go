@@ -0,0 +1,46 @@
+package store
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// localStateFiles are runtime files other components keep next to the auth files: the
+// usage database, the budget tracker state and the file store's availability state and
+// virtual keys. They are node-local and must never be mirrored to a remote backend.
+var localStateFiles = []string{".usage.db", ".budget-state", ".auth-state", ".virtual-keys"}
+
+// isLocalStateFile reports whether path names one of localStateFiles, including the
+// SQLite journals and temporary files written alongside them.
+func isLocalStateFile(path string) bool {
+	name := filepath.Base(path)
+	for _, stateFile := range localStateFiles {
+		if strings.HasPrefix(name, stateFile) {
+			return true
+		}
+	}
+	return false
+}
+
+// resetAuthDir empties dir before it is repopulated from a remote backend, keeping the
+// local state files so usage history and budgets survive a restart.
+func resetAuthDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return os.MkdirAll(dir, 0o700)
+		}
+		return fmt.Errorf("read auth directory: %w", err)
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() && isLocalStateFile(entry.Name()) {
+			continue
+		}
+		if err = os.RemoveAll(filepath.Join(dir, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
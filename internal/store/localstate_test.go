@@ -0,0 +1,64 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIsLocalStateFile(t *testing.T) {
+	cases := map[string]bool{
+		"/auths/.usage.db":          true,
+		"/auths/.usage.db-wal":      true,
+		".budget-state.tmp":         true,
+		"nested/.auth-state":        true,
+		".virtual-keys":             true,
+		"/auths/claude-user.json":   false,
+		"/auths/usage.db.json":      false,
+		"/auths/nested/gemini.json": false,
+	}
+	for path, want := range cases {
+		if got := isLocalStateFile(path); got != want {
+			t.Errorf("isLocalStateFile(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestResetAuthDirKeepsLocalStateFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{".usage.db", ".usage.db-wal", ".budget-state", "stale.json", "nested/old.json"} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("x"), 0o600); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	if err := resetAuthDir(dir); err != nil {
+		t.Fatalf("resetAuthDir() error = %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("read dir: %v", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if len(names) != 3 || names[0] != ".budget-state" || names[1] != ".usage.db" || names[2] != ".usage.db-wal" {
+		t.Fatalf("remaining entries = %v, want only the local state files", names)
+	}
+}
+
+func TestResetAuthDirCreatesMissingDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "auths")
+	if err := resetAuthDir(dir); err != nil {
+		t.Fatalf("resetAuthDir() error = %v", err)
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		t.Fatalf("auth dir not created: %v", err)
+	}
+}
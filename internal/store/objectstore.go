@@ -25,6 +25,8 @@ import (
 const (
 	objectStoreConfigKey  = "config/config.yaml"
 	objectStoreAuthPrefix = "auths"
+	objectStoreStateKey   = "state/auth-state.json"
+	objectStoreKeysKey    = "state/virtual-keys.json"
 )
 
 // ObjectStoreConfig captures configuration for the object storage-backed token store.
@@ -292,7 +294,7 @@ func (s *ObjectTokenStore) PersistAuthFiles(ctx context.Context, _ string, paths
 
 	for _, p := range paths {
 		trimmed := strings.TrimSpace(p)
-		if trimmed == "" {
+		if trimmed == "" || isLocalStateFile(trimmed) {
 			continue
 		}
 		abs := trimmed
@@ -324,6 +326,58 @@ func (s *ObjectTokenStore) PersistConfig(ctx context.Context) error {
 	return s.putObject(ctx, objectStoreConfigKey, data, "application/x-yaml")
 }
 
+// LoadState downloads the runtime availability state saved by SaveState.
+func (s *ObjectTokenStore) LoadState(ctx context.Context) ([]byte, error) {
+	key := s.prefixedKey(objectStoreStateKey)
+	object, err := s.client.GetObject(ctx, s.cfg.Bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		if isObjectNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("object store: fetch state: %w", err)
+	}
+	defer object.Close()
+	data, err := io.ReadAll(object)
+	if err != nil {
+		if isObjectNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("object store: read state: %w", err)
+	}
+	return data, nil
+}
+
+// SaveState uploads the runtime availability state.
+func (s *ObjectTokenStore) SaveState(ctx context.Context, data []byte) error {
+	return s.putObject(ctx, objectStoreStateKey, data, "application/json")
+}
+
+// LoadVirtualKeys downloads the virtual key document saved by SaveVirtualKeys.
+func (s *ObjectTokenStore) LoadVirtualKeys(ctx context.Context) ([]byte, error) {
+	key := s.prefixedKey(objectStoreKeysKey)
+	object, err := s.client.GetObject(ctx, s.cfg.Bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		if isObjectNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("object store: fetch virtual keys: %w", err)
+	}
+	defer object.Close()
+	data, err := io.ReadAll(object)
+	if err != nil {
+		if isObjectNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("object store: read virtual keys: %w", err)
+	}
+	return data, nil
+}
+
+// SaveVirtualKeys uploads the virtual key document.
+func (s *ObjectTokenStore) SaveVirtualKeys(ctx context.Context, data []byte) error {
+	return s.putObject(ctx, objectStoreKeysKey, data, "application/json")
+}
+
 func (s *ObjectTokenStore) ensureBucket(ctx context.Context) error {
 	exists, err := s.client.BucketExists(ctx, s.cfg.Bucket)
 	if err != nil {
@@ -404,7 +458,7 @@ func (s *ObjectTokenStore) syncAuthFromBucket(ctx context.Context) error {
 			return fmt.Errorf("object store: list auth objects: %w", object.Err)
 		}
 		rel := strings.TrimPrefix(object.Key, prefix)
-		if rel == "" || strings.HasSuffix(rel, "/") {
+		if rel == "" || strings.HasSuffix(rel, "/") || isLocalStateFile(rel) {
 			continue
 		}
 		relPath := filepath.FromSlash(rel)
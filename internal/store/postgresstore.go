@@ -22,7 +22,10 @@ import (
 const (
 	defaultConfigTable = "config_store"
 	defaultAuthTable   = "auth_store"
+	defaultStateTable  = "auth_state"
 	defaultConfigKey   = "config"
+	defaultStateKey    = "runtime"
+	defaultKeysKey     = "virtual-keys"
 )
 
 // PostgresStoreConfig captures configuration required to initialize a Postgres-backed store.
@@ -31,6 +34,7 @@ type PostgresStoreConfig struct {
 	Schema      string
 	ConfigTable string
 	AuthTable   string
+	StateTable  string
 	SpoolDir    string
 }
 
@@ -58,6 +62,9 @@ func NewPostgresStore(ctx context.Context, cfg PostgresStoreConfig) (*PostgresSt
 	if cfg.AuthTable == "" {
 		cfg.AuthTable = defaultAuthTable
 	}
+	if cfg.StateTable == "" {
+		cfg.StateTable = defaultStateTable
+	}
 
 	spoolRoot := strings.TrimSpace(cfg.SpoolDir)
 	if spoolRoot == "" {
@@ -140,6 +147,17 @@ func (s *PostgresStore) EnsureSchema(ctx context.Context) error {
 	`, authTable)); err != nil {
 		return fmt.Errorf("postgres store: create auth table: %w", err)
 	}
+	stateTable := s.fullTableName(s.cfg.StateTable)
+	if _, err := s.db.ExecContext(ctx, fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			id TEXT PRIMARY KEY,
+			content JSONB NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)
+	`, stateTable)); err != nil {
+		return fmt.Errorf("postgres store: create state table: %w", err)
+	}
 	return nil
 }
 
@@ -352,7 +370,7 @@ func (s *PostgresStore) PersistAuthFiles(ctx context.Context, _ string, paths ..
 
 	for _, p := range paths {
 		trimmed := strings.TrimSpace(p)
-		if trimmed == "" {
+		if trimmed == "" || isLocalStateFile(trimmed) {
 			continue
 		}
 		relID, err := s.relativeAuthID(trimmed)
@@ -442,12 +460,9 @@ func (s *PostgresStore) syncAuthFromDatabase(ctx context.Context) error {
 	}
 	defer rows.Close()
 
-	if err = os.RemoveAll(s.authDir); err != nil {
+	if err = resetAuthDir(s.authDir); err != nil {
 		return fmt.Errorf("postgres store: reset auth directory: %w", err)
 	}
-	if err = os.MkdirAll(s.authDir, 0o700); err != nil {
-		return fmt.Errorf("postgres store: recreate auth directory: %w", err)
-	}
 
 	for rows.Next() {
 		var (
@@ -536,6 +551,66 @@ func (s *PostgresStore) persistConfig(ctx context.Context, data []byte) error {
 	return nil
 }
 
+// LoadState reads the runtime availability state saved by SaveState.
+func (s *PostgresStore) LoadState(ctx context.Context) ([]byte, error) {
+	data, err := s.loadStateRow(ctx, defaultStateKey)
+	if err != nil {
+		return nil, fmt.Errorf("postgres store: load state: %w", err)
+	}
+	return data, nil
+}
+
+// SaveState replaces the runtime availability state row.
+func (s *PostgresStore) SaveState(ctx context.Context, data []byte) error {
+	if err := s.saveStateRow(ctx, defaultStateKey, data); err != nil {
+		return fmt.Errorf("postgres store: upsert state: %w", err)
+	}
+	return nil
+}
+
+// LoadVirtualKeys reads the virtual key document saved by SaveVirtualKeys.
+func (s *PostgresStore) LoadVirtualKeys(ctx context.Context) ([]byte, error) {
+	data, err := s.loadStateRow(ctx, defaultKeysKey)
+	if err != nil {
+		return nil, fmt.Errorf("postgres store: load virtual keys: %w", err)
+	}
+	return data, nil
+}
+
+// SaveVirtualKeys replaces the virtual key document row.
+func (s *PostgresStore) SaveVirtualKeys(ctx context.Context, data []byte) error {
+	if err := s.saveStateRow(ctx, defaultKeysKey, data); err != nil {
+		return fmt.Errorf("postgres store: upsert virtual keys: %w", err)
+	}
+	return nil
+}
+
+// loadStateRow returns the content of row id in the state table, or nil when it is missing.
+func (s *PostgresStore) loadStateRow(ctx context.Context, id string) ([]byte, error) {
+	query := fmt.Sprintf("SELECT content FROM %s WHERE id = $1", s.fullTableName(s.cfg.StateTable))
+	var content string
+	err := s.db.QueryRowContext(ctx, query, id).Scan(&content)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+	return []byte(content), nil
+}
+
+// saveStateRow upserts row id in the state table.
+func (s *PostgresStore) saveStateRow(ctx context.Context, id string, data []byte) error {
+	query := fmt.Sprintf(`
+		INSERT INTO %s (id, content, created_at, updated_at)
+		VALUES ($1, $2, NOW(), NOW())
+		ON CONFLICT (id)
+		DO UPDATE SET content = EXCLUDED.content, updated_at = NOW()
+	`, s.fullTableName(s.cfg.StateTable))
+	_, err := s.db.ExecContext(ctx, query, id, json.RawMessage(data))
+	return err
+}
+
 func (s *PostgresStore) deleteConfigRecord(ctx context.Context) error {
 	query := fmt.Sprintf("DELETE FROM %s WHERE id = $1", s.fullTableName(s.cfg.ConfigTable))
 	if _, err := s.db.ExecContext(ctx, query, defaultConfigKey); err != nil {
@@ -0,0 +1,159 @@
+// Package tracing wires proxied requests into OpenTelemetry. Spans are started from the
+// global OpenTelemetry tracer provider, so SDK embedders can install their own with
+// otel.SetTracerProvider. When tracing.enable is set the proxy installs a provider that
+// exports over OTLP (HTTP or gRPC) or to stdout. Incoming W3C trace context is honoured so
+// proxy spans join the caller's trace.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/buildinfo"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationScope = "github.com/router-for-me/CLIProxyAPI"
+
+// propagator reads W3C trace context from inbound requests.
+var propagator = propagation.TraceContext{}
+
+var (
+	configureMu sync.Mutex
+	// installed is the provider created from the configuration, and previous the global
+	// provider it replaced, restored when tracing is disabled.
+	installed    *sdktrace.TracerProvider
+	installedCfg config.TracingConfig
+	previous     trace.TracerProvider
+)
+
+// Tracer returns the tracer for proxy spans from the current global tracer provider.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationScope)
+}
+
+// Configure applies tracing settings, replacing the installed provider when they changed.
+// Disabling tracing flushes the installed provider and restores the one it replaced.
+func Configure(cfg config.TracingConfig) error {
+	configureMu.Lock()
+	defer configureMu.Unlock()
+
+	if !cfg.Enable {
+		if installed != nil {
+			uninstallLocked(context.Background())
+			log.Info("tracing disabled")
+		}
+		return nil
+	}
+	if installed != nil && reflect.DeepEqual(installedCfg, cfg) {
+		return nil
+	}
+	exporter, err := newExporter(context.Background(), cfg)
+	if err != nil {
+		return err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(
+			attribute.String("service.name", cfg.ServiceName),
+			attribute.String("service.version", buildinfo.Version),
+		)),
+	)
+	old := installed
+	if old == nil {
+		previous = otel.GetTracerProvider()
+	}
+	otel.SetTracerProvider(provider)
+	installed, installedCfg = provider, cfg
+	if old != nil {
+		shutdownProvider(context.Background(), old)
+	}
+	log.Infof("tracing enabled: exporter=%s endpoint=%s sample-ratio=%g", cfg.Exporter, cfg.Endpoint, cfg.SampleRatio)
+	return nil
+}
+
+// Shutdown flushes queued spans and uninstalls the configured provider.
+func Shutdown(ctx context.Context) {
+	configureMu.Lock()
+	defer configureMu.Unlock()
+	if installed != nil {
+		uninstallLocked(ctx)
+	}
+}
+
+func uninstallLocked(ctx context.Context) {
+	otel.SetTracerProvider(previous)
+	shutdownProvider(ctx, installed)
+	installed, installedCfg, previous = nil, config.TracingConfig{}, nil
+}
+
+func shutdownProvider(ctx context.Context, provider *sdktrace.TracerProvider) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if err := provider.Shutdown(ctx); err != nil {
+		log.Warnf("tracing: shutdown failed: %v", err)
+	}
+}
+
+func newExporter(ctx context.Context, cfg config.TracingConfig) (sdktrace.SpanExporter, error) {
+	switch cfg.Exporter {
+	case config.TracingExporterStdout:
+		return stdouttrace.New()
+	case config.TracingExporterOTLPGRPC:
+		opts := []otlptracegrpc.Option{otlptracegrpc.WithHeaders(cfg.Headers)}
+		if strings.Contains(cfg.Endpoint, "://") {
+			opts = append(opts, otlptracegrpc.WithEndpointURL(cfg.Endpoint))
+		} else {
+			opts = append(opts, otlptracegrpc.WithEndpoint(cfg.Endpoint))
+			if cfg.Insecure {
+				opts = append(opts, otlptracegrpc.WithInsecure())
+			}
+		}
+		return otlptracegrpc.New(ctx, opts...)
+	case config.TracingExporterOTLPHTTP, "":
+		endpoint, err := otlpHTTPEndpoint(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(endpoint), otlptracehttp.WithHeaders(cfg.Headers))
+	default:
+		return nil, fmt.Errorf("tracing: unsupported exporter %q", cfg.Exporter)
+	}
+}
+
+// otlpHTTPEndpoint returns the full traces URL, defaulting the scheme from Insecure and the
+// path to /v1/traces.
+func otlpHTTPEndpoint(cfg config.TracingConfig) (string, error) {
+	endpoint := cfg.Endpoint
+	if !strings.Contains(endpoint, "://") {
+		scheme := "https://"
+		if cfg.Insecure {
+			scheme = "http://"
+		}
+		endpoint = scheme + endpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("tracing: invalid otlp-http endpoint %q", cfg.Endpoint)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/v1/traces"
+	}
+	return u.String(), nil
+}
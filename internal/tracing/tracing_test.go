@@ -0,0 +1,111 @@
+package tracing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// useRecorder installs an embedder-style global provider that records every span.
+func useRecorder(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+	return recorder
+}
+
+func TestMiddlewareJoinsIncomingTrace(t *testing.T) {
+	recorder := useRecorder(t)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer upstream.Close()
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(Middleware())
+	engine.POST("/v1/messages", func(c *gin.Context) {
+		req, _ := http.NewRequestWithContext(c.Request.Context(), http.MethodGet, upstream.URL+"/v1/chat?key=secret", nil)
+		resp, err := (&http.Client{Transport: WrapTransport(nil)}).Do(req)
+		if err != nil {
+			t.Errorf("upstream request: %v", err)
+			return
+		}
+		_ = resp.Body.Close()
+		c.Status(http.StatusTooManyRequests)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	engine.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("recorded %d spans, want 2", len(spans))
+	}
+	client, server := spans[0], spans[1]
+	if server.SpanContext().TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" || server.Parent().SpanID().String() != "00f067aa0ba902b7" {
+		t.Fatalf("server span parent = %v, want the incoming traceparent", server.Parent())
+	}
+	if client.Parent().SpanID() != server.SpanContext().SpanID() {
+		t.Fatalf("client span parent = %v, want server span %v", client.Parent().SpanID(), server.SpanContext().SpanID())
+	}
+	for _, attr := range client.Attributes() {
+		if attr.Key == "url.path" && attr.Value.AsString() != "/v1/chat" {
+			t.Fatalf("url.path = %q, want no query string", attr.Value.AsString())
+		}
+	}
+}
+
+func TestConfigureRestoresEmbedderProvider(t *testing.T) {
+	recorder := useRecorder(t)
+	embedder := otel.GetTracerProvider()
+
+	if err := Configure(config.TracingConfig{Enable: true, Exporter: config.TracingExporterStdout, ServiceName: "test", SampleRatio: 1}); err != nil {
+		t.Fatalf("Configure() error = %v", err)
+	}
+	if otel.GetTracerProvider() == embedder {
+		t.Fatal("Configure() did not install the configured provider")
+	}
+	if err := Configure(config.TracingConfig{}); err != nil {
+		t.Fatalf("Configure(disabled) error = %v", err)
+	}
+	if otel.GetTracerProvider() != embedder {
+		t.Fatal("disabling tracing did not restore the embedder's provider")
+	}
+
+	_, span := Tracer().Start(t.Context(), "after-disable")
+	span.End()
+	if len(recorder.Ended()) != 1 {
+		t.Fatalf("recorded %d spans, want the embedder to receive spans again", len(recorder.Ended()))
+	}
+}
+
+func TestOTLPHTTPEndpoint(t *testing.T) {
+	cases := []struct {
+		cfg  config.TracingConfig
+		want string
+	}{
+		{config.TracingConfig{Endpoint: "http://localhost:4318"}, "http://localhost:4318/v1/traces"},
+		{config.TracingConfig{Endpoint: "collector:4318", Insecure: true}, "http://collector:4318/v1/traces"},
+		{config.TracingConfig{Endpoint: "collector.example.com"}, "https://collector.example.com/v1/traces"},
+		{config.TracingConfig{Endpoint: "https://collector.example.com/otlp/traces"}, "https://collector.example.com/otlp/traces"},
+	}
+	for _, tc := range cases {
+		got, err := otlpHTTPEndpoint(tc.cfg)
+		if err != nil || got != tc.want {
+			t.Errorf("otlpHTTPEndpoint(%q) = %q, %v; want %q", tc.cfg.Endpoint, got, err, tc.want)
+		}
+	}
+	if _, err := otlpHTTPEndpoint(config.TracingConfig{Endpoint: "http://"}); err == nil {
+		t.Error("otlpHTTPEndpoint() accepted an endpoint without a host")
+	}
+}
@@ -0,0 +1,106 @@
+package usage
+
+import "sort"
+
+// LatencySnapshot holds timing percentiles grouped by model and by auth index, so a slow
+// credential can be told apart from a slow model.
+type LatencySnapshot struct {
+	Models map[string]LatencySummary `json:"models"`
+	Auths  map[string]LatencySummary `json:"auths"`
+}
+
+// LatencySummary summarises the timing of the requests in one group.
+type LatencySummary struct {
+	Requests       int64       `json:"requests"`
+	Latency        Percentiles `json:"latency_ms"`
+	FirstToken     Percentiles `json:"first_token_ms"`
+	StreamDuration Percentiles `json:"stream_duration_ms"`
+}
+
+// Percentiles are nearest-rank percentiles in milliseconds over the requests that reported
+// the measurement; Count is how many did.
+type Percentiles struct {
+	Count int64 `json:"count"`
+	P50   int64 `json:"p50"`
+	P95   int64 `json:"p95"`
+	P99   int64 `json:"p99"`
+}
+
+type latencySamples struct {
+	requests                          int64
+	latency, firstToken, streamLength []int64
+}
+
+func (l *latencySamples) add(detail RequestDetail) {
+	l.requests++
+	if detail.LatencyMs > 0 {
+		l.latency = append(l.latency, detail.LatencyMs)
+	}
+	if detail.FirstTokenMs > 0 {
+		l.firstToken = append(l.firstToken, detail.FirstTokenMs)
+	}
+	if detail.StreamDurationMs > 0 {
+		l.streamLength = append(l.streamLength, detail.StreamDurationMs)
+	}
+}
+
+func (l *latencySamples) summary() LatencySummary {
+	return LatencySummary{
+		Requests:       l.requests,
+		Latency:        percentiles(l.latency),
+		FirstToken:     percentiles(l.firstToken),
+		StreamDuration: percentiles(l.streamLength),
+	}
+}
+
+// summariseLatency computes per-model and per-auth percentiles from request details.
+func summariseLatency(apis map[string]APISnapshot) LatencySnapshot {
+	models := make(map[string]*latencySamples)
+	auths := make(map[string]*latencySamples)
+	sample := func(groups map[string]*latencySamples, key string, detail RequestDetail) {
+		if key == "" {
+			return
+		}
+		group, ok := groups[key]
+		if !ok {
+			group = &latencySamples{}
+			groups[key] = group
+		}
+		group.add(detail)
+	}
+	for _, api := range apis {
+		for modelName, model := range api.Models {
+			for _, detail := range model.Details {
+				sample(models, modelName, detail)
+				sample(auths, detail.AuthIndex, detail)
+			}
+		}
+	}
+	out := LatencySnapshot{
+		Models: make(map[string]LatencySummary, len(models)),
+		Auths:  make(map[string]LatencySummary, len(auths)),
+	}
+	for key, group := range models {
+		out.Models[key] = group.summary()
+	}
+	for key, group := range auths {
+		out.Auths[key] = group.summary()
+	}
+	return out
+}
+
+func percentiles(values []int64) Percentiles {
+	if len(values) == 0 {
+		return Percentiles{}
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+	rank := func(p int) int64 {
+		// Nearest rank: the smallest value with at least p% of samples at or below it.
+		idx := (p*len(values)+99)/100 - 1
+		if idx < 0 {
+			idx = 0
+		}
+		return values[idx]
+	}
+	return Percentiles{Count: int64(len(values)), P50: rank(50), P95: rank(95), P99: rank(99)}
+}
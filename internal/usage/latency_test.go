@@ -0,0 +1,69 @@
+package usage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+)
+
+func TestPercentilesNearestRank(t *testing.T) {
+	values := make([]int64, 0, 100)
+	for i := 100; i >= 1; i-- {
+		values = append(values, int64(i))
+	}
+	got := percentiles(values)
+	if got != (Percentiles{Count: 100, P50: 50, P95: 95, P99: 99}) {
+		t.Fatalf("percentiles = %+v", got)
+	}
+	if got = percentiles([]int64{7}); got != (Percentiles{Count: 1, P50: 7, P95: 7, P99: 7}) {
+		t.Fatalf("single sample = %+v", got)
+	}
+}
+
+func recordTimed(stats *RequestStatistics, model, authIndex string, latency time.Duration, at time.Time) {
+	stats.Record(context.Background(), coreusage.Record{
+		APIKey: "key", Model: model, AuthIndex: authIndex, RequestedAt: at, Format: "openai", StatusCode: 200,
+		Latency: latency, FirstToken: latency + 10*time.Millisecond, StreamDuration: time.Second, Retries: 1, AuthsTried: 2,
+		Detail: coreusage.Detail{InputTokens: 1},
+	})
+}
+
+func checkLatencySnapshot(t *testing.T, snapshot StatisticsSnapshot) {
+	t.Helper()
+	slowAuth := snapshot.Latency.Auths["2"]
+	if slowAuth.Requests != 2 || slowAuth.Latency.P50 != 900 || slowAuth.FirstToken.P99 != 910 || slowAuth.StreamDuration.P50 != 1000 {
+		t.Fatalf("auth 2 summary = %+v", slowAuth)
+	}
+	model := snapshot.Latency.Models["gpt-5"]
+	if model.Requests != 3 || model.Latency.Count != 3 || model.Latency.P50 != 900 {
+		t.Fatalf("gpt-5 summary = %+v", model)
+	}
+	if fastAuth := snapshot.Latency.Auths["1"]; fastAuth.Latency.P99 != 100 {
+		t.Fatalf("auth 1 summary = %+v", fastAuth)
+	}
+	detail := snapshot.APIs["key"].Models["gpt-5"].Details[0]
+	if detail.Format != "openai" || detail.StatusCode != 200 || detail.Retries != 1 || detail.AuthsTried != 2 {
+		t.Fatalf("detail = %+v", detail)
+	}
+}
+
+func TestSnapshotLatencyPercentiles(t *testing.T) {
+	at := time.Now()
+	record := func(stats *RequestStatistics) {
+		recordTimed(stats, "gpt-5", "1", 100*time.Millisecond, at)
+		recordTimed(stats, "gpt-5", "2", 900*time.Millisecond, at.Add(time.Second))
+		recordTimed(stats, "gpt-5", "2", 900*time.Millisecond, at.Add(2*time.Second))
+	}
+
+	memory := NewRequestStatistics()
+	record(memory)
+	checkLatencySnapshot(t, memory.Snapshot())
+
+	stored := NewRequestStatistics()
+	stored.SetConfig(newTestStoreConfig(t))
+	defer func() { _ = stored.Close() }()
+	record(stored)
+	checkLatencySnapshot(t, stored.Snapshot())
+}
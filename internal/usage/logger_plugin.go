@@ -13,6 +13,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+	log "github.com/sirupsen/logrus"
 )
 
 var statisticsEnabled atomic.Bool
@@ -56,7 +57,8 @@ func SetStatisticsEnabled(enabled bool) { statisticsEnabled.Store(enabled) }
 // StatisticsEnabled reports the current recording state.
 func StatisticsEnabled() bool { return statisticsEnabled.Load() }
 
-// RequestStatistics maintains aggregated request metrics in memory.
+// RequestStatistics maintains aggregated request metrics in memory, or in a persistent
+// Store when usage-store is enabled.
 type RequestStatistics struct {
 	mu sync.RWMutex
 
@@ -71,6 +73,10 @@ type RequestStatistics struct {
 	requestsByHour map[int]int64
 	tokensByDay    map[string]int64
 	tokensByHour   map[int]int64
+
+	storeMu  sync.Mutex
+	storeKey string
+	store    atomic.Pointer[Store]
 }
 
 // apiStats holds aggregated metrics for a single API key.
@@ -90,10 +96,20 @@ type modelStats struct {
 // RequestDetail stores the timestamp and token usage for a single request.
 type RequestDetail struct {
 	Timestamp time.Time  `json:"timestamp"`
+	Provider  string     `json:"provider,omitempty"`
 	Source    string     `json:"source"`
 	AuthIndex string     `json:"auth_index"`
 	Tokens    TokenStats `json:"tokens"`
 	Failed    bool       `json:"failed"`
+	Hedged    bool       `json:"hedged,omitempty"`
+
+	Format           string `json:"format,omitempty"`
+	StatusCode       int    `json:"status_code,omitempty"`
+	LatencyMs        int64  `json:"latency_ms,omitempty"`
+	FirstTokenMs     int64  `json:"first_token_ms,omitempty"`
+	StreamDurationMs int64  `json:"stream_duration_ms,omitempty"`
+	Retries          int    `json:"retries,omitempty"`
+	AuthsTried       int    `json:"auths_tried,omitempty"`
 }
 
 // TokenStats captures the token usage breakdown for a request.
@@ -118,6 +134,8 @@ type StatisticsSnapshot struct {
 	RequestsByHour map[string]int64 `json:"requests_by_hour"`
 	TokensByDay    map[string]int64 `json:"tokens_by_day"`
 	TokensByHour   map[string]int64 `json:"tokens_by_hour"`
+
+	Latency LatencySnapshot `json:"latency"`
 }
 
 // APISnapshot summarises metrics for a single API key.
@@ -177,6 +195,26 @@ func (s *RequestStatistics) Record(ctx context.Context, record coreusage.Record)
 	if modelName == "" {
 		modelName = "unknown"
 	}
+	requestDetail := RequestDetail{
+		Timestamp:        timestamp,
+		Provider:         record.Provider,
+		Source:           record.Source,
+		AuthIndex:        record.AuthIndex,
+		Tokens:           detail,
+		Failed:           failed,
+		Hedged:           record.Hedged,
+		Format:           record.Format,
+		StatusCode:       record.StatusCode,
+		LatencyMs:        record.Latency.Milliseconds(),
+		FirstTokenMs:     record.FirstToken.Milliseconds(),
+		StreamDurationMs: record.StreamDuration.Milliseconds(),
+		Retries:          record.Retries,
+		AuthsTried:       record.AuthsTried,
+	}
+	if store := s.store.Load(); store != nil {
+		store.Enqueue(storedRecord{APIKey: statsKey, Model: modelName, Detail: requestDetail})
+		return
+	}
 	dayKey := timestamp.Format("2006-01-02")
 	hourKey := timestamp.Hour()
 
@@ -196,13 +234,7 @@ func (s *RequestStatistics) Record(ctx context.Context, record coreusage.Record)
 		stats = &apiStats{Models: make(map[string]*modelStats)}
 		s.apis[statsKey] = stats
 	}
-	s.updateAPIStats(stats, modelName, RequestDetail{
-		Timestamp: timestamp,
-		Source:    record.Source,
-		AuthIndex: record.AuthIndex,
-		Tokens:    detail,
-		Failed:    failed,
-	})
+	s.updateAPIStats(stats, modelName, requestDetail)
 
 	s.requestsByDay[dayKey]++
 	s.requestsByHour[hourKey]++
@@ -229,6 +261,13 @@ func (s *RequestStatistics) Snapshot() StatisticsSnapshot {
 	if s == nil {
 		return result
 	}
+	if store := s.store.Load(); store != nil {
+		snapshot, err := store.Snapshot(context.Background())
+		if err != nil {
+			log.Errorf("usage store: snapshot: %v", err)
+		}
+		return snapshot
+	}
 
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -279,6 +318,7 @@ func (s *RequestStatistics) Snapshot() StatisticsSnapshot {
 		result.TokensByHour[key] = v
 	}
 
+	result.Latency = summariseLatency(result.APIs)
 	return result
 }
 
@@ -294,6 +334,13 @@ func (s *RequestStatistics) MergeSnapshot(snapshot StatisticsSnapshot) MergeResu
 	if s == nil {
 		return result
 	}
+	if store := s.store.Load(); store != nil {
+		merged, err := store.Merge(context.Background(), snapshot)
+		if err != nil {
+			log.Errorf("usage store: merge snapshot: %v", err)
+		}
+		return merged
+	}
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -0,0 +1,428 @@
+package usage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Dimensions accepted by Query for grouping and filtering. Days and hours are UTC.
+const (
+	DimensionAPIKey    = "api_key"
+	DimensionModel     = "model"
+	DimensionProvider  = "provider"
+	DimensionAuthIndex = "auth_index"
+	DimensionSource    = "source"
+	DimensionDay       = "day"
+	DimensionHour      = "hour"
+)
+
+// QueryDimensions lists every dimension Query understands, in canonical order.
+var QueryDimensions = []string{
+	DimensionAPIKey, DimensionModel, DimensionProvider, DimensionAuthIndex, DimensionSource, DimensionDay, DimensionHour,
+}
+
+const (
+	queryDayLayout  = "2006-01-02"
+	queryHourLayout = "2006-01-02T15"
+)
+
+// Resolutions reported by QueryResult.
+const (
+	ResolutionRaw  = "raw"
+	ResolutionHour = "hour"
+	ResolutionDay  = "day"
+)
+
+// Query selects and aggregates usage records.
+type Query struct {
+	// From and To bound the request time; From is inclusive and To exclusive. Zero values
+	// leave the range open.
+	From time.Time
+	To   time.Time
+	// GroupBy lists the dimensions rows are grouped by. Empty returns a single total row.
+	GroupBy []string
+	// Filters keeps records whose dimension matches one of the listed values. Day values use
+	// 2006-01-02 and hour values 2006-01-02T15.
+	Filters map[string][]string
+}
+
+// QueryRow is one aggregated group.
+type QueryRow struct {
+	Group           map[string]string `json:"group,omitempty"`
+	Requests        int64             `json:"requests"`
+	SuccessCount    int64             `json:"success_count"`
+	FailureCount    int64             `json:"failure_count"`
+	InputTokens     int64             `json:"input_tokens"`
+	OutputTokens    int64             `json:"output_tokens"`
+	ReasoningTokens int64             `json:"reasoning_tokens"`
+	CachedTokens    int64             `json:"cached_tokens"`
+	TotalTokens     int64             `json:"total_tokens"`
+}
+
+// QueryResult holds the rows of a Query. Resolution tells whether the persistent store
+// answered from raw records or from hourly or daily rollups; rollups include every bucket
+// that overlaps the requested range.
+type QueryResult struct {
+	GroupBy    []string   `json:"group_by"`
+	Resolution string     `json:"resolution"`
+	Rows       []QueryRow `json:"rows"`
+	Totals     QueryRow   `json:"totals"`
+}
+
+func (r *QueryRow) add(requests, failures int64, tokens TokenStats) {
+	r.Requests += requests
+	r.FailureCount += failures
+	r.SuccessCount += requests - failures
+	r.InputTokens += tokens.InputTokens
+	r.OutputTokens += tokens.OutputTokens
+	r.ReasoningTokens += tokens.ReasoningTokens
+	r.CachedTokens += tokens.CachedTokens
+	r.TotalTokens += tokens.TotalTokens
+}
+
+func (r *QueryRow) addRow(other QueryRow) {
+	r.Requests += other.Requests
+	r.SuccessCount += other.SuccessCount
+	r.FailureCount += other.FailureCount
+	r.InputTokens += other.InputTokens
+	r.OutputTokens += other.OutputTokens
+	r.ReasoningTokens += other.ReasoningTokens
+	r.CachedTokens += other.CachedTokens
+	r.TotalTokens += other.TotalTokens
+}
+
+// Validate checks dimension names and the format of day and hour filter values.
+func (q Query) Validate() error {
+	seen := make(map[string]struct{}, len(q.GroupBy))
+	for _, dim := range q.GroupBy {
+		if !isQueryDimension(dim) {
+			return fmt.Errorf("unknown group_by dimension %q", dim)
+		}
+		if _, dup := seen[dim]; dup {
+			return fmt.Errorf("duplicate group_by dimension %q", dim)
+		}
+		seen[dim] = struct{}{}
+	}
+	for dim, values := range q.Filters {
+		if !isQueryDimension(dim) {
+			return fmt.Errorf("unknown filter dimension %q", dim)
+		}
+		for _, value := range values {
+			if _, err := parseBucket(dim, value); err != nil {
+				return err
+			}
+		}
+	}
+	if !q.From.IsZero() && !q.To.IsZero() && !q.To.After(q.From) {
+		return fmt.Errorf("to must be after from")
+	}
+	return nil
+}
+
+func isQueryDimension(name string) bool {
+	for _, dim := range QueryDimensions {
+		if dim == name {
+			return true
+		}
+	}
+	return false
+}
+
+// parseBucket parses a day or hour filter value into its UTC bucket start. Other
+// dimensions are not parsed.
+func parseBucket(dim, value string) (time.Time, error) {
+	switch dim {
+	case DimensionDay:
+		t, err := time.Parse(queryDayLayout, value)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid day %q, want YYYY-MM-DD", value)
+		}
+		return t, nil
+	case DimensionHour:
+		t, err := time.Parse(queryHourLayout, value)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid hour %q, want YYYY-MM-DDTHH", value)
+		}
+		return t, nil
+	}
+	return time.Time{}, nil
+}
+
+func (q Query) usesDimension(dim string) bool {
+	if _, ok := q.Filters[dim]; ok {
+		return true
+	}
+	for _, d := range q.GroupBy {
+		if d == dim {
+			return true
+		}
+	}
+	return false
+}
+
+// Query aggregates usage matching q. With a persistent store the database does the
+// aggregation; otherwise the in-memory request details are scanned.
+func (s *RequestStatistics) Query(ctx context.Context, q Query) (QueryResult, error) {
+	if err := q.Validate(); err != nil {
+		return QueryResult{}, err
+	}
+	if s == nil {
+		return finishQuery(q, ResolutionRaw, nil), nil
+	}
+	if store := s.store.Load(); store != nil {
+		return store.Query(ctx, q)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	groups := make(map[string]*QueryRow)
+	values := make(map[string]string, len(QueryDimensions))
+	for apiName, stats := range s.apis {
+		for modelName, modelStatsValue := range stats.Models {
+			for _, detail := range modelStatsValue.Details {
+				ts := detail.Timestamp.UTC()
+				if (!q.From.IsZero() && ts.Before(q.From)) || (!q.To.IsZero() && !ts.Before(q.To)) {
+					continue
+				}
+				values[DimensionAPIKey] = apiName
+				values[DimensionModel] = modelName
+				values[DimensionProvider] = detail.Provider
+				values[DimensionAuthIndex] = detail.AuthIndex
+				values[DimensionSource] = detail.Source
+				values[DimensionDay] = ts.Format(queryDayLayout)
+				values[DimensionHour] = ts.Format(queryHourLayout)
+				if !matchesFilters(q.Filters, values) {
+					continue
+				}
+				row := groupRow(groups, q.GroupBy, values)
+				failures := int64(0)
+				if detail.Failed {
+					failures = 1
+				}
+				row.add(1, failures, normaliseTokenStats(detail.Tokens))
+			}
+		}
+	}
+	rows := make([]QueryRow, 0, len(groups))
+	for _, row := range groups {
+		rows = append(rows, *row)
+	}
+	return finishQuery(q, ResolutionRaw, rows), nil
+}
+
+func matchesFilters(filters map[string][]string, values map[string]string) bool {
+	for dim, allowed := range filters {
+		if len(allowed) == 0 {
+			continue
+		}
+		matched := false
+		for _, value := range allowed {
+			if values[dim] == value {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+func groupRow(groups map[string]*QueryRow, groupBy []string, values map[string]string) *QueryRow {
+	parts := make([]string, len(groupBy))
+	for i, dim := range groupBy {
+		parts[i] = values[dim]
+	}
+	key := strings.Join(parts, "\x00")
+	row, ok := groups[key]
+	if !ok {
+		row = &QueryRow{}
+		if len(groupBy) > 0 {
+			row.Group = make(map[string]string, len(groupBy))
+			for i, dim := range groupBy {
+				row.Group[dim] = parts[i]
+			}
+		}
+		groups[key] = row
+	}
+	return row
+}
+
+// finishQuery sorts rows by their group values and computes totals.
+func finishQuery(q Query, resolution string, rows []QueryRow) QueryResult {
+	sort.Slice(rows, func(i, j int) bool {
+		for _, dim := range q.GroupBy {
+			if a, b := rows[i].Group[dim], rows[j].Group[dim]; a != b {
+				return a < b
+			}
+		}
+		return false
+	})
+	result := QueryResult{GroupBy: q.GroupBy, Resolution: resolution, Rows: rows}
+	if result.GroupBy == nil {
+		result.GroupBy = []string{}
+	}
+	if result.Rows == nil {
+		result.Rows = []QueryRow{}
+	}
+	for _, row := range rows {
+		result.Totals.addRow(row)
+	}
+	return result
+}
+
+// Query aggregates stored usage. Raw records are used when the range starts inside the raw
+// retention window, then hourly rollups, then daily rollups.
+func (s *Store) Query(ctx context.Context, q Query) (QueryResult, error) {
+	if err := s.Flush(ctx); err != nil {
+		return QueryResult{}, err
+	}
+	now := time.Now()
+	resolution := ResolutionDay
+	switch {
+	case retentionCovers(q.From, s.retention.RawRetentionDays, now):
+		resolution = ResolutionRaw
+	case retentionCovers(q.From, s.retention.HourlyRetentionDays, now):
+		resolution = ResolutionHour
+	}
+	if resolution == ResolutionDay && q.usesDimension(DimensionHour) {
+		return QueryResult{}, fmt.Errorf("hour is only available within the last %d days", s.retention.HourlyRetentionDays)
+	}
+
+	var seconds string
+	var where []string
+	var args []any
+	var counters string
+	if resolution == ResolutionRaw {
+		seconds = "(requested_at / 1000000000)"
+		counters = `COUNT(*), COALESCE(SUM(failed), 0), COALESCE(SUM(input_tokens), 0), COALESCE(SUM(output_tokens), 0),
+			COALESCE(SUM(reasoning_tokens), 0), COALESCE(SUM(cached_tokens), 0), COALESCE(SUM(total_tokens), 0)`
+		if !q.From.IsZero() {
+			where, args = append(where, "requested_at >= ?"), append(args, q.From.UnixNano())
+		}
+		if !q.To.IsZero() {
+			where, args = append(where, "requested_at < ?"), append(args, q.To.UnixNano())
+		}
+	} else {
+		seconds = "bucket"
+		counters = `COALESCE(SUM(requests), 0), COALESCE(SUM(failures), 0), COALESCE(SUM(input_tokens), 0), COALESCE(SUM(output_tokens), 0),
+			COALESCE(SUM(reasoning_tokens), 0), COALESCE(SUM(cached_tokens), 0), COALESCE(SUM(total_tokens), 0)`
+		where, args = append(where, "granularity = ?"), append(args, resolution)
+		if !q.From.IsZero() {
+			start := q.From.UTC().Truncate(time.Hour)
+			if resolution == ResolutionDay {
+				start = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+			}
+			where, args = append(where, "bucket >= ?"), append(args, start.Unix())
+		}
+		if !q.To.IsZero() {
+			where, args = append(where, "bucket < ?"), append(args, q.To.Unix())
+		}
+	}
+
+	expressions := map[string]string{
+		DimensionAPIKey:    "api_key",
+		DimensionModel:     "model",
+		DimensionProvider:  "provider",
+		DimensionAuthIndex: "auth_index",
+		DimensionSource:    "source",
+		DimensionDay:       "(" + seconds + " / 86400) * 86400",
+		DimensionHour:      "(" + seconds + " / 3600) * 3600",
+	}
+	for _, dim := range QueryDimensions {
+		values := q.Filters[dim]
+		if len(values) == 0 {
+			continue
+		}
+		placeholders := make([]string, len(values))
+		for i, value := range values {
+			placeholders[i] = "?"
+			if dim == DimensionDay || dim == DimensionHour {
+				bucket, _ := parseBucket(dim, value)
+				args = append(args, bucket.Unix())
+			} else {
+				args = append(args, value)
+			}
+		}
+		where = append(where, expressions[dim]+" IN ("+strings.Join(placeholders, ", ")+")")
+	}
+
+	table := s.records
+	if resolution != ResolutionRaw {
+		table = s.rollups
+	}
+	selected := make([]string, 0, len(q.GroupBy)+1)
+	for _, dim := range q.GroupBy {
+		selected = append(selected, expressions[dim])
+	}
+	query := "SELECT " + strings.Join(append(selected, counters), ", ") + " FROM " + table
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	if len(selected) > 0 {
+		query += " GROUP BY " + strings.Join(selected, ", ")
+	}
+
+	rows, err := s.db.QueryContext(ctx, s.rebind(query), args...)
+	if err != nil {
+		return QueryResult{}, err
+	}
+	defer func() { _ = rows.Close() }()
+	var result []QueryRow
+	for rows.Next() {
+		strs := make([]string, len(q.GroupBy))
+		buckets := make([]int64, len(q.GroupBy))
+		dest := make([]any, 0, len(q.GroupBy)+7)
+		for i, dim := range q.GroupBy {
+			if dim == DimensionDay || dim == DimensionHour {
+				dest = append(dest, &buckets[i])
+			} else {
+				dest = append(dest, &strs[i])
+			}
+		}
+		var requests, failures int64
+		var tokens TokenStats
+		dest = append(dest, &requests, &failures, &tokens.InputTokens, &tokens.OutputTokens,
+			&tokens.ReasoningTokens, &tokens.CachedTokens, &tokens.TotalTokens)
+		if err = rows.Scan(dest...); err != nil {
+			return QueryResult{}, err
+		}
+		if requests == 0 {
+			continue
+		}
+		row := QueryRow{}
+		if len(q.GroupBy) > 0 {
+			row.Group = make(map[string]string, len(q.GroupBy))
+			for i, dim := range q.GroupBy {
+				switch dim {
+				case DimensionDay:
+					row.Group[dim] = time.Unix(buckets[i], 0).UTC().Format(queryDayLayout)
+				case DimensionHour:
+					row.Group[dim] = time.Unix(buckets[i], 0).UTC().Format(queryHourLayout)
+				default:
+					row.Group[dim] = strs[i]
+				}
+			}
+		}
+		row.add(requests, failures, tokens)
+		result = append(result, row)
+	}
+	if err = rows.Err(); err != nil {
+		return QueryResult{}, err
+	}
+	return finishQuery(q, resolution, result), nil
+}
+
+// retentionCovers reports whether data starting at from is still inside a retention window
+// of days. An open start is only covered by unlimited retention.
+func retentionCovers(from time.Time, days int, now time.Time) bool {
+	if days < 0 {
+		return true
+	}
+	return !from.IsZero() && !from.Before(now.AddDate(0, 0, -days))
+}
@@ -0,0 +1,108 @@
+package usage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+)
+
+func recordQueryFixtures(stats *RequestStatistics) time.Time {
+	at := time.Date(2026, 3, 4, 15, 30, 0, 0, time.UTC)
+	ctx := context.Background()
+	stats.Record(ctx, coreusage.Record{APIKey: "team-a", Model: "gpt-5", Provider: "codex", AuthIndex: "1", Source: "a@example.com",
+		RequestedAt: at, Detail: coreusage.Detail{InputTokens: 10, OutputTokens: 5, ReasoningTokens: 2, CachedTokens: 4}})
+	stats.Record(ctx, coreusage.Record{APIKey: "team-a", Model: "claude", Provider: "claude", AuthIndex: "2",
+		RequestedAt: at.Add(time.Hour), Failed: true, Detail: coreusage.Detail{InputTokens: 3}})
+	stats.Record(ctx, coreusage.Record{APIKey: "team-b", Model: "gpt-5", Provider: "codex", AuthIndex: "1",
+		RequestedAt: at.AddDate(0, 0, 1), Detail: coreusage.Detail{InputTokens: 1, OutputTokens: 1}})
+	return at
+}
+
+func checkQuery(t *testing.T, stats *RequestStatistics, at time.Time) {
+	t.Helper()
+	ctx := context.Background()
+
+	result, err := stats.Query(ctx, Query{GroupBy: []string{DimensionAPIKey, DimensionDay}})
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if len(result.Rows) != 2 || result.Totals.Requests != 3 || result.Totals.FailureCount != 1 {
+		t.Fatalf("grouped result = %+v", result)
+	}
+	first := result.Rows[0]
+	if first.Group[DimensionAPIKey] != "team-a" || first.Group[DimensionDay] != "2026-03-04" || first.Requests != 2 ||
+		first.SuccessCount != 1 || first.InputTokens != 13 || first.OutputTokens != 5 || first.ReasoningTokens != 2 || first.CachedTokens != 4 {
+		t.Fatalf("team-a row = %+v", first)
+	}
+
+	result, err = stats.Query(ctx, Query{
+		From:    at,
+		To:      at.Add(2 * time.Hour),
+		GroupBy: []string{DimensionHour},
+		Filters: map[string][]string{DimensionProvider: {"codex", "claude"}, DimensionAPIKey: {"team-a"}},
+	})
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if len(result.Rows) != 2 || result.Rows[0].Group[DimensionHour] != "2026-03-04T15" || result.Rows[1].Group[DimensionHour] != "2026-03-04T16" {
+		t.Fatalf("hourly rows = %+v", result.Rows)
+	}
+
+	result, err = stats.Query(ctx, Query{Filters: map[string][]string{DimensionDay: {"2026-03-05"}}})
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if len(result.Rows) != 1 || result.Rows[0].Group != nil || result.Totals.TotalTokens != 2 {
+		t.Fatalf("day filter result = %+v", result)
+	}
+}
+
+func TestQueryInMemory(t *testing.T) {
+	stats := NewRequestStatistics()
+	checkQuery(t, stats, recordQueryFixtures(stats))
+}
+
+func TestQueryStore(t *testing.T) {
+	cfg := newTestStoreConfig(t)
+	cfg.UsageStore.RawRetentionDays = -1
+	stats := NewRequestStatistics()
+	stats.SetConfig(cfg)
+	defer func() { _ = stats.Close() }()
+	checkQuery(t, stats, recordQueryFixtures(stats))
+}
+
+func TestQueryStoreFallsBackToRollups(t *testing.T) {
+	cfg := newTestStoreConfig(t)
+	stats := NewRequestStatistics()
+	stats.SetConfig(cfg)
+	defer func() { _ = stats.Close() }()
+	recordQueryFixtures(stats)
+
+	// The fixtures are older than the raw retention window, so daily rollups answer.
+	result, err := stats.Query(context.Background(), Query{GroupBy: []string{DimensionModel}})
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if result.Resolution != ResolutionDay || len(result.Rows) != 2 || result.Totals.Requests != 3 || result.Totals.InputTokens != 14 {
+		t.Fatalf("rollup result = %+v", result)
+	}
+	if _, err = stats.Query(context.Background(), Query{GroupBy: []string{DimensionHour}}); err == nil {
+		t.Fatal("Query() grouped by hour over daily rollups succeeded, want error")
+	}
+}
+
+func TestQueryValidate(t *testing.T) {
+	cases := []Query{
+		{GroupBy: []string{"team"}},
+		{GroupBy: []string{DimensionModel, DimensionModel}},
+		{Filters: map[string][]string{DimensionDay: {"March 4"}}},
+		{From: time.Unix(10, 0), To: time.Unix(5, 0)},
+	}
+	for _, q := range cases {
+		if err := q.Validate(); err == nil {
+			t.Errorf("Validate(%+v) = nil, want error", q)
+		}
+	}
+}
@@ -0,0 +1,632 @@
+package usage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	log "github.com/sirupsen/logrus"
+	_ "modernc.org/sqlite"
+)
+
+const (
+	defaultStoreFileName = ".usage.db"
+	storeBatchSize       = 256
+	storeFlushInterval   = time.Second
+	storeCompactInterval = time.Hour
+	storeQueueSize       = 4096
+
+	granularityHour = "hour"
+	granularityDay  = "day"
+)
+
+// storedRecord is one request as persisted in the usage_records table.
+type storedRecord struct {
+	APIKey string
+	Model  string
+	Detail RequestDetail
+}
+
+// Store persists usage records in SQLite or Postgres. Raw records are kept for the raw
+// retention window; hourly and daily rollups are maintained on insert and kept longer.
+// Writes are batched on a background goroutine.
+type Store struct {
+	db        *sql.DB
+	driver    string
+	records   string
+	rollups   string
+	retention config.UsageStoreConfig
+
+	queue    chan storedRecord
+	flushReq chan chan error
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// OpenStore opens the store described by cfg.UsageStore and ensures its schema exists.
+func OpenStore(ctx context.Context, cfg *config.Config) (*Store, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("usage store: config is nil")
+	}
+	driver, dsn, schema, err := resolveStoreTarget(cfg)
+	if err != nil {
+		return nil, err
+	}
+	sqlDriver := "pgx"
+	if driver == config.UsageStoreDriverSQLite {
+		sqlDriver = "sqlite"
+	}
+	db, err := sql.Open(sqlDriver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("usage store: open %s: %w", driver, err)
+	}
+	if driver == config.UsageStoreDriverSQLite {
+		// A single connection serializes writers and avoids SQLITE_BUSY under load.
+		db.SetMaxOpenConns(1)
+	}
+	if err = db.PingContext(ctx); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("usage store: ping %s: %w", driver, err)
+	}
+	s := &Store{
+		db:        db,
+		driver:    driver,
+		records:   qualifiedTable(schema, "usage_records"),
+		rollups:   qualifiedTable(schema, "usage_rollups"),
+		retention: cfg.UsageStore,
+		queue:     make(chan storedRecord, storeQueueSize),
+		flushReq:  make(chan chan error),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	if err = s.ensureSchema(ctx, schema); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	go s.run()
+	return s, nil
+}
+
+// resolveStoreTarget picks the driver, DSN and optional Postgres schema for cfg.
+func resolveStoreTarget(cfg *config.Config) (driver, dsn, schema string, err error) {
+	pgDSN := cfg.UsageStore.DSN
+	if pgDSN == "" {
+		pgDSN = lookupEnv("PGSTORE_DSN", "pgstore_dsn")
+	}
+	driver = cfg.UsageStore.Driver
+	if driver == "" {
+		driver = config.UsageStoreDriverSQLite
+		if pgDSN != "" {
+			driver = config.UsageStoreDriverPostgres
+		}
+	}
+	switch driver {
+	case config.UsageStoreDriverPostgres:
+		if pgDSN == "" {
+			return "", "", "", fmt.Errorf("usage store: postgres driver requires usage-store.dsn or PGSTORE_DSN")
+		}
+		return driver, pgDSN, lookupEnv("PGSTORE_SCHEMA", "pgstore_schema"), nil
+	case config.UsageStoreDriverSQLite:
+		path := cfg.UsageStore.Path
+		if path == "" {
+			dir, errDir := util.ResolveAuthDir(cfg.AuthDir)
+			if errDir != nil || dir == "" {
+				return "", "", "", fmt.Errorf("usage store: cannot resolve auth-dir for the default database path")
+			}
+			path = filepath.Join(dir, defaultStoreFileName)
+		}
+		if errMkdir := os.MkdirAll(filepath.Dir(path), 0o700); errMkdir != nil {
+			return "", "", "", fmt.Errorf("usage store: create database directory: %w", errMkdir)
+		}
+		return driver, "file:" + path + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)", "", nil
+	default:
+		return "", "", "", fmt.Errorf("usage store: unsupported driver %q", driver)
+	}
+}
+
+func lookupEnv(keys ...string) string {
+	for _, key := range keys {
+		if value := strings.TrimSpace(os.Getenv(key)); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func qualifiedTable(schema, name string) string {
+	if schema == "" {
+		return quoteIdentifier(name)
+	}
+	return quoteIdentifier(schema) + "." + quoteIdentifier(name)
+}
+
+func quoteIdentifier(identifier string) string {
+	return `"` + strings.ReplaceAll(identifier, `"`, `""`) + `"`
+}
+
+func (s *Store) ensureSchema(ctx context.Context, schema string) error {
+	var statements []string
+	if schema != "" {
+		statements = append(statements, "CREATE SCHEMA IF NOT EXISTS "+quoteIdentifier(schema))
+	}
+	statements = append(statements,
+		`CREATE TABLE IF NOT EXISTS `+s.records+` (
+			requested_at BIGINT NOT NULL,
+			api_key TEXT NOT NULL,
+			model TEXT NOT NULL,
+			provider TEXT NOT NULL,
+			auth_index TEXT NOT NULL,
+			source TEXT NOT NULL,
+			failed INTEGER NOT NULL,
+			hedged INTEGER NOT NULL,
+			input_tokens BIGINT NOT NULL,
+			output_tokens BIGINT NOT NULL,
+			reasoning_tokens BIGINT NOT NULL,
+			cached_tokens BIGINT NOT NULL,
+			total_tokens BIGINT NOT NULL,
+			format TEXT NOT NULL,
+			status_code INTEGER NOT NULL,
+			latency_ms BIGINT NOT NULL,
+			first_token_ms BIGINT NOT NULL,
+			stream_duration_ms BIGINT NOT NULL,
+			retries INTEGER NOT NULL,
+			auths_tried INTEGER NOT NULL,
+			dedup_key TEXT NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS usage_records_requested_at ON `+s.records+` (requested_at)`,
+		`CREATE INDEX IF NOT EXISTS usage_records_dedup_key ON `+s.records+` (dedup_key)`,
+		`CREATE TABLE IF NOT EXISTS `+s.rollups+` (
+			granularity TEXT NOT NULL,
+			bucket BIGINT NOT NULL,
+			api_key TEXT NOT NULL,
+			model TEXT NOT NULL,
+			provider TEXT NOT NULL,
+			auth_index TEXT NOT NULL,
+			source TEXT NOT NULL,
+			requests BIGINT NOT NULL,
+			failures BIGINT NOT NULL,
+			input_tokens BIGINT NOT NULL,
+			output_tokens BIGINT NOT NULL,
+			reasoning_tokens BIGINT NOT NULL,
+			cached_tokens BIGINT NOT NULL,
+			total_tokens BIGINT NOT NULL,
+			PRIMARY KEY (granularity, bucket, api_key, model, provider, auth_index, source)
+		)`,
+	)
+	for _, statement := range statements {
+		if _, err := s.db.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("usage store: create schema: %w", err)
+		}
+	}
+	return nil
+}
+
+// rebind converts ? placeholders to $n for Postgres.
+func (s *Store) rebind(query string) string {
+	if s.driver != config.UsageStoreDriverPostgres {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Enqueue queues rec for the next batched write. Records are dropped with a warning when
+// the queue is full.
+func (s *Store) Enqueue(rec storedRecord) {
+	select {
+	case s.queue <- rec:
+	default:
+		log.Warn("usage store: write queue full, dropping usage record")
+	}
+}
+
+// Flush writes all queued records and waits for the write to finish.
+func (s *Store) Flush(ctx context.Context) error {
+	reply := make(chan error, 1)
+	select {
+	case s.flushReq <- reply:
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case err := <-reply:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close flushes queued records and closes the database.
+func (s *Store) Close() error {
+	if s == nil {
+		return nil
+	}
+	s.stopOnce.Do(func() { close(s.stop) })
+	<-s.done
+	return s.db.Close()
+}
+
+func (s *Store) run() {
+	defer close(s.done)
+	flushTicker := time.NewTicker(storeFlushInterval)
+	defer flushTicker.Stop()
+	compactTicker := time.NewTicker(storeCompactInterval)
+	defer compactTicker.Stop()
+
+	s.compact(time.Now())
+	batch := make([]storedRecord, 0, storeBatchSize)
+	write := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		err := s.insert(ctx, batch)
+		if err != nil {
+			log.Errorf("usage store: write %d records: %v", len(batch), err)
+		}
+		batch = batch[:0]
+		return err
+	}
+	drain := func() {
+		for {
+			select {
+			case rec := <-s.queue:
+				batch = append(batch, rec)
+			default:
+				return
+			}
+		}
+	}
+	for {
+		select {
+		case rec := <-s.queue:
+			batch = append(batch, rec)
+			if len(batch) >= storeBatchSize {
+				_ = write()
+			}
+		case <-flushTicker.C:
+			_ = write()
+		case reply := <-s.flushReq:
+			drain()
+			reply <- write()
+		case <-compactTicker.C:
+			s.compact(time.Now())
+		case <-s.stop:
+			drain()
+			_ = write()
+			return
+		}
+	}
+}
+
+// insert writes records and updates their rollups in one transaction.
+func (s *Store) insert(ctx context.Context, records []storedRecord) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	insertRecord, err := tx.PrepareContext(ctx, s.rebind(`INSERT INTO `+s.records+` (
+		requested_at, api_key, model, provider, auth_index, source, failed, hedged,
+		input_tokens, output_tokens, reasoning_tokens, cached_tokens, total_tokens,
+		format, status_code, latency_ms, first_token_ms, stream_duration_ms, retries, auths_tried, dedup_key
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = insertRecord.Close() }()
+	upsertRollup, err := tx.PrepareContext(ctx, s.rebind(`INSERT INTO `+s.rollups+` AS r (
+		granularity, bucket, api_key, model, provider, auth_index, source, requests, failures,
+		input_tokens, output_tokens, reasoning_tokens, cached_tokens, total_tokens
+	) VALUES (?, ?, ?, ?, ?, ?, ?, 1, ?, ?, ?, ?, ?, ?)
+	ON CONFLICT (granularity, bucket, api_key, model, provider, auth_index, source) DO UPDATE SET
+		requests = r.requests + excluded.requests,
+		failures = r.failures + excluded.failures,
+		input_tokens = r.input_tokens + excluded.input_tokens,
+		output_tokens = r.output_tokens + excluded.output_tokens,
+		reasoning_tokens = r.reasoning_tokens + excluded.reasoning_tokens,
+		cached_tokens = r.cached_tokens + excluded.cached_tokens,
+		total_tokens = r.total_tokens + excluded.total_tokens`))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = upsertRollup.Close() }()
+
+	for _, rec := range records {
+		d := rec.Detail
+		ts := d.Timestamp.UTC()
+		if _, err = insertRecord.ExecContext(ctx,
+			ts.UnixNano(), rec.APIKey, rec.Model, d.Provider, d.AuthIndex, d.Source, boolInt(d.Failed), boolInt(d.Hedged),
+			d.Tokens.InputTokens, d.Tokens.OutputTokens, d.Tokens.ReasoningTokens, d.Tokens.CachedTokens, d.Tokens.TotalTokens,
+			d.Format, d.StatusCode, d.LatencyMs, d.FirstTokenMs, d.StreamDurationMs, d.Retries, d.AuthsTried,
+			dedupKey(rec.APIKey, rec.Model, d),
+		); err != nil {
+			return fmt.Errorf("insert record: %w", err)
+		}
+		buckets := []struct {
+			granularity string
+			start       time.Time
+		}{
+			{granularityHour, ts.Truncate(time.Hour)},
+			{granularityDay, time.Date(ts.Year(), ts.Month(), ts.Day(), 0, 0, 0, 0, time.UTC)},
+		}
+		for _, bucket := range buckets {
+			if _, err = upsertRollup.ExecContext(ctx,
+				bucket.granularity, bucket.start.Unix(), rec.APIKey, rec.Model, d.Provider, d.AuthIndex, d.Source, boolInt(d.Failed),
+				d.Tokens.InputTokens, d.Tokens.OutputTokens, d.Tokens.ReasoningTokens, d.Tokens.CachedTokens, d.Tokens.TotalTokens,
+			); err != nil {
+				return fmt.Errorf("update %s rollup: %w", bucket.granularity, err)
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// compact deletes raw records and rollups that fell out of their retention windows.
+func (s *Store) compact(now time.Time) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	if days := s.retention.RawRetentionDays; days > 0 {
+		cutoff := now.AddDate(0, 0, -days).UnixNano()
+		if _, err := s.db.ExecContext(ctx, s.rebind(`DELETE FROM `+s.records+` WHERE requested_at < ?`), cutoff); err != nil {
+			log.Warnf("usage store: prune raw records: %v", err)
+		}
+	}
+	for granularity, days := range map[string]int{granularityHour: s.retention.HourlyRetentionDays, granularityDay: s.retention.DailyRetentionDays} {
+		if days <= 0 {
+			continue
+		}
+		cutoff := now.AddDate(0, 0, -days).Unix()
+		if _, err := s.db.ExecContext(ctx, s.rebind(`DELETE FROM `+s.rollups+` WHERE granularity = ? AND bucket < ?`), granularity, cutoff); err != nil {
+			log.Warnf("usage store: prune %s rollups: %v", granularity, err)
+		}
+	}
+}
+
+// Snapshot builds a StatisticsSnapshot from the store. Totals and per-day counts come from
+// daily rollups and per-hour counts from hourly rollups. Request details and latency
+// percentiles come from the most recent raw records, capped by usage-store.snapshot-details.
+// Days and hours are UTC.
+func (s *Store) Snapshot(ctx context.Context) (StatisticsSnapshot, error) {
+	result := StatisticsSnapshot{
+		APIs:           make(map[string]APISnapshot),
+		RequestsByDay:  make(map[string]int64),
+		RequestsByHour: make(map[string]int64),
+		TokensByDay:    make(map[string]int64),
+		TokensByHour:   make(map[string]int64),
+	}
+	if err := s.Flush(ctx); err != nil {
+		return result, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, s.rebind(`SELECT granularity, bucket, api_key, model, SUM(requests), SUM(failures), SUM(total_tokens)
+		FROM `+s.rollups+` GROUP BY granularity, bucket, api_key, model`))
+	if err != nil {
+		return result, err
+	}
+	for rows.Next() {
+		var granularity, apiKey, model string
+		var bucket, requests, failures, tokens int64
+		if err = rows.Scan(&granularity, &bucket, &apiKey, &model, &requests, &failures, &tokens); err != nil {
+			_ = rows.Close()
+			return result, err
+		}
+		start := time.Unix(bucket, 0).UTC()
+		if granularity == granularityHour {
+			result.RequestsByHour[formatHour(start.Hour())] += requests
+			result.TokensByHour[formatHour(start.Hour())] += tokens
+			continue
+		}
+		result.TotalRequests += requests
+		result.FailureCount += failures
+		result.SuccessCount += requests - failures
+		result.TotalTokens += tokens
+		dayKey := start.Format("2006-01-02")
+		result.RequestsByDay[dayKey] += requests
+		result.TokensByDay[dayKey] += tokens
+
+		api := result.APIs[apiKey]
+		if api.Models == nil {
+			api.Models = make(map[string]ModelSnapshot)
+		}
+		api.TotalRequests += requests
+		api.TotalTokens += tokens
+		modelSnapshot := api.Models[model]
+		modelSnapshot.TotalRequests += requests
+		modelSnapshot.TotalTokens += tokens
+		api.Models[model] = modelSnapshot
+		result.APIs[apiKey] = api
+	}
+	if err = rows.Close(); err != nil {
+		return result, err
+	}
+
+	columns := `requested_at, api_key, model, provider, auth_index, source, failed, hedged,
+		input_tokens, output_tokens, reasoning_tokens, cached_tokens, total_tokens,
+		format, status_code, latency_ms, first_token_ms, stream_duration_ms, retries, auths_tried`
+	query := `SELECT ` + columns + ` FROM ` + s.records + ` ORDER BY requested_at`
+	if limit := s.retention.SnapshotDetails; limit > 0 {
+		query = `SELECT ` + columns + ` FROM (SELECT ` + columns + ` FROM ` + s.records +
+			` ORDER BY requested_at DESC LIMIT ` + strconv.Itoa(limit) + `) recent ORDER BY requested_at`
+	}
+	details, err := s.db.QueryContext(ctx, s.rebind(query))
+	if err != nil {
+		return result, err
+	}
+	defer func() { _ = details.Close() }()
+	for details.Next() {
+		var requestedAt int64
+		var apiKey, model string
+		var failed, hedged int
+		var detail RequestDetail
+		if err = details.Scan(&requestedAt, &apiKey, &model, &detail.Provider, &detail.AuthIndex, &detail.Source, &failed, &hedged,
+			&detail.Tokens.InputTokens, &detail.Tokens.OutputTokens, &detail.Tokens.ReasoningTokens, &detail.Tokens.CachedTokens, &detail.Tokens.TotalTokens,
+			&detail.Format, &detail.StatusCode, &detail.LatencyMs, &detail.FirstTokenMs, &detail.StreamDurationMs, &detail.Retries, &detail.AuthsTried,
+		); err != nil {
+			return result, err
+		}
+		detail.Timestamp = time.Unix(0, requestedAt).UTC()
+		detail.Failed = failed != 0
+		detail.Hedged = hedged != 0
+		api, ok := result.APIs[apiKey]
+		if !ok {
+			// Daily rollups expired before the raw record; keep the detail visible anyway.
+			api = APISnapshot{Models: make(map[string]ModelSnapshot)}
+		}
+		modelSnapshot := api.Models[model]
+		modelSnapshot.Details = append(modelSnapshot.Details, detail)
+		api.Models[model] = modelSnapshot
+		result.APIs[apiKey] = api
+	}
+	if err = details.Err(); err != nil {
+		return result, err
+	}
+	result.Latency = summariseLatency(result.APIs)
+	return result, nil
+}
+
+// Merge imports an exported snapshot, skipping details whose raw record already exists.
+func (s *Store) Merge(ctx context.Context, snapshot StatisticsSnapshot) (MergeResult, error) {
+	result := MergeResult{}
+	if err := s.Flush(ctx); err != nil {
+		return result, err
+	}
+	var pending []storedRecord
+	seen := make(map[string]struct{})
+	for apiName, apiSnapshot := range snapshot.APIs {
+		apiName = strings.TrimSpace(apiName)
+		if apiName == "" {
+			continue
+		}
+		for modelName, modelSnapshot := range apiSnapshot.Models {
+			modelName = strings.TrimSpace(modelName)
+			if modelName == "" {
+				modelName = "unknown"
+			}
+			for _, detail := range modelSnapshot.Details {
+				detail.Tokens = normaliseTokenStats(detail.Tokens)
+				if detail.Timestamp.IsZero() {
+					detail.Timestamp = time.Now()
+				}
+				key := dedupKey(apiName, modelName, detail)
+				if _, dup := seen[key]; dup {
+					result.Skipped++
+					continue
+				}
+				seen[key] = struct{}{}
+				exists, err := s.hasRecord(ctx, key)
+				if err != nil {
+					return result, err
+				}
+				if exists {
+					result.Skipped++
+					continue
+				}
+				pending = append(pending, storedRecord{APIKey: apiName, Model: modelName, Detail: detail})
+				result.Added++
+			}
+		}
+	}
+	if len(pending) == 0 {
+		return result, nil
+	}
+	if err := s.insert(ctx, pending); err != nil {
+		return MergeResult{}, err
+	}
+	return result, nil
+}
+
+func (s *Store) hasRecord(ctx context.Context, key string) (bool, error) {
+	var one int
+	err := s.db.QueryRowContext(ctx, s.rebind(`SELECT 1 FROM `+s.records+` WHERE dedup_key = ? LIMIT 1`), key).Scan(&one)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func boolInt(v bool) int {
+	if v {
+		return 1
+	}
+	return 0
+}
+
+// SetConfig opens, replaces or closes the persistent store to match cfg.UsageStore. While
+// a store is active, records bypass the in-memory aggregates. A store that fails to open
+// is logged and statistics stay in memory.
+func (s *RequestStatistics) SetConfig(cfg *config.Config) {
+	if s == nil {
+		return
+	}
+	s.storeMu.Lock()
+	defer s.storeMu.Unlock()
+
+	key := ""
+	if cfg != nil && cfg.UsageStore.Enable {
+		driver, dsn, schema, err := resolveStoreTarget(cfg)
+		if err != nil {
+			log.Errorf("%v", err)
+		} else {
+			r := cfg.UsageStore
+			key = fmt.Sprintf("%s|%s|%s|%d|%d|%d|%d", driver, dsn, schema, r.RawRetentionDays, r.HourlyRetentionDays, r.DailyRetentionDays, r.SnapshotDetails)
+		}
+	}
+	if key == s.storeKey {
+		return
+	}
+	var next *Store
+	if key != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		store, err := OpenStore(ctx, cfg)
+		cancel()
+		if err != nil {
+			log.Errorf("%v; usage statistics stay in memory", err)
+			key = ""
+		} else {
+			next = store
+			log.Infof("usage store: persisting usage statistics to %s", store.driver)
+		}
+	}
+	s.storeKey = key
+	if previous := s.store.Swap(next); previous != nil {
+		if err := previous.Close(); err != nil {
+			log.Warnf("usage store: close: %v", err)
+		}
+	}
+}
+
+// Close flushes and closes the persistent store, if any.
+func (s *RequestStatistics) Close() error {
+	if s == nil {
+		return nil
+	}
+	s.storeMu.Lock()
+	defer s.storeMu.Unlock()
+	s.storeKey = ""
+	return s.store.Swap(nil).Close()
+}
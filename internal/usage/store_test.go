@@ -0,0 +1,154 @@
+package usage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+)
+
+func newTestStoreConfig(t *testing.T) *config.Config {
+	t.Helper()
+	t.Setenv("PGSTORE_DSN", "")
+	cfg := &config.Config{}
+	cfg.UsageStore = config.UsageStoreConfig{Enable: true, Path: filepath.Join(t.TempDir(), "usage.db")}
+	cfg.SanitizeUsageStore()
+	return cfg
+}
+
+func TestRequestStatisticsPersistsToStore(t *testing.T) {
+	cfg := newTestStoreConfig(t)
+	stats := NewRequestStatistics()
+	stats.SetConfig(cfg)
+	defer func() { _ = stats.Close() }()
+
+	at := time.Date(2026, 3, 4, 15, 30, 0, 0, time.UTC)
+	ctx := context.Background()
+	stats.Record(ctx, coreusage.Record{APIKey: "key-a", Model: "gpt-5", Provider: "codex", AuthIndex: "1", RequestedAt: at,
+		Detail: coreusage.Detail{InputTokens: 10, OutputTokens: 5}})
+	stats.Record(ctx, coreusage.Record{APIKey: "key-a", Model: "gpt-5", Provider: "codex", AuthIndex: "1", RequestedAt: at.Add(time.Minute),
+		Failed: true, Detail: coreusage.Detail{InputTokens: 3}})
+	stats.Record(ctx, coreusage.Record{APIKey: "key-b", Model: "claude", Provider: "claude", RequestedAt: at.Add(-24 * time.Hour),
+		Detail: coreusage.Detail{TotalTokens: 7}})
+
+	snapshot := stats.Snapshot()
+	if snapshot.TotalRequests != 3 || snapshot.SuccessCount != 2 || snapshot.FailureCount != 1 || snapshot.TotalTokens != 25 {
+		t.Fatalf("totals = %+v", snapshot)
+	}
+	model := snapshot.APIs["key-a"].Models["gpt-5"]
+	if model.TotalRequests != 2 || model.TotalTokens != 18 || len(model.Details) != 2 || !model.Details[1].Failed {
+		t.Fatalf("key-a/gpt-5 = %+v", model)
+	}
+	if snapshot.RequestsByDay["2026-03-04"] != 2 || snapshot.RequestsByDay["2026-03-03"] != 1 || snapshot.RequestsByHour["15"] != 3 {
+		t.Fatalf("buckets: by day %v, by hour %v", snapshot.RequestsByDay, snapshot.RequestsByHour)
+	}
+
+	// Reopening the same database keeps the data.
+	if err := stats.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	reopened := NewRequestStatistics()
+	reopened.SetConfig(cfg)
+	defer func() { _ = reopened.Close() }()
+	if got := reopened.Snapshot().TotalRequests; got != 3 {
+		t.Fatalf("reopened TotalRequests = %d, want 3", got)
+	}
+}
+
+func TestStoreMergeSkipsDuplicates(t *testing.T) {
+	cfg := newTestStoreConfig(t)
+	stats := NewRequestStatistics()
+	stats.SetConfig(cfg)
+	defer func() { _ = stats.Close() }()
+
+	stats.Record(context.Background(), coreusage.Record{APIKey: "key-a", Model: "gpt-5", RequestedAt: time.Now(),
+		Detail: coreusage.Detail{InputTokens: 1, OutputTokens: 1}})
+	exported := stats.Snapshot()
+	exported.APIs["key-b"] = APISnapshot{Models: map[string]ModelSnapshot{
+		"claude": {Details: []RequestDetail{{Timestamp: time.Now(), Tokens: TokenStats{InputTokens: 4}}}},
+	}}
+
+	result := stats.MergeSnapshot(exported)
+	if result.Added != 1 || result.Skipped != 1 {
+		t.Fatalf("MergeSnapshot() = %+v, want 1 added, 1 skipped", result)
+	}
+	if got := stats.Snapshot().TotalRequests; got != 2 {
+		t.Fatalf("TotalRequests after merge = %d, want 2", got)
+	}
+}
+
+func TestStoreCompactAppliesRetention(t *testing.T) {
+	cfg := newTestStoreConfig(t)
+	cfg.UsageStore.RawRetentionDays = 1
+	cfg.UsageStore.HourlyRetentionDays = 2
+	cfg.UsageStore.DailyRetentionDays = -1
+	ctx := context.Background()
+	store, err := OpenStore(ctx, cfg)
+	if err != nil {
+		t.Fatalf("OpenStore() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	now := time.Now().UTC()
+	old := storedRecord{APIKey: "k", Model: "m", Detail: RequestDetail{Timestamp: now.AddDate(0, 0, -5), Tokens: TokenStats{TotalTokens: 2}}}
+	recent := storedRecord{APIKey: "k", Model: "m", Detail: RequestDetail{Timestamp: now, Tokens: TokenStats{TotalTokens: 3}}}
+	if err = store.insert(ctx, []storedRecord{old, recent}); err != nil {
+		t.Fatalf("insert() error = %v", err)
+	}
+	store.compact(now)
+
+	snapshot, err := store.Snapshot(ctx)
+	if err != nil {
+		t.Fatalf("Snapshot() error = %v", err)
+	}
+	// Daily rollups are kept forever, so totals still include the expired raw record.
+	if snapshot.TotalRequests != 2 || snapshot.TotalTokens != 5 {
+		t.Fatalf("totals = %d requests / %d tokens, want 2 / 5", snapshot.TotalRequests, snapshot.TotalTokens)
+	}
+	if details := snapshot.APIs["k"].Models["m"].Details; len(details) != 1 || details[0].Tokens.TotalTokens != 3 {
+		t.Fatalf("details = %+v, want only the recent record", details)
+	}
+	var hourly int64
+	for _, v := range snapshot.RequestsByHour {
+		hourly += v
+	}
+	if hourly != 1 {
+		t.Fatalf("hourly requests = %d, want 1", hourly)
+	}
+}
+
+func TestStoreSnapshotCapsDetails(t *testing.T) {
+	cfg := newTestStoreConfig(t)
+	cfg.UsageStore.SnapshotDetails = 2
+	ctx := context.Background()
+	store, err := OpenStore(ctx, cfg)
+	if err != nil {
+		t.Fatalf("OpenStore() error = %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	now := time.Now().UTC()
+	var records []storedRecord
+	for i := 0; i < 5; i++ {
+		records = append(records, storedRecord{APIKey: "k", Model: "m", Detail: RequestDetail{
+			Timestamp: now.Add(time.Duration(i) * time.Second), Tokens: TokenStats{TotalTokens: int64(i + 1)}}})
+	}
+	if err = store.insert(ctx, records); err != nil {
+		t.Fatalf("insert() error = %v", err)
+	}
+
+	snapshot, err := store.Snapshot(ctx)
+	if err != nil {
+		t.Fatalf("Snapshot() error = %v", err)
+	}
+	if snapshot.TotalRequests != 5 || snapshot.TotalTokens != 15 {
+		t.Fatalf("totals = %d requests / %d tokens, want 5 / 15", snapshot.TotalRequests, snapshot.TotalTokens)
+	}
+	details := snapshot.APIs["k"].Models["m"].Details
+	if len(details) != 2 || details[0].Tokens.TotalTokens != 4 || details[1].Tokens.TotalTokens != 5 {
+		t.Fatalf("details = %+v, want the two most recent records in order", details)
+	}
+}
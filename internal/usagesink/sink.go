@@ -0,0 +1,320 @@
+// Package usagesink forwards usage records to config-driven sinks: rotating JSONL files,
+// batched HTTP webhooks and stdout. Each sink selects its own fields and redacts the client
+// API key according to its settings.
+package usagesink
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Fields lists every record field a sink can emit, in default order.
+var Fields = []string{
+	"timestamp", "provider", "model", "api_key", "auth_id", "auth_index", "source", "failed", "hedged",
+	"format", "status_code", "latency_ms", "first_token_ms", "stream_duration_ms", "retries", "auths_tried",
+	"input_tokens", "output_tokens", "reasoning_tokens", "cached_tokens", "total_tokens",
+}
+
+var defaultManager = NewManager()
+
+func init() {
+	coreusage.RegisterPlugin(defaultManager)
+}
+
+// Default returns the process-wide manager fed by usage records.
+func Default() *Manager { return defaultManager }
+
+// writer is the output of a configured sink. write receives one encoded JSON object.
+type writer interface {
+	write(line []byte)
+	close() error
+}
+
+type sink struct {
+	name      string
+	fields    []string
+	redaction string
+	out       writer
+}
+
+// Manager owns the configured sinks and implements coreusage.Plugin.
+type Manager struct {
+	mu      sync.RWMutex
+	sinks   []*sink
+	current []config.UsageSink
+	logDir  string
+	stdout  io.Writer
+}
+
+// NewManager returns a manager without sinks.
+func NewManager() *Manager {
+	return &Manager{stdout: os.Stdout}
+}
+
+// SetConfig rebuilds the sinks when cfg.UsageSinks or the logs directory changed. Replaced
+// sinks are closed, which flushes pending webhook batches to their spool.
+func (m *Manager) SetConfig(cfg *config.Config) {
+	if m == nil {
+		return
+	}
+	var sinks []config.UsageSink
+	logDir := ""
+	if cfg != nil {
+		sinks = cfg.UsageSinks
+		logDir = logging.ResolveLogDirectory(cfg)
+	}
+	m.mu.Lock()
+	if reflect.DeepEqual(sinks, m.current) && logDir == m.logDir {
+		m.mu.Unlock()
+		return
+	}
+	previous := m.sinks
+	m.sinks = nil
+	m.current = append([]config.UsageSink(nil), sinks...)
+	m.logDir = logDir
+	for _, sinkCfg := range sinks {
+		built, err := m.build(sinkCfg, logDir)
+		if err != nil {
+			log.Errorf("usage sink %s: %v", sinkCfg.Name, err)
+			continue
+		}
+		m.sinks = append(m.sinks, built)
+	}
+	m.mu.Unlock()
+	closeSinks(previous)
+}
+
+// Close flushes and closes every sink.
+func (m *Manager) Close() {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	previous := m.sinks
+	m.sinks = nil
+	m.current = nil
+	m.mu.Unlock()
+	closeSinks(previous)
+}
+
+func closeSinks(sinks []*sink) {
+	for _, s := range sinks {
+		if err := s.out.close(); err != nil {
+			log.Warnf("usage sink %s: close: %v", s.name, err)
+		}
+	}
+}
+
+func (m *Manager) build(cfg config.UsageSink, logDir string) (*sink, error) {
+	s := &sink{name: cfg.Name, redaction: cfg.APIKeyRedaction}
+	for _, field := range cfg.Fields {
+		if !knownField(field) {
+			log.Warnf("usage sink %s: ignoring unknown field %q", cfg.Name, field)
+			continue
+		}
+		s.fields = append(s.fields, field)
+	}
+	if len(s.fields) == 0 {
+		s.fields = Fields
+	}
+	switch cfg.Type {
+	case config.UsageSinkFile:
+		path := cfg.Path
+		if path == "" {
+			path = filepath.Join(logDir, "usage-"+cfg.Name+".jsonl")
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil, fmt.Errorf("create directory: %w", err)
+		}
+		s.out = &fileWriter{logger: &lumberjack.Logger{
+			Filename:   path,
+			MaxSize:    cfg.MaxSizeMB,
+			MaxBackups: cfg.MaxBackups,
+			MaxAge:     cfg.MaxAgeDays,
+			Compress:   cfg.Compress,
+		}}
+	case config.UsageSinkStdout:
+		s.out = &streamWriter{w: m.stdout}
+	case config.UsageSinkWebhook:
+		spoolDir := cfg.SpoolDir
+		if spoolDir == "" {
+			spoolDir = filepath.Join(logDir, "usage-spool", cfg.Name)
+		}
+		out, err := newWebhookWriter(cfg, spoolDir)
+		if err != nil {
+			return nil, err
+		}
+		s.out = out
+	default:
+		return nil, fmt.Errorf("unsupported type %q", cfg.Type)
+	}
+	return s, nil
+}
+
+func knownField(name string) bool {
+	for _, field := range Fields {
+		if field == name {
+			return true
+		}
+	}
+	return false
+}
+
+// HandleUsage implements coreusage.Plugin.
+func (m *Manager) HandleUsage(_ context.Context, record coreusage.Record) {
+	if m == nil {
+		return
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, s := range m.sinks {
+		s.out.write(encodeRecord(record, s.fields, s.redaction))
+	}
+}
+
+// encodeRecord renders record as a JSON object holding fields in order.
+func encodeRecord(record coreusage.Record, fields []string, redaction string) []byte {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	first := true
+	for _, field := range fields {
+		var value any
+		switch field {
+		case "timestamp":
+			ts := record.RequestedAt
+			if ts.IsZero() {
+				ts = time.Now()
+			}
+			value = ts.UTC().Format(time.RFC3339Nano)
+		case "provider":
+			value = record.Provider
+		case "model":
+			value = record.Model
+		case "api_key":
+			if redaction == config.UsageSinkRedactDrop {
+				continue
+			}
+			value = redactAPIKey(record.APIKey, redaction)
+		case "auth_id":
+			value = record.AuthID
+		case "auth_index":
+			value = record.AuthIndex
+		case "source":
+			value = record.Source
+		case "failed":
+			value = record.Failed
+		case "hedged":
+			value = record.Hedged
+		case "format":
+			value = record.Format
+		case "status_code":
+			value = record.StatusCode
+		case "latency_ms":
+			value = record.Latency.Milliseconds()
+		case "first_token_ms":
+			value = record.FirstToken.Milliseconds()
+		case "stream_duration_ms":
+			value = record.StreamDuration.Milliseconds()
+		case "retries":
+			value = record.Retries
+		case "auths_tried":
+			value = record.AuthsTried
+		case "input_tokens":
+			value = record.Detail.InputTokens
+		case "output_tokens":
+			value = record.Detail.OutputTokens
+		case "reasoning_tokens":
+			value = record.Detail.ReasoningTokens
+		case "cached_tokens":
+			value = record.Detail.CachedTokens
+		case "total_tokens":
+			total := record.Detail.TotalTokens
+			if total == 0 {
+				total = record.Detail.InputTokens + record.Detail.OutputTokens + record.Detail.ReasoningTokens
+			}
+			value = total
+		default:
+			continue
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			continue
+		}
+		if !first {
+			buf.WriteByte(',')
+		}
+		first = false
+		name, _ := json.Marshal(field)
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(encoded)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes()
+}
+
+func redactAPIKey(key, mode string) string {
+	if key == "" {
+		return ""
+	}
+	switch mode {
+	case config.UsageSinkRedactNone:
+		return key
+	case config.UsageSinkRedactHash:
+		sum := sha256.Sum256([]byte(key))
+		return "sha256:" + hex.EncodeToString(sum[:])
+	default:
+		return util.HideAPIKey(key)
+	}
+}
+
+// fileWriter appends JSON lines to a size-rotated file.
+type fileWriter struct {
+	mu     sync.Mutex
+	logger *lumberjack.Logger
+}
+
+func (w *fileWriter) write(line []byte) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, err := w.logger.Write(append(line, '\n')); err != nil {
+		log.Warnf("usage sink: write %s: %v", w.logger.Filename, err)
+	}
+}
+
+func (w *fileWriter) close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.logger.Close()
+}
+
+// streamWriter writes JSON lines to stdout for log shippers.
+type streamWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (w *streamWriter) write(line []byte) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, _ = w.w.Write(append(line, '\n'))
+}
+
+func (w *streamWriter) close() error { return nil }
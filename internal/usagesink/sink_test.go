@@ -0,0 +1,194 @@
+package usagesink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+)
+
+func testRecord() coreusage.Record {
+	return coreusage.Record{
+		Provider:    "claude",
+		Model:       "claude-sonnet-4",
+		APIKey:      "sk-team-a-secret",
+		AuthIndex:   "3",
+		RequestedAt: time.Date(2026, 3, 4, 15, 0, 0, 0, time.UTC),
+		Latency:     250 * time.Millisecond,
+		Detail:      coreusage.Detail{InputTokens: 10, OutputTokens: 5},
+	}
+}
+
+func newTestConfig(dir string, sinks ...config.UsageSink) *config.Config {
+	cfg := &config.Config{AuthDir: dir, UsageSinks: sinks}
+	cfg.SanitizeUsageSinks()
+	return cfg
+}
+
+func TestEncodeRecordSelectsFieldsAndRedacts(t *testing.T) {
+	fields := []string{"model", "api_key", "latency_ms", "total_tokens"}
+	cases := map[string]string{
+		config.UsageSinkRedactMask: `{"model":"claude-sonnet-4","api_key":"sk-t...cret","latency_ms":250,"total_tokens":15}`,
+		config.UsageSinkRedactNone: `{"model":"claude-sonnet-4","api_key":"sk-team-a-secret","latency_ms":250,"total_tokens":15}`,
+		config.UsageSinkRedactDrop: `{"model":"claude-sonnet-4","latency_ms":250,"total_tokens":15}`,
+	}
+	for mode, want := range cases {
+		if got := string(encodeRecord(testRecord(), fields, mode)); got != want {
+			t.Errorf("%s: got %s, want %s", mode, got, want)
+		}
+	}
+	var hashed map[string]any
+	if err := json.Unmarshal(encodeRecord(testRecord(), []string{"api_key"}, config.UsageSinkRedactHash), &hashed); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if key, _ := hashed["api_key"].(string); !strings.HasPrefix(key, "sha256:") || strings.Contains(key, "secret") {
+		t.Fatalf("hashed api_key = %q", key)
+	}
+	var all map[string]any
+	if err := json.Unmarshal(encodeRecord(testRecord(), Fields, config.UsageSinkRedactMask), &all); err != nil || len(all) != len(Fields) {
+		t.Fatalf("all fields = %v (%v)", all, err)
+	}
+}
+
+func TestFileAndStdoutSinks(t *testing.T) {
+	dir := t.TempDir()
+	var stdout bytes.Buffer
+	m := NewManager()
+	m.stdout = &stdout
+	path := filepath.Join(dir, "usage.jsonl")
+	m.SetConfig(newTestConfig(dir,
+		config.UsageSink{Type: "file", Path: path, Fields: []string{"model", "input_tokens", "bogus"}},
+		config.UsageSink{Type: "stdout", Fields: []string{"api_key"}, APIKeyRedaction: "drop"},
+	))
+	m.HandleUsage(context.Background(), testRecord())
+	m.HandleUsage(context.Background(), testRecord())
+	m.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read file sink: %v", err)
+	}
+	line := `{"model":"claude-sonnet-4","input_tokens":10}` + "\n"
+	if string(data) != line+line {
+		t.Fatalf("file sink = %q", data)
+	}
+	if stdout.String() != "{}\n{}\n" {
+		t.Fatalf("stdout sink = %q", stdout.String())
+	}
+}
+
+type webhookRecorder struct {
+	mu       sync.Mutex
+	failures int
+	batches  [][]map[string]any
+	auth     string
+}
+
+func (r *webhookRecorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.failures > 0 {
+		r.failures--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	body, _ := io.ReadAll(req.Body)
+	var batch []map[string]any
+	_ = json.Unmarshal(body, &batch)
+	r.batches = append(r.batches, batch)
+	r.auth = req.Header.Get("Authorization")
+}
+
+func (r *webhookRecorder) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := 0
+	for _, batch := range r.batches {
+		n += len(batch)
+	}
+	return n
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met before deadline")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWebhookSinkBatchesAndRetries(t *testing.T) {
+	backoffBase = time.Millisecond
+	recorder := &webhookRecorder{failures: 2}
+	srv := httptest.NewServer(recorder)
+	defer srv.Close()
+
+	m := NewManager()
+	m.SetConfig(newTestConfig(t.TempDir(), config.UsageSink{
+		Type: "webhook", URL: srv.URL, BatchSize: 2, Headers: map[string]string{"Authorization": "Bearer sink"},
+	}))
+	defer m.Close()
+	for i := 0; i < 4; i++ {
+		m.HandleUsage(context.Background(), testRecord())
+	}
+	waitFor(t, func() bool { return recorder.count() == 4 })
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	if len(recorder.batches) != 2 || recorder.auth != "Bearer sink" {
+		t.Fatalf("batches = %d, auth = %q", len(recorder.batches), recorder.auth)
+	}
+	if key := recorder.batches[0][0]["api_key"]; key != "sk-t...cret" {
+		t.Fatalf("api_key = %v, want masked", key)
+	}
+}
+
+func TestWebhookSinkSpoolsAndReplays(t *testing.T) {
+	backoffBase = time.Millisecond
+	recorder := &webhookRecorder{failures: 1000}
+	srv := httptest.NewServer(recorder)
+	defer srv.Close()
+
+	dir := t.TempDir()
+	spoolDir := filepath.Join(dir, "spool")
+	sinkCfg := config.UsageSink{Type: "webhook", URL: srv.URL, BatchSize: 3, MaxRetries: 1, SpoolDir: spoolDir}
+	m := NewManager()
+	m.SetConfig(newTestConfig(dir, sinkCfg))
+	for i := 0; i < 3; i++ {
+		m.HandleUsage(context.Background(), testRecord())
+	}
+	waitFor(t, func() bool {
+		entries, _ := os.ReadDir(spoolDir)
+		return len(entries) == 1
+	})
+	// A pending partial batch is spooled on close.
+	m.HandleUsage(context.Background(), testRecord())
+	m.Close()
+	if entries, _ := os.ReadDir(spoolDir); len(entries) != 2 {
+		t.Fatalf("spool files = %d, want 2", len(entries))
+	}
+
+	recorder.mu.Lock()
+	recorder.failures = 0
+	recorder.mu.Unlock()
+	m.SetConfig(newTestConfig(dir, sinkCfg))
+	defer m.Close()
+	waitFor(t, func() bool { return recorder.count() == 4 })
+	if entries, _ := os.ReadDir(spoolDir); len(entries) != 0 {
+		t.Fatalf("spool files after replay = %d, want 0", len(entries))
+	}
+}
@@ -0,0 +1,309 @@
+package usagesink
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	webhookQueueFactor  = 10
+	webhookPendingSize  = 4
+	spoolReplayInterval = 30 * time.Second
+	maxBackoff          = time.Minute
+)
+
+// backoffBase is the first retry delay; later retries double it up to maxBackoff.
+var backoffBase = time.Second
+
+// errPermanent marks a batch the endpoint rejected; it is dropped instead of spooled.
+var errPermanent = errors.New("rejected by endpoint")
+
+// webhookWriter batches lines and POSTs them as a JSON array. Collecting and sending run
+// on separate goroutines so a slow endpoint never blocks usage dispatch; batches that
+// cannot be delivered after the retries, or that arrive while the sender is backed up, are
+// written to the spool directory and replayed once the endpoint recovers.
+type webhookWriter struct {
+	cfg      config.UsageSink
+	spoolDir string
+	client   *http.Client
+
+	lines   chan []byte
+	batches chan [][]byte
+	stop    chan struct{}
+	done    chan struct{}
+	spoolMu sync.Mutex
+}
+
+func newWebhookWriter(cfg config.UsageSink, spoolDir string) (*webhookWriter, error) {
+	if err := os.MkdirAll(spoolDir, 0o700); err != nil {
+		return nil, fmt.Errorf("create spool directory: %w", err)
+	}
+	w := &webhookWriter{
+		cfg:      cfg,
+		spoolDir: spoolDir,
+		client:   &http.Client{Timeout: time.Duration(cfg.TimeoutSeconds) * time.Second},
+		lines:    make(chan []byte, cfg.BatchSize*webhookQueueFactor),
+		batches:  make(chan [][]byte, webhookPendingSize),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go w.collect()
+	go func() {
+		defer close(w.done)
+		w.send()
+	}()
+	return w, nil
+}
+
+func (w *webhookWriter) write(line []byte) {
+	select {
+	case w.lines <- line:
+	default:
+		w.spool([][]byte{line})
+	}
+}
+
+// close flushes the pending batch and spools whatever the sender has not delivered.
+func (w *webhookWriter) close() error {
+	close(w.stop)
+	<-w.done
+	return nil
+}
+
+func (w *webhookWriter) collect() {
+	defer close(w.batches)
+	ticker := time.NewTicker(time.Duration(w.cfg.FlushIntervalSeconds) * time.Second)
+	defer ticker.Stop()
+	batch := make([][]byte, 0, w.cfg.BatchSize)
+	hand := func() {
+		if len(batch) == 0 {
+			return
+		}
+		select {
+		case w.batches <- batch:
+		default:
+			w.spool(batch)
+		}
+		batch = make([][]byte, 0, w.cfg.BatchSize)
+	}
+	for {
+		select {
+		case line := <-w.lines:
+			batch = append(batch, line)
+			if len(batch) >= w.cfg.BatchSize {
+				hand()
+			}
+		case <-ticker.C:
+			hand()
+		case <-w.stop:
+			for {
+				select {
+				case line := <-w.lines:
+					batch = append(batch, line)
+				default:
+					if len(batch) > 0 {
+						w.spool(batch)
+					}
+					return
+				}
+			}
+		}
+	}
+}
+
+func (w *webhookWriter) send() {
+	replay := time.NewTicker(spoolReplayInterval)
+	defer replay.Stop()
+	w.replaySpool()
+	for {
+		select {
+		case batch, ok := <-w.batches:
+			if !ok {
+				return
+			}
+			if w.stopped() {
+				w.spool(batch)
+				continue
+			}
+			if err := w.deliver(batch, w.cfg.MaxRetries); err != nil {
+				if errors.Is(err, errPermanent) {
+					log.Errorf("usage sink %s: dropping %d records: %v", w.cfg.Name, len(batch), err)
+					continue
+				}
+				log.Warnf("usage sink %s: spooling %d records: %v", w.cfg.Name, len(batch), err)
+				w.spool(batch)
+			}
+		case <-replay.C:
+			w.replaySpool()
+		}
+	}
+}
+
+func (w *webhookWriter) stopped() bool {
+	select {
+	case <-w.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// deliver POSTs batch, retrying transient failures with exponential backoff.
+func (w *webhookWriter) deliver(batch [][]byte, retries int) error {
+	body := make([]byte, 0, 2+len(batch)*256)
+	body = append(body, '[')
+	body = append(body, bytes.Join(batch, []byte{','})...)
+	body = append(body, ']')
+
+	delay := backoffBase
+	var err error
+	for attempt := 0; ; attempt++ {
+		if err = w.post(body); err == nil || errors.Is(err, errPermanent) || attempt >= retries {
+			return err
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-w.stop:
+			timer.Stop()
+			return err
+		}
+		delay = min(delay*2, maxBackoff)
+	}
+}
+
+func (w *webhookWriter) post(body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), w.client.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%w: %v", errPermanent, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range w.cfg.Headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	_ = resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return fmt.Errorf("endpoint returned %d", resp.StatusCode)
+	default:
+		return fmt.Errorf("%w: status %d", errPermanent, resp.StatusCode)
+	}
+}
+
+// spool writes batch to a new file and trims the oldest files beyond the size limit.
+func (w *webhookWriter) spool(batch [][]byte) {
+	w.spoolMu.Lock()
+	defer w.spoolMu.Unlock()
+	var buf bytes.Buffer
+	for _, line := range batch {
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	name := filepath.Join(w.spoolDir, strconv.FormatInt(time.Now().UnixNano(), 10)+".jsonl")
+	if err := os.WriteFile(name, buf.Bytes(), 0o600); err != nil {
+		log.Errorf("usage sink %s: spool %d records: %v", w.cfg.Name, len(batch), err)
+		return
+	}
+	files := w.spoolFiles()
+	var total int64
+	for _, file := range files {
+		total += file.size
+	}
+	limit := int64(w.cfg.MaxSpoolMB) << 20
+	for i := 0; total > limit && i < len(files)-1; i++ {
+		if err := os.Remove(files[i].path); err == nil {
+			total -= files[i].size
+			log.Warnf("usage sink %s: spool over %d MB, dropped %s", w.cfg.Name, w.cfg.MaxSpoolMB, filepath.Base(files[i].path))
+		}
+	}
+}
+
+type spoolFile struct {
+	path string
+	size int64
+}
+
+// spoolFiles lists spooled batches, oldest first.
+func (w *webhookWriter) spoolFiles() []spoolFile {
+	entries, err := os.ReadDir(w.spoolDir)
+	if err != nil {
+		return nil
+	}
+	var files []spoolFile
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".jsonl") {
+			continue
+		}
+		info, errInfo := entry.Info()
+		if errInfo != nil {
+			continue
+		}
+		files = append(files, spoolFile{path: filepath.Join(w.spoolDir, entry.Name()), size: info.Size()})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].path < files[j].path })
+	return files
+}
+
+// replaySpool sends spooled batches oldest first and stops at the first failure.
+func (w *webhookWriter) replaySpool() {
+	for _, file := range w.spoolFiles() {
+		if w.stopped() {
+			return
+		}
+		batch, err := readSpoolFile(file.path)
+		if err != nil {
+			log.Warnf("usage sink %s: read spool %s: %v", w.cfg.Name, file.path, err)
+			continue
+		}
+		if len(batch) > 0 {
+			if err = w.deliver(batch, 0); err != nil && !errors.Is(err, errPermanent) {
+				return
+			}
+		}
+		if errRemove := os.Remove(file.path); errRemove != nil {
+			log.Warnf("usage sink %s: remove spool %s: %v", w.cfg.Name, file.path, errRemove)
+			return
+		}
+	}
+}
+
+func readSpoolFile(path string) ([][]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	var batch [][]byte
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for scanner.Scan() {
+		if line := bytes.TrimSpace(scanner.Bytes()); len(line) > 0 {
+			batch = append(batch, append([]byte(nil), line...))
+		}
+	}
+	return batch, scanner.Err()
+}
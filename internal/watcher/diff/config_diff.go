@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/url"
 	"reflect"
+	"sort"
 	"strings"
 
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
@@ -33,12 +34,60 @@ func BuildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 	if strings.TrimSpace(oldCfg.Pprof.Addr) != strings.TrimSpace(newCfg.Pprof.Addr) {
 		changes = append(changes, fmt.Sprintf("pprof.addr: %s -> %s", strings.TrimSpace(oldCfg.Pprof.Addr), strings.TrimSpace(newCfg.Pprof.Addr)))
 	}
+	if oldCfg.Metrics.Enable != newCfg.Metrics.Enable {
+		changes = append(changes, fmt.Sprintf("metrics.enable: %t -> %t", oldCfg.Metrics.Enable, newCfg.Metrics.Enable))
+	}
+	if oldCfg.Metrics.BearerToken != newCfg.Metrics.BearerToken {
+		changes = append(changes, "metrics.bearer-token: updated")
+	}
+	if oldCfg.Tracing.Enable != newCfg.Tracing.Enable {
+		changes = append(changes, fmt.Sprintf("tracing.enable: %t -> %t", oldCfg.Tracing.Enable, newCfg.Tracing.Enable))
+	}
+	if oldCfg.Tracing.Exporter != newCfg.Tracing.Exporter {
+		changes = append(changes, fmt.Sprintf("tracing.exporter: %s -> %s", oldCfg.Tracing.Exporter, newCfg.Tracing.Exporter))
+	}
+	if oldCfg.Tracing.Endpoint != newCfg.Tracing.Endpoint {
+		changes = append(changes, fmt.Sprintf("tracing.endpoint: %s -> %s", oldCfg.Tracing.Endpoint, newCfg.Tracing.Endpoint))
+	}
+	if oldCfg.Tracing.SampleRatio != newCfg.Tracing.SampleRatio {
+		changes = append(changes, fmt.Sprintf("tracing.sample-ratio: %g -> %g", oldCfg.Tracing.SampleRatio, newCfg.Tracing.SampleRatio))
+	}
+	if !reflect.DeepEqual(oldCfg.Tracing.Headers, newCfg.Tracing.Headers) {
+		changes = append(changes, "tracing.headers: updated")
+	}
 	if oldCfg.LoggingToFile != newCfg.LoggingToFile {
 		changes = append(changes, fmt.Sprintf("logging-to-file: %t -> %t", oldCfg.LoggingToFile, newCfg.LoggingToFile))
 	}
 	if oldCfg.UsageStatisticsEnabled != newCfg.UsageStatisticsEnabled {
 		changes = append(changes, fmt.Sprintf("usage-statistics-enabled: %t -> %t", oldCfg.UsageStatisticsEnabled, newCfg.UsageStatisticsEnabled))
 	}
+	if oldCfg.UsageStore.Enable != newCfg.UsageStore.Enable {
+		changes = append(changes, fmt.Sprintf("usage-store.enable: %t -> %t", oldCfg.UsageStore.Enable, newCfg.UsageStore.Enable))
+	}
+	if oldCfg.UsageStore.Driver != newCfg.UsageStore.Driver {
+		changes = append(changes, fmt.Sprintf("usage-store.driver: %s -> %s", oldCfg.UsageStore.Driver, newCfg.UsageStore.Driver))
+	}
+	if oldCfg.UsageStore.Path != newCfg.UsageStore.Path {
+		changes = append(changes, fmt.Sprintf("usage-store.path: %s -> %s", oldCfg.UsageStore.Path, newCfg.UsageStore.Path))
+	}
+	if oldCfg.UsageStore.DSN != newCfg.UsageStore.DSN {
+		changes = append(changes, "usage-store.dsn: updated")
+	}
+	if oldCfg.UsageStore.RawRetentionDays != newCfg.UsageStore.RawRetentionDays || oldCfg.UsageStore.HourlyRetentionDays != newCfg.UsageStore.HourlyRetentionDays || oldCfg.UsageStore.DailyRetentionDays != newCfg.UsageStore.DailyRetentionDays {
+		changes = append(changes, fmt.Sprintf("usage-store retention (raw/hourly/daily days): %d/%d/%d -> %d/%d/%d",
+			oldCfg.UsageStore.RawRetentionDays, oldCfg.UsageStore.HourlyRetentionDays, oldCfg.UsageStore.DailyRetentionDays,
+			newCfg.UsageStore.RawRetentionDays, newCfg.UsageStore.HourlyRetentionDays, newCfg.UsageStore.DailyRetentionDays))
+	}
+	if oldCfg.UsageStore.SnapshotDetails != newCfg.UsageStore.SnapshotDetails {
+		changes = append(changes, fmt.Sprintf("usage-store.snapshot-details: %d -> %d", oldCfg.UsageStore.SnapshotDetails, newCfg.UsageStore.SnapshotDetails))
+	}
+	if !reflect.DeepEqual(oldCfg.UsageSinks, newCfg.UsageSinks) {
+		changes = append(changes, fmt.Sprintf("usage-sinks: updated (%d -> %d sinks)", len(oldCfg.UsageSinks), len(newCfg.UsageSinks)))
+	}
+	if !reflect.DeepEqual(oldCfg.Notifications, newCfg.Notifications) {
+		changes = append(changes, fmt.Sprintf("notifications: updated (enable %t -> %t, %d -> %d webhooks)",
+			oldCfg.Notifications.Enable, newCfg.Notifications.Enable, len(oldCfg.Notifications.Webhooks), len(newCfg.Notifications.Webhooks)))
+	}
 	if oldCfg.DisableCooling != newCfg.DisableCooling {
 		changes = append(changes, fmt.Sprintf("disable-cooling: %t -> %t", oldCfg.DisableCooling, newCfg.DisableCooling))
 	}
@@ -81,6 +130,79 @@ func BuildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 	if oldCfg.Routing.Strategy != newCfg.Routing.Strategy {
 		changes = append(changes, fmt.Sprintf("routing.strategy: %s -> %s", oldCfg.Routing.Strategy, newCfg.Routing.Strategy))
 	}
+	if oldCfg.Routing.SessionAffinityTTL != newCfg.Routing.SessionAffinityTTL {
+		changes = append(changes, fmt.Sprintf("routing.session-affinity-ttl: %d -> %d", oldCfg.Routing.SessionAffinityTTL, newCfg.Routing.SessionAffinityTTL))
+	}
+	if oldCfg.Routing.SessionAffinityHeader != newCfg.Routing.SessionAffinityHeader {
+		changes = append(changes, fmt.Sprintf("routing.session-affinity-header: %s -> %s", oldCfg.Routing.SessionAffinityHeader, newCfg.Routing.SessionAffinityHeader))
+	}
+	if oldCfg.Routing.AdaptiveExploration != newCfg.Routing.AdaptiveExploration {
+		changes = append(changes, fmt.Sprintf("routing.adaptive-exploration: %g -> %g", oldCfg.Routing.AdaptiveExploration, newCfg.Routing.AdaptiveExploration))
+	}
+	if oldCfg.CircuitBreaker.Enable != newCfg.CircuitBreaker.Enable {
+		changes = append(changes, fmt.Sprintf("circuit-breaker.enable: %t -> %t", oldCfg.CircuitBreaker.Enable, newCfg.CircuitBreaker.Enable))
+	}
+	if oldCfg.CircuitBreaker.FailureThreshold != newCfg.CircuitBreaker.FailureThreshold {
+		changes = append(changes, fmt.Sprintf("circuit-breaker.failure-threshold: %d -> %d", oldCfg.CircuitBreaker.FailureThreshold, newCfg.CircuitBreaker.FailureThreshold))
+	}
+	if oldCfg.CircuitBreaker.ProviderFailureThreshold != newCfg.CircuitBreaker.ProviderFailureThreshold {
+		changes = append(changes, fmt.Sprintf("circuit-breaker.provider-failure-threshold: %d -> %d", oldCfg.CircuitBreaker.ProviderFailureThreshold, newCfg.CircuitBreaker.ProviderFailureThreshold))
+	}
+	if oldCfg.CircuitBreaker.OpenSeconds != newCfg.CircuitBreaker.OpenSeconds {
+		changes = append(changes, fmt.Sprintf("circuit-breaker.open-seconds: %d -> %d", oldCfg.CircuitBreaker.OpenSeconds, newCfg.CircuitBreaker.OpenSeconds))
+	}
+	if oldCfg.MaxConcurrencyWaitSeconds != newCfg.MaxConcurrencyWaitSeconds {
+		changes = append(changes, fmt.Sprintf("max-concurrency-wait-seconds: %d -> %d", oldCfg.MaxConcurrencyWaitSeconds, newCfg.MaxConcurrencyWaitSeconds))
+	}
+	if oldCfg.CooldownQueue.Enable != newCfg.CooldownQueue.Enable {
+		changes = append(changes, fmt.Sprintf("cooldown-queue.enable: %t -> %t", oldCfg.CooldownQueue.Enable, newCfg.CooldownQueue.Enable))
+	}
+	if oldCfg.CooldownQueue.MaxWaitSeconds != newCfg.CooldownQueue.MaxWaitSeconds {
+		changes = append(changes, fmt.Sprintf("cooldown-queue.max-wait-seconds: %d -> %d", oldCfg.CooldownQueue.MaxWaitSeconds, newCfg.CooldownQueue.MaxWaitSeconds))
+	}
+	if oldCfg.CooldownQueue.MaxDepth != newCfg.CooldownQueue.MaxDepth {
+		changes = append(changes, fmt.Sprintf("cooldown-queue.max-depth: %d -> %d", oldCfg.CooldownQueue.MaxDepth, newCfg.CooldownQueue.MaxDepth))
+	}
+	if !reflect.DeepEqual(oldCfg.CooldownQueue.Priorities, newCfg.CooldownQueue.Priorities) {
+		changes = append(changes, fmt.Sprintf("cooldown-queue.priorities: updated (%d -> %d entries)", len(oldCfg.CooldownQueue.Priorities), len(newCfg.CooldownQueue.Priorities)))
+	}
+
+	for _, model := range unionKeys(oldCfg.ModelFallbacks, newCfg.ModelFallbacks) {
+		oldChain, newChain := oldCfg.ModelFallbacks[model], newCfg.ModelFallbacks[model]
+		if !reflect.DeepEqual(oldChain, newChain) {
+			changes = append(changes, fmt.Sprintf("model-fallbacks.%s: %v -> %v", model, oldChain, newChain))
+		}
+	}
+	if !reflect.DeepEqual(oldCfg.Hedging, newCfg.Hedging) {
+		changes = append(changes, fmt.Sprintf("hedging: %d -> %d rules", len(oldCfg.Hedging), len(newCfg.Hedging)))
+	}
+	if !reflect.DeepEqual(oldCfg.Pricing, newCfg.Pricing) {
+		changes = append(changes, fmt.Sprintf("pricing: %d -> %d entries", len(oldCfg.Pricing), len(newCfg.Pricing)))
+	}
+	if oldCfg.Budget.SoftLimitPercent != newCfg.Budget.SoftLimitPercent {
+		changes = append(changes, fmt.Sprintf("budget.soft-limit-percent: %d -> %d", oldCfg.Budget.SoftLimitPercent, newCfg.Budget.SoftLimitPercent))
+	}
+	if oldCfg.Budget.StateFile != newCfg.Budget.StateFile {
+		changes = append(changes, fmt.Sprintf("budget.state-file: %s -> %s", oldCfg.Budget.StateFile, newCfg.Budget.StateFile))
+	}
+	if oldCfg.Budget.WebhookURL != newCfg.Budget.WebhookURL {
+		changes = append(changes, "budget.webhook-url: updated")
+	}
+
+	// CORS policies
+	changes = append(changes, corsPolicyChanges("cors", oldCfg.CORS.CORSPolicy, newCfg.CORS.CORSPolicy)...)
+	for _, group := range unionKeys(oldCfg.CORS.Routes, newCfg.CORS.Routes) {
+		oldPolicy, oldOK := oldCfg.CORS.Routes[group]
+		newPolicy, newOK := newCfg.CORS.Routes[group]
+		switch {
+		case !oldOK:
+			changes = append(changes, fmt.Sprintf("cors.routes.%s: added", group))
+		case !newOK:
+			changes = append(changes, fmt.Sprintf("cors.routes.%s: removed", group))
+		default:
+			changes = append(changes, corsPolicyChanges("cors.routes."+group, oldPolicy, newPolicy)...)
+		}
+	}
 
 	// API keys (redacted) and counts
 	if len(oldCfg.APIKeys) != len(newCfg.APIKeys) {
@@ -88,6 +210,12 @@ func BuildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 	} else if !reflect.DeepEqual(trimStrings(oldCfg.APIKeys), trimStrings(newCfg.APIKeys)) {
 		changes = append(changes, "api-keys: values updated (count unchanged, redacted)")
 	}
+	if !reflect.DeepEqual(oldCfg.Access, newCfg.Access) {
+		changes = append(changes, fmt.Sprintf("auth.providers: updated (%d -> %d providers)", len(oldCfg.Access.Providers), len(newCfg.Access.Providers)))
+	}
+	if !reflect.DeepEqual(oldCfg.APIKeyPolicies, newCfg.APIKeyPolicies) {
+		changes = append(changes, fmt.Sprintf("api-key-policies: updated (%d -> %d entries, redacted)", len(oldCfg.APIKeyPolicies), len(newCfg.APIKeyPolicies)))
+	}
 	if len(oldCfg.GeminiKey) != len(newCfg.GeminiKey) {
 		changes = append(changes, fmt.Sprintf("gemini-api-key count: %d -> %d", len(oldCfg.GeminiKey), len(newCfg.GeminiKey)))
 	} else {
@@ -103,6 +231,9 @@ func BuildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 			if strings.TrimSpace(o.Prefix) != strings.TrimSpace(n.Prefix) {
 				changes = append(changes, fmt.Sprintf("gemini[%d].prefix: %s -> %s", i, strings.TrimSpace(o.Prefix), strings.TrimSpace(n.Prefix)))
 			}
+			if o.MaxConcurrency != n.MaxConcurrency {
+				changes = append(changes, fmt.Sprintf("gemini[%d].max-concurrency: %d -> %d", i, o.MaxConcurrency, n.MaxConcurrency))
+			}
 			if strings.TrimSpace(o.APIKey) != strings.TrimSpace(n.APIKey) {
 				changes = append(changes, fmt.Sprintf("gemini[%d].api-key: updated", i))
 			}
@@ -138,6 +269,9 @@ func BuildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 			if strings.TrimSpace(o.Prefix) != strings.TrimSpace(n.Prefix) {
 				changes = append(changes, fmt.Sprintf("claude[%d].prefix: %s -> %s", i, strings.TrimSpace(o.Prefix), strings.TrimSpace(n.Prefix)))
 			}
+			if o.MaxConcurrency != n.MaxConcurrency {
+				changes = append(changes, fmt.Sprintf("claude[%d].max-concurrency: %d -> %d", i, o.MaxConcurrency, n.MaxConcurrency))
+			}
 			if strings.TrimSpace(o.APIKey) != strings.TrimSpace(n.APIKey) {
 				changes = append(changes, fmt.Sprintf("claude[%d].api-key: updated", i))
 			}
@@ -184,6 +318,9 @@ func BuildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 			if strings.TrimSpace(o.Prefix) != strings.TrimSpace(n.Prefix) {
 				changes = append(changes, fmt.Sprintf("codex[%d].prefix: %s -> %s", i, strings.TrimSpace(o.Prefix), strings.TrimSpace(n.Prefix)))
 			}
+			if o.MaxConcurrency != n.MaxConcurrency {
+				changes = append(changes, fmt.Sprintf("codex[%d].max-concurrency: %d -> %d", i, o.MaxConcurrency, n.MaxConcurrency))
+			}
 			if strings.TrimSpace(o.APIKey) != strings.TrimSpace(n.APIKey) {
 				changes = append(changes, fmt.Sprintf("codex[%d].api-key: updated", i))
 			}
@@ -265,6 +402,9 @@ func BuildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 			changes = append(changes, "remote-management.secret-key: updated")
 		}
 	}
+	if !reflect.DeepEqual(oldCfg.RemoteManagement.Keys, newCfg.RemoteManagement.Keys) {
+		changes = append(changes, fmt.Sprintf("remote-management.keys: updated (%d -> %d entries, redacted)", len(oldCfg.RemoteManagement.Keys), len(newCfg.RemoteManagement.Keys)))
+	}
 
 	// OpenAI compatibility providers (summarized)
 	if compat := DiffOpenAICompatibility(oldCfg.OpenAICompatibility, newCfg.OpenAICompatibility); len(compat) > 0 {
@@ -290,6 +430,9 @@ func BuildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 			if strings.TrimSpace(o.Prefix) != strings.TrimSpace(n.Prefix) {
 				changes = append(changes, fmt.Sprintf("vertex[%d].prefix: %s -> %s", i, strings.TrimSpace(o.Prefix), strings.TrimSpace(n.Prefix)))
 			}
+			if o.MaxConcurrency != n.MaxConcurrency {
+				changes = append(changes, fmt.Sprintf("vertex[%d].max-concurrency: %d -> %d", i, o.MaxConcurrency, n.MaxConcurrency))
+			}
 			if strings.TrimSpace(o.APIKey) != strings.TrimSpace(n.APIKey) {
 				changes = append(changes, fmt.Sprintf("vertex[%d].api-key: updated", i))
 			}
@@ -307,6 +450,49 @@ func BuildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 	return changes
 }
 
+func corsPolicyChanges(path string, oldPolicy, newPolicy config.CORSPolicy) []string {
+	var changes []string
+	if oldPolicy.Disable != newPolicy.Disable {
+		changes = append(changes, fmt.Sprintf("%s.disable: %t -> %t", path, oldPolicy.Disable, newPolicy.Disable))
+	}
+	if !reflect.DeepEqual(oldPolicy.AllowedOrigins, newPolicy.AllowedOrigins) {
+		changes = append(changes, fmt.Sprintf("%s.allowed-origins: %v -> %v", path, oldPolicy.AllowedOrigins, newPolicy.AllowedOrigins))
+	}
+	if !reflect.DeepEqual(oldPolicy.AllowedMethods, newPolicy.AllowedMethods) {
+		changes = append(changes, fmt.Sprintf("%s.allowed-methods: %v -> %v", path, oldPolicy.AllowedMethods, newPolicy.AllowedMethods))
+	}
+	if !reflect.DeepEqual(oldPolicy.AllowedHeaders, newPolicy.AllowedHeaders) {
+		changes = append(changes, fmt.Sprintf("%s.allowed-headers: %v -> %v", path, oldPolicy.AllowedHeaders, newPolicy.AllowedHeaders))
+	}
+	if !reflect.DeepEqual(oldPolicy.ExposedHeaders, newPolicy.ExposedHeaders) {
+		changes = append(changes, fmt.Sprintf("%s.exposed-headers: %v -> %v", path, oldPolicy.ExposedHeaders, newPolicy.ExposedHeaders))
+	}
+	if oldPolicy.AllowCredentials != newPolicy.AllowCredentials {
+		changes = append(changes, fmt.Sprintf("%s.allow-credentials: %t -> %t", path, oldPolicy.AllowCredentials, newPolicy.AllowCredentials))
+	}
+	if oldPolicy.MaxAge != newPolicy.MaxAge {
+		changes = append(changes, fmt.Sprintf("%s.max-age: %d -> %d", path, oldPolicy.MaxAge, newPolicy.MaxAge))
+	}
+	return changes
+}
+
+func unionKeys[V any](a, b map[string]V) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	seen := make(map[string]struct{}, len(a)+len(b))
+	for k := range a {
+		seen[k] = struct{}{}
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := seen[k]; ok {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 func trimStrings(in []string) []string {
 	out := make([]string, len(in))
 	for i := range in {
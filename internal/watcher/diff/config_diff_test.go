@@ -138,6 +138,23 @@ func TestBuildConfigChangeDetails_GeminiVertexHeadersAndForceMappings(t *testing
 	expectContains(t, details, "ampcode.force-model-mappings: false -> true")
 }
 
+func TestBuildConfigChangeDetails_MaxConcurrency(t *testing.T) {
+	oldCfg := &config.Config{
+		VertexCompatAPIKey:  []config.VertexCompatKey{{APIKey: "v1", MaxConcurrency: 1}},
+		OpenAICompatibility: []config.OpenAICompatibility{{Name: "compat", MaxConcurrency: 2}},
+	}
+	newCfg := &config.Config{
+		MaxConcurrencyWaitSeconds: 10,
+		VertexCompatAPIKey:        []config.VertexCompatKey{{APIKey: "v1", MaxConcurrency: 3}},
+		OpenAICompatibility:       []config.OpenAICompatibility{{Name: "compat", MaxConcurrency: 4}},
+	}
+
+	details := BuildConfigChangeDetails(oldCfg, newCfg)
+	expectContains(t, details, "max-concurrency-wait-seconds: 0 -> 10")
+	expectContains(t, details, "vertex[0].max-concurrency: 1 -> 3")
+	expectContains(t, details, "  provider updated: compat (max-concurrency 2 -> 4)")
+}
+
 func TestBuildConfigChangeDetails_ModelPrefixes(t *testing.T) {
 	oldCfg := &config.Config{
 		GeminiKey: []config.GeminiKey{
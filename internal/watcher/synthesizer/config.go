@@ -63,6 +63,9 @@ func (s *ConfigSynthesizer) synthesizeGeminiKeys(ctx *SynthesisContext) []*corea
 		if entry.Priority != 0 {
 			attrs["priority"] = strconv.Itoa(entry.Priority)
 		}
+		if entry.MaxConcurrency > 0 {
+			attrs["max_concurrency"] = strconv.Itoa(entry.MaxConcurrency)
+		}
 		if base != "" {
 			attrs["base_url"] = base
 		}
@@ -110,6 +113,9 @@ func (s *ConfigSynthesizer) synthesizeClaudeKeys(ctx *SynthesisContext) []*corea
 		if ck.Priority != 0 {
 			attrs["priority"] = strconv.Itoa(ck.Priority)
 		}
+		if ck.MaxConcurrency > 0 {
+			attrs["max_concurrency"] = strconv.Itoa(ck.MaxConcurrency)
+		}
 		if base != "" {
 			attrs["base_url"] = base
 		}
@@ -157,6 +163,9 @@ func (s *ConfigSynthesizer) synthesizeCodexKeys(ctx *SynthesisContext) []*coreau
 		if ck.Priority != 0 {
 			attrs["priority"] = strconv.Itoa(ck.Priority)
 		}
+		if ck.MaxConcurrency > 0 {
+			attrs["max_concurrency"] = strconv.Itoa(ck.MaxConcurrency)
+		}
 		if ck.BaseURL != "" {
 			attrs["base_url"] = ck.BaseURL
 		}
@@ -215,6 +224,9 @@ func (s *ConfigSynthesizer) synthesizeOpenAICompat(ctx *SynthesisContext) []*cor
 			if compat.Priority != 0 {
 				attrs["priority"] = strconv.Itoa(compat.Priority)
 			}
+			if compat.MaxConcurrency > 0 {
+				attrs["max_concurrency"] = strconv.Itoa(compat.MaxConcurrency)
+			}
 			if key != "" {
 				attrs["api_key"] = key
 			}
@@ -249,6 +261,9 @@ func (s *ConfigSynthesizer) synthesizeOpenAICompat(ctx *SynthesisContext) []*cor
 			if compat.Priority != 0 {
 				attrs["priority"] = strconv.Itoa(compat.Priority)
 			}
+			if compat.MaxConcurrency > 0 {
+				attrs["max_concurrency"] = strconv.Itoa(compat.MaxConcurrency)
+			}
 			if hash := diff.ComputeOpenAICompatModelsHash(compat.Models); hash != "" {
 				attrs["models_hash"] = hash
 			}
@@ -294,6 +309,9 @@ func (s *ConfigSynthesizer) synthesizeVertexCompat(ctx *SynthesisContext) []*cor
 		if compat.Priority != 0 {
 			attrs["priority"] = strconv.Itoa(compat.Priority)
 		}
+		if compat.MaxConcurrency > 0 {
+			attrs["max_concurrency"] = strconv.Itoa(compat.MaxConcurrency)
+		}
 		if key != "" {
 			attrs["api_key"] = key
 		}
@@ -124,6 +124,25 @@ func (s *FileSynthesizer) Synthesize(ctx *SynthesisContext) ([]*coreauth.Auth, e
 				}
 			}
 		}
+		// Read max concurrency from auth file
+		for _, key := range []string{"max_concurrency", "max-concurrency"} {
+			rawMax, ok := metadata[key]
+			if !ok {
+				continue
+			}
+			switch v := rawMax.(type) {
+			case float64:
+				if v > 0 {
+					a.Attributes["max_concurrency"] = strconv.Itoa(int(v))
+				}
+			case string:
+				maxConcurrency := strings.TrimSpace(v)
+				if parsed, errAtoi := strconv.Atoi(maxConcurrency); errAtoi == nil && parsed > 0 {
+					a.Attributes["max_concurrency"] = maxConcurrency
+				}
+			}
+			break
+		}
 		ApplyAuthExcludedModelsMeta(a, cfg, perAccountExcluded, "oauth")
 		if provider == "gemini-cli" {
 			if virtuals := SynthesizeGeminiVirtualAuths(a, metadata, now); len(virtuals) > 0 {
@@ -187,6 +206,9 @@ func SynthesizeGeminiVirtualAuths(primary *coreauth.Auth, metadata map[string]an
 		if priorityVal, hasPriority := primary.Attributes["priority"]; hasPriority && priorityVal != "" {
 			attrs["priority"] = priorityVal
 		}
+		if maxVal, hasMax := primary.Attributes["max_concurrency"]; hasMax && maxVal != "" {
+			attrs["max_concurrency"] = maxVal
+		}
 		metadataCopy := map[string]any{
 			"email":             email,
 			"project_id":        projectID,
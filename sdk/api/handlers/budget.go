@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/budget"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+)
+
+// budgetWarningHeader carries one soft-limit warning per budget on successful responses.
+const budgetWarningHeader = "X-Budget-Warning"
+
+// applyClientBudget rejects the request with a 429 in the inbound format when the client API
+// key has used up one of its budgets, and otherwise adds an X-Budget-Warning header for every
+// budget past its soft limit. The returned context marks the request so its upstream attempts
+// count once against max-requests.
+func (h *BaseAPIHandler) applyClientBudget(ctx context.Context, handlerType string) (context.Context, *interfaces.ErrorMessage) {
+	if h.Cfg == nil || len(h.Cfg.APIKeyPolicies) == 0 {
+		return ctx, nil
+	}
+	key := clientAPIKey(ctx)
+	if policy := h.Cfg.APIKeyPolicy(key); policy == nil || len(policy.Budgets) == 0 {
+		return ctx, nil
+	}
+	warnings, exhausted := budget.Default().Check(key)
+	if exhausted != nil {
+		seconds := int(math.Ceil(time.Until(exhausted.ResetsAt).Seconds()))
+		addon := http.Header{}
+		addon.Set("Retry-After", strconv.Itoa(max(seconds, 1)))
+		return ctx, newFormattedErrorMessage(handlerType, http.StatusTooManyRequests, "budget_exceeded",
+			fmt.Sprintf("%s %s budget exhausted for this API key; it resets at %s",
+				exhausted.Period, exhausted.Metric, exhausted.ResetsAt.Format(time.RFC3339)), addon)
+	}
+	if ginCtx, ok := ctx.Value("gin").(*gin.Context); ok && ginCtx != nil {
+		for _, warning := range warnings {
+			ginCtx.Writer.Header().Add(budgetWarningHeader, fmt.Sprintf("%s %s budget %.0f%% used, resets at %s",
+				warning.Period, warning.Metric, warning.Used/warning.Limit*100, warning.ResetsAt.Format(time.RFC3339)))
+		}
+	}
+	return budget.WithRequest(ctx), nil
+}
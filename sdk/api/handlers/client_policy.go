@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/constant"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/thinking"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+)
+
+// clientAPIKey returns the authenticated client API key stored on the request's gin context.
+func clientAPIKey(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	ginCtx, ok := ctx.Value("gin").(*gin.Context)
+	if !ok || ginCtx == nil {
+		return ""
+	}
+	return ginAPIKey(ginCtx)
+}
+
+func ginAPIKey(c *gin.Context) string {
+	value, exists := c.Get("apiKey")
+	if !exists {
+		return ""
+	}
+	key, _ := value.(string)
+	return key
+}
+
+// clientPolicy returns the policy of the client API key authenticated for ctx, if any.
+func (h *BaseAPIHandler) clientPolicy(ctx context.Context) *config.APIKeyPolicy {
+	if h.Cfg == nil || len(h.Cfg.APIKeyPolicies) == 0 {
+		return nil
+	}
+	return h.Cfg.APIKeyPolicy(clientAPIKey(ctx))
+}
+
+// bindCredentialPrefix returns the first bound prefix of policy whose credentials serve the
+// unprefixed model, or "" when the key is unbound, the model already names a prefix, or no
+// bound credential serves it.
+func (h *BaseAPIHandler) bindCredentialPrefix(policy *config.APIKeyPolicy, model string) string {
+	if !policy.IsBound() || model == "" {
+		return ""
+	}
+	if prefix, _ := h.splitCredentialPrefix(model); prefix != "" {
+		return ""
+	}
+	for _, prefix := range policy.BoundPrefixes {
+		if len(util.GetProviderName(prefix+"/"+model)) > 0 {
+			return prefix
+		}
+	}
+	return ""
+}
+
+// splitCredentialPrefix separates a "prefix/model" request into the credential prefix and
+// the model name. Model names that merely contain a slash keep an empty prefix.
+func (h *BaseAPIHandler) splitCredentialPrefix(model string) (string, string) {
+	idx := strings.Index(model, "/")
+	if idx <= 0 || h.AuthManager == nil || !h.AuthManager.HasPrefix(model[:idx]) {
+		return "", model
+	}
+	return model[:idx], model[idx+1:]
+}
+
+// policyAllowsModel reports whether policy lets the client use modelID at all: the model
+// pattern, its credential prefix and at least one provider serving it must be allowed.
+func (h *BaseAPIHandler) policyAllowsModel(policy *config.APIKeyPolicy, modelID string) bool {
+	if bound := h.bindCredentialPrefix(policy, modelID); bound != "" {
+		modelID = bound + "/" + modelID
+	}
+	prefix, model := h.splitCredentialPrefix(modelID)
+	if !policy.AllowsModel(model) || (prefix == "" && policy.IsBound()) || (prefix != "" && !policy.AllowsPrefix(prefix)) {
+		return false
+	}
+	if len(policy.AllowedProviders) == 0 {
+		return true
+	}
+	for _, provider := range util.GetProviderName(modelID) {
+		if policy.AllowsProvider(provider) {
+			return true
+		}
+	}
+	return false
+}
+
+// authorizeClientModel applies the client API key's policy to a resolved request. It returns
+// the providers the key may use and a context that applies the policy to model fallbacks, or
+// a 403 in the inbound format when the model, prefix or every provider is not allowed.
+// Keys bound to credential prefixes are refused models none of their credentials serve,
+// and the returned context restricts the conductor to the bound credentials.
+func (h *BaseAPIHandler) authorizeClientModel(ctx context.Context, handlerType, modelName string, providers []string) (context.Context, []string, *interfaces.ErrorMessage) {
+	policy := h.clientPolicy(ctx)
+	if policy == nil {
+		return ctx, providers, nil
+	}
+	prefix, model := h.splitCredentialPrefix(thinking.ParseSuffix(modelName).ModelName)
+	if prefix != "" && !policy.AllowsPrefix(prefix) {
+		return ctx, nil, newFormattedErrorMessage(handlerType, http.StatusForbidden, "permission_denied",
+			fmt.Sprintf("this API key may not use credentials with prefix %s", prefix), nil)
+	}
+	if prefix == "" && policy.IsBound() {
+		return ctx, nil, newFormattedErrorMessage(handlerType, http.StatusForbidden, "permission_denied",
+			fmt.Sprintf("no credentials bound to this API key serve model %s", modelName), nil)
+	}
+	if !policy.AllowsModel(model) {
+		return ctx, nil, newFormattedErrorMessage(handlerType, http.StatusForbidden, "model_not_allowed",
+			fmt.Sprintf("this API key may not use model %s", modelName), nil)
+	}
+	allowed := make([]string, 0, len(providers))
+	for _, provider := range providers {
+		if policy.AllowsProvider(provider) {
+			allowed = append(allowed, provider)
+		}
+	}
+	if len(allowed) == 0 {
+		return ctx, nil, newFormattedErrorMessage(handlerType, http.StatusForbidden, "provider_not_allowed",
+			fmt.Sprintf("this API key may not use the providers serving model %s", modelName), nil)
+	}
+	ctx = coreauth.WithCredentialPrefixes(ctx, policy.BoundPrefixes)
+	ctx = coreauth.WithModelAccess(ctx, func(fallbackModel, provider string) bool {
+		fallbackPrefix, fallbackBase := h.splitCredentialPrefix(thinking.ParseSuffix(fallbackModel).ModelName)
+		return policy.AllowsModel(fallbackBase) && policy.AllowsProvider(provider) &&
+			(fallbackPrefix == "" || policy.AllowsPrefix(fallbackPrefix))
+	})
+	return ctx, allowed, nil
+}
+
+// FilterModelsForClient removes models the requesting client API key is not allowed to use
+// from a models listing. Entries are identified by "id", or by "name" for Gemini listings.
+func (h *BaseAPIHandler) FilterModelsForClient(c *gin.Context, models []map[string]any) []map[string]any {
+	if h.Cfg == nil || len(h.Cfg.APIKeyPolicies) == 0 || c == nil {
+		return models
+	}
+	policy := h.Cfg.APIKeyPolicy(ginAPIKey(c))
+	if policy == nil {
+		return models
+	}
+	out := make([]map[string]any, 0, len(models))
+	for _, model := range models {
+		id, _ := model["id"].(string)
+		if id == "" {
+			name, _ := model["name"].(string)
+			id = strings.TrimPrefix(name, "models/")
+		}
+		if id != "" && h.policyAllowsModel(policy, id) {
+			out = append(out, model)
+		}
+	}
+	return out
+}
+
+// newFormattedErrorMessage builds an error whose body follows the inbound API's error shape,
+// so clients of each format can parse proxy-generated rejections.
+func newFormattedErrorMessage(handlerType string, status int, code, message string, addon http.Header) *interfaces.ErrorMessage {
+	return &interfaces.ErrorMessage{
+		StatusCode: status,
+		Error:      fmt.Errorf("%s", formatErrorBody(handlerType, status, code, message)),
+		Addon:      addon,
+	}
+}
+
+func formatErrorBody(handlerType string, status int, code, message string) []byte {
+	var payload any
+	switch handlerType {
+	case constant.Claude:
+		errType := "permission_error"
+		if status == http.StatusTooManyRequests {
+			errType = "rate_limit_error"
+		}
+		payload = map[string]any{
+			"type":  "error",
+			"error": map[string]any{"type": errType, "message": message},
+		}
+	case constant.Gemini, constant.GeminiCLI:
+		state := "PERMISSION_DENIED"
+		if status == http.StatusTooManyRequests {
+			state = "RESOURCE_EXHAUSTED"
+		}
+		payload = map[string]any{
+			"error": map[string]any{"code": status, "message": message, "status": state},
+		}
+	default:
+		errType := "permission_error"
+		if status == http.StatusTooManyRequests {
+			errType = "rate_limit_error"
+		}
+		payload = ErrorResponse{Error: ErrorDetail{Message: message, Type: errType, Code: code}}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return BuildErrorResponseBody(status, message)
+	}
+	return body
+}
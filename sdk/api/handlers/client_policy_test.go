@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/budget"
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+)
+
+func newPolicyTestHandler(t *testing.T) *BaseAPIHandler {
+	t.Helper()
+	modelRegistry := registry.GetGlobalRegistry()
+	modelRegistry.RegisterClient("policy-claude", "claude", []*registry.ModelInfo{{ID: "policy-sonnet"}, {ID: "policy-opus"}})
+	modelRegistry.RegisterClient("policy-codex", "codex", []*registry.ModelInfo{{ID: "policy-sonnet"}})
+	t.Cleanup(func() {
+		modelRegistry.UnregisterClient("policy-claude")
+		modelRegistry.UnregisterClient("policy-codex")
+	})
+
+	manager := coreauth.NewManager(nil, nil, nil)
+	if _, err := manager.Register(context.Background(), &coreauth.Auth{ID: "policy-team-b", Provider: "claude", Prefix: "team-b"}); err != nil {
+		t.Fatalf("register auth: %v", err)
+	}
+	cfg := &sdkconfig.SDKConfig{APIKeyPolicies: []sdkconfig.APIKeyPolicy{{
+		APIKey:           "restricted",
+		AllowedModels:    []string{"policy-*"},
+		DeniedModels:     []string{"*-opus"},
+		AllowedProviders: []string{"claude"},
+		AllowedPrefixes:  []string{"team-a"},
+	}}}
+	return NewBaseAPIHandlers(cfg, manager)
+}
+
+func policyTestContext(apiKey string) (*gin.Context, context.Context) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+	c.Set("apiKey", apiKey)
+	return c, context.WithValue(context.Background(), "gin", c)
+}
+
+func TestAuthorizeClientModel(t *testing.T) {
+	handler := newPolicyTestHandler(t)
+	_, ctx := policyTestContext("restricted")
+
+	_, providers, errMsg := handler.authorizeClientModel(ctx, "openai", "policy-sonnet(high)", []string{"claude", "codex"})
+	if errMsg != nil {
+		t.Fatalf("authorizeClientModel() error = %v", errMsg.Error)
+	}
+	if !reflect.DeepEqual(providers, []string{"claude"}) {
+		t.Fatalf("providers = %v, want [claude]", providers)
+	}
+
+	tests := []struct {
+		name        string
+		handlerType string
+		model       string
+		wantBody    string
+	}{
+		{"denied model", "openai", "policy-opus", `"code":"model_not_allowed"`},
+		{"unlisted model", "claude", "gpt-5", `"type":"permission_error"`},
+		{"other tenant prefix", "gemini", "team-b/policy-sonnet", `"status":"PERMISSION_DENIED"`},
+	}
+	for _, tc := range tests {
+		_, _, errMsg = handler.authorizeClientModel(ctx, tc.handlerType, tc.model, []string{"claude"})
+		if errMsg == nil || errMsg.StatusCode != http.StatusForbidden {
+			t.Fatalf("%s: authorizeClientModel() = %+v, want 403", tc.name, errMsg)
+		}
+		if body := errMsg.Error.Error(); !strings.Contains(body, tc.wantBody) {
+			t.Fatalf("%s: body = %s, want it to contain %s", tc.name, body, tc.wantBody)
+		}
+	}
+
+	_, unrestricted := policyTestContext("other-key")
+	if _, providers, errMsg = handler.authorizeClientModel(unrestricted, "openai", "policy-opus", []string{"claude", "codex"}); errMsg != nil || len(providers) != 2 {
+		t.Fatalf("unrestricted key: providers = %v, err = %v", providers, errMsg)
+	}
+}
+
+func TestFilterModelsForClient(t *testing.T) {
+	handler := newPolicyTestHandler(t)
+	c, _ := policyTestContext("restricted")
+
+	models := []map[string]any{
+		{"id": "policy-sonnet"},
+		{"id": "policy-opus"},
+		{"name": "models/gpt-5"},
+		{"id": "team-b/policy-sonnet"},
+	}
+	got := handler.FilterModelsForClient(c, models)
+	if len(got) != 1 || got[0]["id"] != "policy-sonnet" {
+		t.Fatalf("FilterModelsForClient() = %v, want only policy-sonnet", got)
+	}
+}
+
+func TestBoundPrefixRouting(t *testing.T) {
+	modelRegistry := registry.GetGlobalRegistry()
+	modelRegistry.RegisterClient("bound-team-a", "claude", []*registry.ModelInfo{{ID: "team-a/bound-sonnet"}})
+	modelRegistry.RegisterClient("bound-team-b", "claude", []*registry.ModelInfo{{ID: "team-b/bound-sonnet"}, {ID: "team-b/bound-opus"}})
+	t.Cleanup(func() {
+		modelRegistry.UnregisterClient("bound-team-a")
+		modelRegistry.UnregisterClient("bound-team-b")
+	})
+	manager := coreauth.NewManager(nil, nil, nil)
+	for id, prefix := range map[string]string{"bound-team-a": "team-a", "bound-team-b": "team-b"} {
+		if _, err := manager.Register(context.Background(), &coreauth.Auth{ID: id, Provider: "claude", Prefix: prefix}); err != nil {
+			t.Fatalf("register auth: %v", err)
+		}
+	}
+	handler := NewBaseAPIHandlers(&sdkconfig.SDKConfig{APIKeyPolicies: []sdkconfig.APIKeyPolicy{{
+		APIKey:        "tenant-a",
+		BoundPrefixes: []string{"team-a"},
+	}}}, manager)
+	c, ctx := policyTestContext("tenant-a")
+
+	providers, model, errMsg := handler.getRequestDetails(ctx, "bound-sonnet(high)")
+	if errMsg != nil || model != "team-a/bound-sonnet(high)" || !reflect.DeepEqual(providers, []string{"claude"}) {
+		t.Fatalf("getRequestDetails() = %v, %q, %v", providers, model, errMsg)
+	}
+	if _, _, errMsg = handler.authorizeClientModel(ctx, "openai", model, providers); errMsg != nil {
+		t.Fatalf("authorizeClientModel() error = %v", errMsg.Error)
+	}
+
+	for _, requested := range []string{"team-b/bound-sonnet", "bound-opus"} {
+		_, model, _ = handler.getRequestDetails(ctx, requested)
+		if _, _, errMsg = handler.authorizeClientModel(ctx, "openai", model, []string{"claude"}); errMsg == nil || errMsg.StatusCode != http.StatusForbidden {
+			t.Fatalf("%s: authorizeClientModel() = %+v, want 403", requested, errMsg)
+		}
+	}
+
+	got := handler.FilterModelsForClient(c, []map[string]any{{"id": "bound-sonnet"}, {"id": "team-b/bound-sonnet"}, {"id": "bound-opus"}})
+	if len(got) != 1 || got[0]["id"] != "bound-sonnet" {
+		t.Fatalf("FilterModelsForClient() = %v, want only bound-sonnet", got)
+	}
+}
+
+func TestExecuteCountWithAuthManager_RejectsExhaustedBudget(t *testing.T) {
+	handler := newPolicyTestHandler(t)
+	policies := []sdkconfig.APIKeyPolicy{{APIKey: "count-budget", Budgets: []internalconfig.APIKeyBudget{{Period: "daily", MaxTokens: 10}}}}
+	handler.Cfg.APIKeyPolicies = policies
+	cfg := &internalconfig.Config{}
+	cfg.APIKeyPolicies = policies
+	cfg.Budget.StateFile = filepath.Join(t.TempDir(), "budget-state")
+	budget.Default().SetConfig(cfg)
+	t.Cleanup(func() { budget.Default().SetConfig(&internalconfig.Config{}) })
+	budget.Default().HandleUsage(context.Background(), coreusage.Record{APIKey: "count-budget", Detail: coreusage.Detail{TotalTokens: 20}})
+
+	_, ctx := policyTestContext("count-budget")
+	_, errMsg := handler.ExecuteCountWithAuthManager(ctx, "claude", "policy-sonnet", []byte(`{}`), "")
+	if errMsg == nil || errMsg.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("ExecuteCountWithAuthManager() = %+v, want 429 budget_exceeded", errMsg)
+	}
+}
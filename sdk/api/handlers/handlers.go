@@ -22,6 +22,8 @@ import (
 	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
 	"github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
 	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/net/context"
 )
 
@@ -50,6 +52,9 @@ const idempotencyKeyMetadataKey = "idempotency_key"
 const (
 	defaultStreamingKeepAliveSeconds = 0
 	defaultStreamingBootstrapRetries = 0
+	// defaultQueueKeepAliveInterval is used for queued streaming requests when
+	// streaming keep-alives are not configured.
+	defaultQueueKeepAliveInterval = 15 * time.Second
 )
 
 // BuildErrorResponseBody builds an OpenAI-compatible JSON error response body.
@@ -241,13 +246,20 @@ func (h *BaseAPIHandler) GetContextWithCancel(handler interfaces.APIHandler, c *
 			parentCtx = logging.WithRequestID(parentCtx, requestID)
 		}
 	}
+	if requestCtx != nil && coreauth.RequestStatsFromContext(parentCtx) == nil {
+		parentCtx = coreauth.WithRequestStats(parentCtx, coreauth.RequestStatsFromContext(requestCtx))
+	}
+	if requestCtx != nil && !trace.SpanContextFromContext(parentCtx).IsValid() {
+		parentCtx = trace.ContextWithSpan(parentCtx, trace.SpanFromContext(requestCtx))
+	}
 	newCtx, cancel := context.WithCancel(parentCtx)
 	if requestCtx != nil && requestCtx != parentCtx {
+		done := newCtx.Done()
 		go func() {
 			select {
 			case <-requestCtx.Done():
 				cancel()
-			case <-newCtx.Done():
+			case <-done:
 			}
 		}()
 	}
@@ -341,6 +353,96 @@ func (h *BaseAPIHandler) StartNonStreamingKeepAlive(c *gin.Context, ctx context.
 	}
 }
 
+// startQueueKeepAlive returns a context that, once the request starts waiting in the
+// cooldown queue, emits SSE comment heartbeats so clients and proxies keep the connection
+// open. Headers are only committed by the first heartbeat, so requests that leave the queue
+// sooner still get a regular error status; later errors are sent as SSE error events by
+// WriteErrorResponse. The returned stop function must be called before the handler writes
+// its response; it waits for the heartbeat goroutine, so the manager call and the response
+// writer are never used concurrently. Streams with a non-empty alt send raw JSON rather than
+// SSE, so they get no keep-alives.
+func (h *BaseAPIHandler) startQueueKeepAlive(ctx context.Context, alt string) (context.Context, func()) {
+	if alt != "" {
+		return ctx, func() {}
+	}
+	c, ok := ctx.Value("gin").(*gin.Context)
+	if !ok || c == nil {
+		return ctx, func() {}
+	}
+	flusher, ok := c.Writer.(http.Flusher)
+	if !ok {
+		return ctx, func() {}
+	}
+	interval := StreamingKeepAliveInterval(h.Cfg)
+	if interval <= 0 {
+		interval = defaultQueueKeepAliveInterval
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	started, stopped := false, false
+	stopChan := make(chan struct{})
+	notify := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if started || stopped {
+			return
+		}
+		started = true
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-stopChan:
+					return
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					if !c.Writer.Written() {
+						c.Header("Content-Type", "text/event-stream")
+						c.Header("Cache-Control", "no-cache")
+						c.Header("Connection", "keep-alive")
+						// The served model is only known after the queue, so it becomes a trailer.
+						c.Header("Trailer", coreauth.ServedModelHeader)
+					}
+					_, _ = c.Writer.Write([]byte(": queued\n\n"))
+					flusher.Flush()
+				}
+			}
+		}()
+	}
+	var stopOnce sync.Once
+	return coreauth.WithQueueNotifier(ctx, notify), func() {
+		stopOnce.Do(func() {
+			mu.Lock()
+			stopped = true
+			close(stopChan)
+			mu.Unlock()
+			wg.Wait()
+		})
+	}
+}
+
+// withServedModelHeader returns a context that captures the model the auth manager reports
+// as serving the request, and a function that sets it as the ServedModelHeader response
+// header, or as a trailer when queue keep-alives already committed the headers. The function
+// must be called once the manager call has returned and keep-alives have stopped.
+func withServedModelHeader(ctx context.Context) (context.Context, func()) {
+	c, ok := ctx.Value("gin").(*gin.Context)
+	if !ok || c == nil {
+		return ctx, func() {}
+	}
+	var served string
+	return coreauth.WithServedModelReporter(ctx, func(model string) { served = model }), func() {
+		if served != "" {
+			c.Header(coreauth.ServedModelHeader, served)
+		}
+	}
+}
+
 // appendAPIResponse preserves any previously captured API response and appends new data.
 func appendAPIResponse(c *gin.Context, data []byte) {
 	if c == nil || len(data) == 0 {
@@ -368,10 +470,33 @@ func appendAPIResponse(c *gin.Context, data []byte) {
 	c.Set("API_RESPONSE", bytes.Clone(data))
 }
 
+// annotateRequest records the inbound format and resolved model on the request's stats and
+// tracing span. model must be the name returned by getRequestDetails, which is empty unless
+// it resolved to a registered model, so raw client input never reaches metric labels.
+func annotateRequest(ctx context.Context, handlerType, model string, stream bool) {
+	coreauth.RequestStatsFromContext(ctx).SetRequest(handlerType, model, stream)
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.String("cliproxy.format", handlerType),
+		attribute.String("cliproxy.model", model),
+		attribute.Bool("cliproxy.stream", stream),
+		attribute.String("cliproxy.request_id", logging.GetRequestID(ctx)),
+	)
+}
+
 // ExecuteWithAuthManager executes a non-streaming request via the core auth manager.
 // This path is the only supported execution route.
 func (h *BaseAPIHandler) ExecuteWithAuthManager(ctx context.Context, handlerType, modelName string, rawJSON []byte, alt string) ([]byte, *interfaces.ErrorMessage) {
-	providers, normalizedModel, errMsg := h.getRequestDetails(modelName)
+	providers, normalizedModel, errMsg := h.getRequestDetails(ctx, modelName)
+	annotateRequest(ctx, handlerType, normalizedModel, false)
+	if errMsg == nil {
+		ctx, providers, errMsg = h.authorizeClientModel(ctx, handlerType, normalizedModel, providers)
+	}
+	if errMsg == nil {
+		ctx, errMsg = h.applyClientBudget(ctx, handlerType)
+	}
+	if errMsg == nil {
+		ctx, _, errMsg = h.applyClientRateLimits(ctx, handlerType, rawJSON, true, false)
+	}
 	if errMsg != nil {
 		return nil, errMsg
 	}
@@ -392,7 +517,9 @@ func (h *BaseAPIHandler) ExecuteWithAuthManager(ctx context.Context, handlerType
 		SourceFormat:    sdktranslator.FromString(handlerType),
 	}
 	opts.Metadata = reqMeta
-	resp, err := h.AuthManager.Execute(ctx, providers, req, opts)
+	execCtx, setServedModel := withServedModelHeader(ctx)
+	resp, err := h.AuthManager.Execute(execCtx, providers, req, opts)
+	setServedModel()
 	if err != nil {
 		status := http.StatusInternalServerError
 		if se, ok := err.(interface{ StatusCode() int }); ok && se != nil {
@@ -414,7 +541,19 @@ func (h *BaseAPIHandler) ExecuteWithAuthManager(ctx context.Context, handlerType
 // ExecuteCountWithAuthManager executes a non-streaming request via the core auth manager.
 // This path is the only supported execution route.
 func (h *BaseAPIHandler) ExecuteCountWithAuthManager(ctx context.Context, handlerType, modelName string, rawJSON []byte, alt string) ([]byte, *interfaces.ErrorMessage) {
-	providers, normalizedModel, errMsg := h.getRequestDetails(modelName)
+	providers, normalizedModel, errMsg := h.getRequestDetails(ctx, modelName)
+	annotateRequest(ctx, handlerType, normalizedModel, false)
+	if errMsg == nil {
+		ctx, providers, errMsg = h.authorizeClientModel(ctx, handlerType, normalizedModel, providers)
+	}
+	if errMsg == nil {
+		// Exhausted budgets block token counting too, but counting is not a request against
+		// max-requests, so the request marker is dropped.
+		_, errMsg = h.applyClientBudget(ctx, handlerType)
+	}
+	if errMsg == nil {
+		ctx, _, errMsg = h.applyClientRateLimits(ctx, handlerType, rawJSON, false, false)
+	}
 	if errMsg != nil {
 		return nil, errMsg
 	}
@@ -457,7 +596,18 @@ func (h *BaseAPIHandler) ExecuteCountWithAuthManager(ctx context.Context, handle
 // ExecuteStreamWithAuthManager executes a streaming request via the core auth manager.
 // This path is the only supported execution route.
 func (h *BaseAPIHandler) ExecuteStreamWithAuthManager(ctx context.Context, handlerType, modelName string, rawJSON []byte, alt string) (<-chan []byte, <-chan *interfaces.ErrorMessage) {
-	providers, normalizedModel, errMsg := h.getRequestDetails(modelName)
+	providers, normalizedModel, errMsg := h.getRequestDetails(ctx, modelName)
+	annotateRequest(ctx, handlerType, normalizedModel, true)
+	if errMsg == nil {
+		ctx, providers, errMsg = h.authorizeClientModel(ctx, handlerType, normalizedModel, providers)
+	}
+	if errMsg == nil {
+		ctx, errMsg = h.applyClientBudget(ctx, handlerType)
+	}
+	releaseStream := func() {}
+	if errMsg == nil {
+		ctx, releaseStream, errMsg = h.applyClientRateLimits(ctx, handlerType, rawJSON, true, true)
+	}
 	if errMsg != nil {
 		errChan := make(chan *interfaces.ErrorMessage, 1)
 		errChan <- errMsg
@@ -481,8 +631,13 @@ func (h *BaseAPIHandler) ExecuteStreamWithAuthManager(ctx context.Context, handl
 		SourceFormat:    sdktranslator.FromString(handlerType),
 	}
 	opts.Metadata = reqMeta
-	chunks, err := h.AuthManager.ExecuteStream(ctx, providers, req, opts)
+	queueCtx, stopQueueKeepAlive := h.startQueueKeepAlive(ctx, alt)
+	execCtx, setServedModel := withServedModelHeader(queueCtx)
+	chunks, err := h.AuthManager.ExecuteStream(execCtx, providers, req, opts)
+	stopQueueKeepAlive()
+	setServedModel()
 	if err != nil {
+		releaseStream()
 		errChan := make(chan *interfaces.ErrorMessage, 1)
 		status := http.StatusInternalServerError
 		if se, ok := err.(interface{ StatusCode() int }); ok && se != nil {
@@ -505,6 +660,7 @@ func (h *BaseAPIHandler) ExecuteStreamWithAuthManager(ctx context.Context, handl
 	go func() {
 		defer close(dataChan)
 		defer close(errChan)
+		defer releaseStream()
 		sentPayload := false
 		bootstrapRetries := 0
 		maxBootstrapRetries := StreamingBootstrapRetries(h.Cfg)
@@ -621,7 +777,7 @@ func statusFromError(err error) int {
 	return 0
 }
 
-func (h *BaseAPIHandler) getRequestDetails(modelName string) (providers []string, normalizedModel string, err *interfaces.ErrorMessage) {
+func (h *BaseAPIHandler) getRequestDetails(ctx context.Context, modelName string) (providers []string, normalizedModel string, err *interfaces.ErrorMessage) {
 	resolvedModelName := modelName
 	initialSuffix := thinking.ParseSuffix(modelName)
 	if initialSuffix.ModelName == "auto" {
@@ -638,6 +794,13 @@ func (h *BaseAPIHandler) getRequestDetails(modelName string) (providers []string
 	parsed := thinking.ParseSuffix(resolvedModelName)
 	baseModel := strings.TrimSpace(parsed.ModelName)
 
+	// Unprefixed requests from a client key bound to credential prefixes are routed to
+	// the first bound prefix whose credentials serve the model.
+	if bound := h.bindCredentialPrefix(h.clientPolicy(ctx), baseModel); bound != "" {
+		resolvedModelName = bound + "/" + resolvedModelName
+		baseModel = bound + "/" + baseModel
+	}
+
 	providers = util.GetProviderName(baseModel)
 	// Fallback: if baseModel has no provider but differs from resolvedModelName,
 	// try using the full model name. This handles edge cases where custom models
@@ -692,6 +855,16 @@ func (h *BaseAPIHandler) WriteErrorResponse(c *gin.Context, msg *interfaces.Erro
 	}
 
 	body := BuildErrorResponseBody(status, errText)
+	if c.Writer.Written() && strings.HasPrefix(c.Writer.Header().Get("Content-Type"), "text/event-stream") {
+		// Queue keep-alives already committed a 200 event stream, so the status can no
+		// longer change; report the error as an SSE error event instead.
+		appendAPIResponse(c, body)
+		_, _ = fmt.Fprintf(c.Writer, "event: error\ndata: %s\n\n", body)
+		if flusher, ok := c.Writer.(http.Flusher); ok {
+			flusher.Flush()
+		}
+		return
+	}
 	// Append first to preserve upstream response logs, then drop duplicate payloads if already recorded.
 	var previous []byte
 	if existing, exists := c.Get("API_RESPONSE"); exists {
@@ -2,9 +2,17 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"net/http/httptest"
+	"strings"
 	"sync"
 	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
 
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
 	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
@@ -252,3 +260,117 @@ func TestExecuteStreamWithAuthManager_DoesNotRetryAfterFirstByte(t *testing.T) {
 		t.Fatalf("expected 1 stream attempt, got %d", executor.Calls())
 	}
 }
+
+func TestWriteErrorResponse_AfterQueueKeepAliveUsesSSEEvent(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Header("Content-Type", "text/event-stream")
+	_, _ = c.Writer.Write([]byte(": queued\n\n"))
+
+	handler := NewBaseAPIHandlers(&sdkconfig.SDKConfig{}, nil)
+	handler.WriteErrorResponse(c, &interfaces.ErrorMessage{
+		StatusCode: http.StatusTooManyRequests,
+		Error:      errors.New("all credentials are cooling down"),
+	})
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected committed status %d, got %d", http.StatusOK, recorder.Code)
+	}
+	body := recorder.Body.String()
+	if !strings.Contains(body, "event: error\ndata: ") {
+		t.Fatalf("expected SSE error event, got %q", body)
+	}
+	if !strings.Contains(body, "all credentials are cooling down") {
+		t.Fatalf("expected error message in body, got %q", body)
+	}
+}
+
+type okStreamExecutor struct{}
+
+func (okStreamExecutor) Identifier() string { return "queue-stream" }
+
+func (okStreamExecutor) Execute(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{}, &coreauth.Error{Code: "not_implemented", Message: "Execute not implemented"}
+}
+
+func (okStreamExecutor) ExecuteStream(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (<-chan coreexecutor.StreamChunk, error) {
+	ch := make(chan coreexecutor.StreamChunk, 1)
+	ch <- coreexecutor.StreamChunk{Payload: []byte(`{"ok":true}`)}
+	close(ch)
+	return ch, nil
+}
+
+func (okStreamExecutor) Refresh(_ context.Context, auth *coreauth.Auth) (*coreauth.Auth, error) {
+	return auth, nil
+}
+
+func (okStreamExecutor) CountTokens(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{}, &coreauth.Error{Code: "not_implemented", Message: "CountTokens not implemented"}
+}
+
+func (okStreamExecutor) HttpRequest(context.Context, *coreauth.Auth, *http.Request) (*http.Response, error) {
+	return nil, &coreauth.Error{Code: "not_implemented", Message: "HttpRequest not implemented"}
+}
+
+func TestExecuteStreamWithAuthManager_QueueKeepAliveRespectsAlt(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for _, tc := range []struct {
+		alt       string
+		keepAlive bool
+	}{
+		{alt: "", keepAlive: true},
+		{alt: "json", keepAlive: false},
+	} {
+		manager := coreauth.NewManager(nil, nil, nil)
+		manager.SetConfig(&internalconfig.Config{CooldownQueue: internalconfig.CooldownQueueConfig{Enable: true, MaxWaitSeconds: 5}})
+		manager.RegisterExecutor(okStreamExecutor{})
+		next := time.Now().Add(1500 * time.Millisecond)
+		auth := &coreauth.Auth{
+			ID:       "queue-stream-auth",
+			Provider: "queue-stream",
+			Status:   coreauth.StatusActive,
+			ModelStates: map[string]*coreauth.ModelState{"queue-stream-model": {
+				Status:         coreauth.StatusError,
+				Unavailable:    true,
+				NextRetryAfter: next,
+				Quota:          coreauth.QuotaState{Exceeded: true, NextRecoverAt: next},
+			}},
+		}
+		if _, err := manager.Register(context.Background(), auth); err != nil {
+			t.Fatalf("manager.Register(): %v", err)
+		}
+		registry.GetGlobalRegistry().RegisterClient(auth.ID, auth.Provider, []*registry.ModelInfo{{ID: "queue-stream-model"}})
+
+		recorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(recorder)
+		ctx := context.WithValue(context.Background(), "gin", c)
+		handler := NewBaseAPIHandlers(&sdkconfig.SDKConfig{
+			Streaming: sdkconfig.StreamingConfig{KeepAliveSeconds: 1},
+		}, manager)
+		dataChan, errChan := handler.ExecuteStreamWithAuthManager(ctx, "gemini", "queue-stream-model", []byte(`{}`), tc.alt)
+		for range dataChan {
+		}
+		for msg := range errChan {
+			if msg != nil {
+				t.Fatalf("alt=%q: unexpected error: %+v", tc.alt, msg)
+			}
+		}
+		registry.GetGlobalRegistry().UnregisterClient(auth.ID)
+
+		gotKeepAlive := strings.Contains(recorder.Body.String(), ": queued")
+		if gotKeepAlive != tc.keepAlive {
+			t.Fatalf("alt=%q: queue keep-alive written = %v, want %v (body %q)", tc.alt, gotKeepAlive, tc.keepAlive, recorder.Body.String())
+		}
+		if !tc.keepAlive && strings.HasPrefix(recorder.Header().Get("Content-Type"), "text/event-stream") {
+			t.Fatalf("alt=%q: Content-Type = %q, want no event stream", tc.alt, recorder.Header().Get("Content-Type"))
+		}
+		served := c.Writer.Header().Get(coreauth.ServedModelHeader)
+		if tc.keepAlive {
+			served = recorder.Result().Trailer.Get(coreauth.ServedModelHeader)
+		}
+		if served != "queue-stream-model" {
+			t.Fatalf("alt=%q: served model = %q, want %q", tc.alt, served, "queue-stream-model")
+		}
+	}
+}
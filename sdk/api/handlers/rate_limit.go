@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+)
+
+// estimatedBytesPerToken approximates how many request bytes make up one prompt token.
+const estimatedBytesPerToken = 4
+
+// defaultClientLimiter enforces per-client-key limits for every handler and reconciles
+// token estimates from usage records.
+var defaultClientLimiter = newClientLimiter()
+
+func init() {
+	coreusage.RegisterPlugin(defaultClientLimiter)
+}
+
+// tokenBucket refills continuously up to capacity over one minute. Its balance may go
+// negative when reconciled usage exceeds the estimate, delaying later requests.
+type tokenBucket struct {
+	capacity  float64
+	available float64
+	updated   time.Time
+}
+
+// refill brings the bucket up to date at now, resizing it when the configured capacity changed.
+func (b *tokenBucket) refill(capacity float64, now time.Time) {
+	if b.capacity != capacity || b.updated.IsZero() {
+		if b.updated.IsZero() || b.available > capacity {
+			b.available = capacity
+		}
+		b.capacity = capacity
+		b.updated = now
+		return
+	}
+	elapsed := now.Sub(b.updated).Minutes()
+	if elapsed > 0 {
+		b.available = math.Min(b.capacity, b.available+elapsed*b.capacity)
+		b.updated = now
+	}
+}
+
+// wait returns how long until the bucket holds need units; zero when it already does.
+func (b *tokenBucket) wait(need float64) time.Duration {
+	need = math.Min(need, b.capacity)
+	if b.available >= need || b.capacity <= 0 {
+		return 0
+	}
+	return time.Duration((need - b.available) / b.capacity * float64(time.Minute))
+}
+
+type clientLimitState struct {
+	requests tokenBucket
+	tokens   tokenBucket
+	streams  int
+}
+
+// tokenReservation records the token estimate charged for a request so the first usage
+// record can replace it with the actual count.
+type tokenReservation struct {
+	key      string
+	estimate atomic.Int64
+}
+
+type tokenReservationContextKey struct{}
+
+// clientLimiter tracks request, token and concurrent-stream budgets per client API key.
+type clientLimiter struct {
+	mu     sync.Mutex
+	states map[string]*clientLimitState
+}
+
+func newClientLimiter() *clientLimiter {
+	return &clientLimiter{states: make(map[string]*clientLimitState)}
+}
+
+// admit charges one request and the estimated tokens to key and, for streams, takes a stream
+// slot. Nothing is charged when a limit is exceeded; the returned reason and wait describe it.
+func (l *clientLimiter) admit(key string, policy *config.APIKeyPolicy, estimate int64, stream bool, now time.Time) (release func(), reservation *tokenReservation, reason string, retryAfter time.Duration) {
+	release = func() {}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	state := l.states[key]
+	if state == nil {
+		state = &clientLimitState{}
+		l.states[key] = state
+	}
+	if policy.RequestsPerMinute > 0 {
+		state.requests.refill(float64(policy.RequestsPerMinute), now)
+		if wait := state.requests.wait(1); wait > 0 {
+			return release, nil, "requests per minute", wait
+		}
+	}
+	if policy.TokensPerMinute > 0 {
+		state.tokens.refill(float64(policy.TokensPerMinute), now)
+		if wait := state.tokens.wait(float64(estimate)); wait > 0 {
+			return release, nil, "tokens per minute", wait
+		}
+	}
+	if stream && policy.MaxConcurrentStreams > 0 && state.streams >= policy.MaxConcurrentStreams {
+		// Streams free up when they finish; suggest a short retry rather than a refill time.
+		return release, nil, "concurrent streams", time.Second
+	}
+
+	if policy.RequestsPerMinute > 0 {
+		state.requests.available--
+	}
+	if policy.TokensPerMinute > 0 {
+		state.tokens.available -= float64(estimate)
+		reservation = &tokenReservation{key: key}
+		reservation.estimate.Store(estimate)
+	}
+	if stream && policy.MaxConcurrentStreams > 0 {
+		state.streams++
+		var once sync.Once
+		release = func() {
+			once.Do(func() {
+				l.mu.Lock()
+				state.streams--
+				l.mu.Unlock()
+			})
+		}
+	}
+	return release, reservation, "", 0
+}
+
+// adjust charges delta additional tokens (negative to refund) to key's token bucket.
+func (l *clientLimiter) adjust(key string, delta int64) {
+	if delta == 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if state := l.states[key]; state != nil && state.tokens.capacity > 0 {
+		state.tokens.available = math.Min(state.tokens.capacity, state.tokens.available-float64(delta))
+	}
+}
+
+// HandleUsage reconciles the token estimate charged at admission with the reported usage.
+// The first record of a request replaces the estimate; later records (retries, hedges) are
+// charged in full.
+func (l *clientLimiter) HandleUsage(ctx context.Context, record coreusage.Record) {
+	if ctx == nil {
+		return
+	}
+	reservation, ok := ctx.Value(tokenReservationContextKey{}).(*tokenReservation)
+	if !ok || reservation == nil {
+		return
+	}
+	actual := record.Detail.TotalTokens
+	if actual == 0 {
+		actual = record.Detail.InputTokens + record.Detail.OutputTokens + record.Detail.ReasoningTokens
+	}
+	l.adjust(reservation.key, actual-reservation.estimate.Swap(0))
+}
+
+// applyClientRateLimits admits a request against the client API key's rate limits. It returns
+// a context carrying the token reservation, a release function for stream slots, and a 429
+// in the inbound format with Retry-After when a limit is exceeded.
+func (h *BaseAPIHandler) applyClientRateLimits(ctx context.Context, handlerType string, rawJSON []byte, countTokens, stream bool) (context.Context, func(), *interfaces.ErrorMessage) {
+	noop := func() {}
+	if h.Cfg == nil || len(h.Cfg.APIKeyPolicies) == 0 {
+		return ctx, noop, nil
+	}
+	key := clientAPIKey(ctx)
+	policy := h.Cfg.APIKeyPolicy(key)
+	if !policy.HasRateLimits() {
+		return ctx, noop, nil
+	}
+	var estimate int64
+	if countTokens {
+		estimate = int64(len(rawJSON)/estimatedBytesPerToken) + 1
+	}
+	release, reservation, reason, retryAfter := defaultClientLimiter.admit(key, policy, estimate, stream, time.Now())
+	if reason != "" {
+		seconds := int(math.Ceil(retryAfter.Seconds()))
+		addon := http.Header{}
+		addon.Set("Retry-After", strconv.Itoa(seconds))
+		return ctx, noop, newFormattedErrorMessage(handlerType, http.StatusTooManyRequests, "rate_limit_exceeded",
+			fmt.Sprintf("rate limit exceeded for this API key (%s); retry after %d seconds", reason, seconds), addon)
+	}
+	if reservation != nil {
+		ctx = context.WithValue(ctx, tokenReservationContextKey{}, reservation)
+	}
+	return ctx, release, nil
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+)
+
+func TestClientLimiterRequestsPerMinute(t *testing.T) {
+	limiter := newClientLimiter()
+	policy := &sdkconfig.APIKeyPolicy{APIKey: "k", RequestsPerMinute: 2}
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		if _, _, reason, _ := limiter.admit("k", policy, 0, false, now); reason != "" {
+			t.Fatalf("request %d rejected: %s", i, reason)
+		}
+	}
+	_, _, reason, wait := limiter.admit("k", policy, 0, false, now)
+	if reason == "" || wait != 30*time.Second {
+		t.Fatalf("third request: reason = %q, wait = %v, want rejection with 30s", reason, wait)
+	}
+	if _, _, reason, _ = limiter.admit("k", policy, 0, false, now.Add(30*time.Second)); reason != "" {
+		t.Fatalf("request after refill rejected: %s", reason)
+	}
+}
+
+func TestClientLimiterConcurrentStreams(t *testing.T) {
+	limiter := newClientLimiter()
+	policy := &sdkconfig.APIKeyPolicy{APIKey: "k", MaxConcurrentStreams: 1}
+	now := time.Now()
+
+	release, _, reason, _ := limiter.admit("k", policy, 0, true, now)
+	if reason != "" {
+		t.Fatalf("first stream rejected: %s", reason)
+	}
+	if _, _, reason, _ = limiter.admit("k", policy, 0, true, now); reason == "" {
+		t.Fatal("second concurrent stream admitted")
+	}
+	if _, _, reason, _ = limiter.admit("k", policy, 0, false, now); reason != "" {
+		t.Fatalf("non-streaming request rejected: %s", reason)
+	}
+	release()
+	release()
+	if _, _, reason, _ = limiter.admit("k", policy, 0, true, now); reason != "" {
+		t.Fatalf("stream after release rejected: %s", reason)
+	}
+}
+
+func TestClientLimiterReconcilesTokens(t *testing.T) {
+	limiter := newClientLimiter()
+	policy := &sdkconfig.APIKeyPolicy{APIKey: "k", TokensPerMinute: 1000}
+	now := time.Now()
+
+	_, reservation, reason, _ := limiter.admit("k", policy, 100, false, now)
+	if reason != "" || reservation == nil {
+		t.Fatalf("admit() reason = %q, reservation = %v", reason, reservation)
+	}
+	ctx := context.WithValue(context.Background(), tokenReservationContextKey{}, reservation)
+	limiter.HandleUsage(ctx, coreusage.Record{Detail: coreusage.Detail{InputTokens: 400, OutputTokens: 500}})
+
+	if got := limiter.states["k"].tokens.available; got != 100 {
+		t.Fatalf("available tokens = %v, want 100 after reconciling 900 actual tokens", got)
+	}
+	_, _, reason, wait := limiter.admit("k", policy, 400, false, now)
+	if reason == "" || wait != 18*time.Second {
+		t.Fatalf("admit() reason = %q, wait = %v, want rejection with 18s", reason, wait)
+	}
+}
+
+func TestApplyClientRateLimitsFormatsError(t *testing.T) {
+	handler := NewBaseAPIHandlers(&sdkconfig.SDKConfig{APIKeyPolicies: []sdkconfig.APIKeyPolicy{{
+		APIKey:            "limited-format-test",
+		RequestsPerMinute: 1,
+	}}}, nil)
+	_, ctx := policyTestContext("limited-format-test")
+
+	if _, _, errMsg := handler.applyClientRateLimits(ctx, "claude", nil, false, false); errMsg != nil {
+		t.Fatalf("first request rejected: %v", errMsg.Error)
+	}
+	_, _, errMsg := handler.applyClientRateLimits(ctx, "claude", nil, false, false)
+	if errMsg == nil || errMsg.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("second request = %+v, want 429", errMsg)
+	}
+	if got := errMsg.Addon.Get("Retry-After"); got != "60" {
+		t.Fatalf("Retry-After = %q, want 60", got)
+	}
+	if body := errMsg.Error.Error(); !strings.Contains(body, `"type":"rate_limit_error"`) {
+		t.Fatalf("body = %s, want a Claude rate_limit_error", body)
+	}
+}
@@ -159,6 +159,88 @@ func (s *FileTokenStore) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
+// fileStateName is the runtime state file kept in the auth directory. It has no .json
+// suffix so List and the auth watcher ignore it.
+const fileStateName = ".auth-state"
+
+// LoadState reads the runtime availability state saved by SaveState.
+func (s *FileTokenStore) LoadState(ctx context.Context) ([]byte, error) {
+	dir := s.baseDirSnapshot()
+	if dir == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(filepath.Join(dir, fileStateName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("auth filestore: read state failed: %w", err)
+	}
+	return data, nil
+}
+
+// SaveState atomically replaces the runtime availability state file.
+func (s *FileTokenStore) SaveState(ctx context.Context, data []byte) error {
+	dir := s.baseDirSnapshot()
+	if dir == "" {
+		return fmt.Errorf("auth filestore: directory not configured")
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("auth filestore: create dir failed: %w", err)
+	}
+	path := filepath.Join(dir, fileStateName)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("auth filestore: write state failed: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("auth filestore: replace state failed: %w", err)
+	}
+	return nil
+}
+
+// fileVirtualKeysName holds managed virtual API keys in the auth directory. Like the state
+// file it has no .json suffix so it is not mistaken for an auth record.
+const fileVirtualKeysName = ".virtual-keys"
+
+// LoadVirtualKeys reads the virtual key document saved by SaveVirtualKeys.
+func (s *FileTokenStore) LoadVirtualKeys(ctx context.Context) ([]byte, error) {
+	dir := s.baseDirSnapshot()
+	if dir == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(filepath.Join(dir, fileVirtualKeysName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("auth filestore: read virtual keys failed: %w", err)
+	}
+	return data, nil
+}
+
+// SaveVirtualKeys atomically replaces the virtual key document.
+func (s *FileTokenStore) SaveVirtualKeys(ctx context.Context, data []byte) error {
+	dir := s.baseDirSnapshot()
+	if dir == "" {
+		return fmt.Errorf("auth filestore: directory not configured")
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("auth filestore: create dir failed: %w", err)
+	}
+	path := filepath.Join(dir, fileVirtualKeysName)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("auth filestore: write virtual keys failed: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("auth filestore: replace virtual keys failed: %w", err)
+	}
+	return nil
+}
+
 func (s *FileTokenStore) resolveDeletePath(id string) (string, error) {
 	if strings.ContainsRune(id, os.PathSeparator) || filepath.IsAbs(id) {
 		return id, nil
@@ -0,0 +1,218 @@
+package auth
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+const (
+	// DefaultAdaptiveExploration is the share of requests routed to a random healthy auth.
+	DefaultAdaptiveExploration = 0.1
+
+	// adaptiveAlpha weights the newest sample in each moving average.
+	adaptiveAlpha = 0.3
+	// adaptiveFailureCostMs is the latency-equivalent cost charged per unit of error rate,
+	// so an auth that always fails ranks behind any reasonably fast healthy one.
+	adaptiveFailureCostMs = 30000.0
+	adaptiveMaxKeys       = 16384
+)
+
+// AdaptiveScore reports the learned statistics for one auth/model pair.
+type AdaptiveScore struct {
+	AuthID      string    `json:"auth_id"`
+	Model       string    `json:"model"`
+	FirstByteMs float64   `json:"first_byte_ms"`
+	LatencyMs   float64   `json:"latency_ms"`
+	ErrorRate   float64   `json:"error_rate"`
+	Score       float64   `json:"score"`
+	Samples     int64     `json:"samples"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type adaptiveStats struct {
+	authID      string
+	model       string
+	firstByteMs float64
+	latencyMs   float64
+	errorRate   float64
+	samples     int64
+	updatedAt   time.Time
+}
+
+// score returns the routing cost of the stats; lower is better. Auths that only served
+// non-streaming requests have no first-byte samples and are scored on total latency.
+func (s *adaptiveStats) score() float64 {
+	firstByteMs := s.firstByteMs
+	if firstByteMs == 0 {
+		firstByteMs = s.latencyMs
+	}
+	return (firstByteMs+s.latencyMs)/2 + s.errorRate*adaptiveFailureCostMs
+}
+
+// AdaptiveSelector prefers fast, healthy credentials using exponentially weighted moving
+// averages of time-to-first-byte, total latency and error rate per auth/model pair.
+// Auths without samples are tried first, and a configurable share of requests is routed
+// to a random available auth so scores keep adapting.
+type AdaptiveSelector struct {
+	exploration float64
+	random      func() float64
+	randomIndex func(n int) int
+
+	mu    sync.RWMutex
+	stats map[string]*adaptiveStats
+}
+
+// NewAdaptiveSelector constructs an adaptive selector.
+// Zero exploration uses DefaultAdaptiveExploration, a negative value disables exploration,
+// and values above 1 are clamped.
+func NewAdaptiveSelector(exploration float64) *AdaptiveSelector {
+	switch {
+	case exploration == 0:
+		exploration = DefaultAdaptiveExploration
+	case exploration < 0:
+		exploration = 0
+	case exploration > 1:
+		exploration = 1
+	}
+	return &AdaptiveSelector{
+		exploration: exploration,
+		random:      rand.Float64,
+		randomIndex: rand.IntN,
+		stats:       make(map[string]*adaptiveStats),
+	}
+}
+
+func adaptiveKey(authID, model string) string {
+	return authID + "|" + canonicalModelKey(model)
+}
+
+// Pick selects the available auth with the lowest score, occasionally exploring others.
+func (s *AdaptiveSelector) Pick(ctx context.Context, provider, model string, opts cliproxyexecutor.Options, auths []*Auth) (*Auth, error) {
+	_ = ctx
+	_ = opts
+	available, err := getAvailableAuths(auths, provider, model, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if len(available) == 1 {
+		return available[0], nil
+	}
+	if s.exploration > 0 && s.random() < s.exploration {
+		return available[s.randomIndex(len(available))], nil
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var best *Auth
+	bestScore := 0.0
+	for _, candidate := range available {
+		stats, ok := s.stats[adaptiveKey(candidate.ID, model)]
+		if !ok || stats.samples == 0 {
+			return candidate, nil
+		}
+		if score := stats.score(); best == nil || score < bestScore {
+			best = candidate
+			bestScore = score
+		}
+	}
+	return best, nil
+}
+
+// ObserveResult folds an execution result into the auth/model moving averages.
+// Client-side request errors are ignored because they say nothing about the auth.
+func (s *AdaptiveSelector) ObserveResult(result Result) {
+	if result.AuthID == "" || result.Model == "" {
+		return
+	}
+	if !result.Success && result.Error != nil && result.Error.HTTPStatus == http.StatusBadRequest {
+		return
+	}
+	errorSample := 0.0
+	if !result.Success {
+		errorSample = 1
+	}
+	latencyMs := float64(result.Latency) / float64(time.Millisecond)
+	firstByteMs := float64(result.FirstByteLatency) / float64(time.Millisecond)
+
+	key := adaptiveKey(result.AuthID, result.Model)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats, ok := s.stats[key]
+	if !ok {
+		if len(s.stats) >= adaptiveMaxKeys {
+			s.stats = make(map[string]*adaptiveStats)
+		}
+		stats = &adaptiveStats{authID: result.AuthID, model: canonicalModelKey(result.Model), errorRate: errorSample}
+		s.stats[key] = stats
+	} else {
+		stats.errorRate = ewma(stats.errorRate, errorSample)
+	}
+	// Failed calls often return fast; keep them out of the latency averages.
+	// Only streams report a first byte, so both averages are seeded independently.
+	if result.Success && latencyMs > 0 {
+		if stats.latencyMs == 0 {
+			stats.latencyMs = latencyMs
+		} else {
+			stats.latencyMs = ewma(stats.latencyMs, latencyMs)
+		}
+		if firstByteMs > 0 {
+			if stats.firstByteMs == 0 {
+				stats.firstByteMs = firstByteMs
+			} else {
+				stats.firstByteMs = ewma(stats.firstByteMs, firstByteMs)
+			}
+		}
+	}
+	stats.samples++
+	stats.updatedAt = time.Now()
+}
+
+func ewma(previous, sample float64) float64 {
+	return adaptiveAlpha*sample + (1-adaptiveAlpha)*previous
+}
+
+// Scores returns a snapshot of the learned statistics sorted by auth and model.
+func (s *AdaptiveSelector) Scores() []AdaptiveScore {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]AdaptiveScore, 0, len(s.stats))
+	for _, stats := range s.stats {
+		out = append(out, AdaptiveScore{
+			AuthID:      stats.authID,
+			Model:       stats.model,
+			FirstByteMs: stats.firstByteMs,
+			LatencyMs:   stats.latencyMs,
+			ErrorRate:   stats.errorRate,
+			Score:       stats.score(),
+			Samples:     stats.samples,
+			UpdatedAt:   stats.updatedAt,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].AuthID != out[j].AuthID {
+			return out[i].AuthID < out[j].AuthID
+		}
+		return out[i].Model < out[j].Model
+	})
+	return out
+}
+
+// AdaptiveScores returns the adaptive selector statistics when that strategy is active.
+func (m *Manager) AdaptiveScores() ([]AdaptiveScore, bool) {
+	if m == nil {
+		return nil, false
+	}
+	m.mu.RLock()
+	selector, ok := m.selector.(*AdaptiveSelector)
+	m.mu.RUnlock()
+	if !ok || selector == nil {
+		return nil, false
+	}
+	return selector.Scores(), true
+}
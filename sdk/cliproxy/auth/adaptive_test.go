@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+func TestAdaptiveSelectorPick_PrefersFastHealthyAuth(t *testing.T) {
+	t.Parallel()
+
+	selector := NewAdaptiveSelector(-1)
+	model := "gpt-5"
+	auths := []*Auth{{ID: "slow"}, {ID: "fast"}, {ID: "flaky"}}
+	for i := 0; i < 5; i++ {
+		selector.ObserveResult(Result{AuthID: "slow", Model: model, Success: true, Latency: 4 * time.Second, FirstByteLatency: 2 * time.Second})
+		selector.ObserveResult(Result{AuthID: "fast", Model: model, Success: true, Latency: time.Second, FirstByteLatency: 200 * time.Millisecond})
+		selector.ObserveResult(Result{AuthID: "flaky", Model: model, Success: false, Latency: 100 * time.Millisecond, Error: &Error{HTTPStatus: 500}})
+	}
+
+	got, err := selector.Pick(context.Background(), "mixed", model, cliproxyexecutor.Options{}, auths)
+	if err != nil {
+		t.Fatalf("Pick() error = %v", err)
+	}
+	if got.ID != "fast" {
+		t.Fatalf("Pick() auth.ID = %q, want %q", got.ID, "fast")
+	}
+}
+
+func TestAdaptiveSelectorPick_TriesUnscoredAuthFirst(t *testing.T) {
+	t.Parallel()
+
+	selector := NewAdaptiveSelector(-1)
+	model := "gpt-5"
+	selector.ObserveResult(Result{AuthID: "a", Model: model, Success: true, Latency: time.Millisecond})
+
+	got, err := selector.Pick(context.Background(), "mixed", model, cliproxyexecutor.Options{}, []*Auth{{ID: "a"}, {ID: "b"}})
+	if err != nil {
+		t.Fatalf("Pick() error = %v", err)
+	}
+	if got.ID != "b" {
+		t.Fatalf("Pick() auth.ID = %q, want unscored %q", got.ID, "b")
+	}
+}
+
+func TestAdaptiveSelectorObserveResult_FirstByteOnlyFromStreams(t *testing.T) {
+	t.Parallel()
+
+	selector := NewAdaptiveSelector(-1)
+	model := "gpt-5"
+	selector.ObserveResult(Result{AuthID: "a", Model: model, Success: true, Latency: time.Second})
+	// Token counts carry no latency and must not move the averages.
+	selector.ObserveResult(Result{AuthID: "a", Model: model, Success: true})
+
+	scores := selector.Scores()
+	if len(scores) != 1 || scores[0].FirstByteMs != 0 || scores[0].LatencyMs != 1000 || scores[0].Score != 1000 {
+		t.Fatalf("Scores() = %+v, want no first byte, 1000ms latency and score", scores)
+	}
+
+	selector.ObserveResult(Result{AuthID: "a", Model: model, Success: true, Latency: time.Second, FirstByteLatency: 200 * time.Millisecond})
+	if scores = selector.Scores(); scores[0].FirstByteMs != 200 {
+		t.Fatalf("FirstByteMs = %v, want 200", scores[0].FirstByteMs)
+	}
+}
+
+func TestAdaptiveSelectorPick_Explores(t *testing.T) {
+	t.Parallel()
+
+	selector := NewAdaptiveSelector(0.5)
+	selector.random = func() float64 { return 0.1 }
+	selector.randomIndex = func(int) int { return 1 }
+	model := "gpt-5"
+	selector.ObserveResult(Result{AuthID: "a", Model: model, Success: true, Latency: time.Millisecond})
+	selector.ObserveResult(Result{AuthID: "b", Model: model, Success: true, Latency: time.Second})
+
+	got, err := selector.Pick(context.Background(), "mixed", model, cliproxyexecutor.Options{}, []*Auth{{ID: "a"}, {ID: "b"}})
+	if err != nil {
+		t.Fatalf("Pick() error = %v", err)
+	}
+	if got.ID != "b" {
+		t.Fatalf("Pick() auth.ID = %q, want explored %q", got.ID, "b")
+	}
+}
+
+func TestManagerMarkResult_FeedsAdaptiveSelector(t *testing.T) {
+	selector := NewAdaptiveSelector(-1)
+	m := NewManager(nil, selector, nil)
+	if _, err := m.Register(context.Background(), &Auth{ID: "auth-1", Provider: "codex"}); err != nil {
+		t.Fatalf("register auth: %v", err)
+	}
+
+	m.MarkResult(context.Background(), Result{AuthID: "auth-1", Provider: "codex", Model: "gpt-5", Success: true, Latency: 300 * time.Millisecond})
+
+	scores, ok := m.AdaptiveScores()
+	if !ok {
+		t.Fatal("AdaptiveScores() ok = false, want true")
+	}
+	if len(scores) != 1 || scores[0].AuthID != "auth-1" || scores[0].Samples != 1 {
+		t.Fatalf("AdaptiveScores() = %+v, want one sample for auth-1", scores)
+	}
+	if scores[0].LatencyMs != 300 {
+		t.Fatalf("AdaptiveScores()[0].LatencyMs = %v, want 300", scores[0].LatencyMs)
+	}
+}
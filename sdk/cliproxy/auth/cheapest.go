@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/thinking"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+// CheapestSelector prefers the available auth whose provider serves the requested model at
+// the lowest effective cost according to the configured pricing table. Auths without a
+// matching price rank behind priced ones, and ties are broken round-robin.
+type CheapestSelector struct {
+	// pricing holds only the pricing table so lookups share Config.LookupModelPrice.
+	pricing    *internalconfig.Config
+	roundRobin RoundRobinSelector
+}
+
+// NewCheapestSelector constructs a cost-aware selector over the given pricing table.
+func NewCheapestSelector(pricing []internalconfig.ModelPrice) *CheapestSelector {
+	return &CheapestSelector{pricing: &internalconfig.Config{Pricing: append([]internalconfig.ModelPrice(nil), pricing...)}}
+}
+
+// Pick selects the cheapest available auth, rotating among equally priced ones.
+func (s *CheapestSelector) Pick(ctx context.Context, provider, model string, opts cliproxyexecutor.Options, auths []*Auth) (*Auth, error) {
+	available, err := getAvailableAuths(auths, provider, model, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if len(available) == 1 {
+		return available[0], nil
+	}
+	name := strings.ToLower(strings.TrimSpace(thinking.ParseSuffix(model).ModelName))
+	var cheapest []*Auth
+	bestCost := 0.0
+	for _, candidate := range available {
+		price, ok := s.pricing.LookupModelPrice(candidate.Provider, name)
+		if !ok {
+			continue
+		}
+		cost := price.EffectiveCost()
+		switch {
+		case cheapest == nil || cost < bestCost:
+			cheapest = []*Auth{candidate}
+			bestCost = cost
+		case cost == bestCost:
+			cheapest = append(cheapest, candidate)
+		}
+	}
+	if len(cheapest) == 0 {
+		cheapest = available
+	}
+	return s.roundRobin.Pick(ctx, provider, model, opts, cheapest)
+}
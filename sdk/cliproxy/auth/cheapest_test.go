@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+func TestCheapestSelectorPick_PrefersLowestEffectiveCost(t *testing.T) {
+	t.Parallel()
+
+	selector := NewCheapestSelector([]internalconfig.ModelPrice{
+		{Provider: "claude", Model: "claude-sonnet-*", Input: 3, Output: 15},
+		{Provider: "reseller", Model: "claude-sonnet-4-5", Input: 2, Output: 10},
+		{Provider: "antigravity", Model: "*", Input: 1, Output: 20},
+	})
+	auths := []*Auth{
+		{ID: "claude-key", Provider: "claude"},
+		{ID: "reseller-key", Provider: "reseller"},
+		{ID: "antigravity-oauth", Provider: "antigravity"},
+		{ID: "unpriced", Provider: "codex"},
+	}
+
+	got, err := selector.Pick(context.Background(), "mixed", "claude-sonnet-4-5(high)", cliproxyexecutor.Options{}, auths)
+	if err != nil {
+		t.Fatalf("Pick() error = %v", err)
+	}
+	if got.ID != "reseller-key" {
+		t.Fatalf("Pick() auth.ID = %q, want reseller-key", got.ID)
+	}
+}
+
+func TestCheapestSelectorPick_SkipsCoolingAuthAndRotatesTies(t *testing.T) {
+	t.Parallel()
+
+	selector := NewCheapestSelector([]internalconfig.ModelPrice{
+		{Provider: "cheap", Model: "m", Input: 1, Output: 1},
+		{Provider: "pricey", Model: "m", Input: 5, Output: 5},
+	})
+	cooling := &Auth{ID: "cheap-cooling", Provider: "cheap", ModelStates: map[string]*ModelState{"m": {
+		Status: StatusError, Unavailable: true, NextRetryAfter: time.Now().Add(time.Hour),
+	}}}
+	auths := []*Auth{cooling, {ID: "cheap-a", Provider: "cheap"}, {ID: "cheap-b", Provider: "cheap"}, {ID: "pricey", Provider: "pricey"}}
+
+	seen := map[string]int{}
+	for i := 0; i < 4; i++ {
+		got, err := selector.Pick(context.Background(), "mixed", "m", cliproxyexecutor.Options{}, auths)
+		if err != nil {
+			t.Fatalf("Pick() error = %v", err)
+		}
+		seen[got.ID]++
+	}
+	if seen["cheap-a"] != 2 || seen["cheap-b"] != 2 {
+		t.Fatalf("Pick() distribution = %v, want cheap-a and cheap-b twice each", seen)
+	}
+}
+
+func TestCheapestSelectorPick_FallsBackWithoutPricing(t *testing.T) {
+	t.Parallel()
+
+	selector := NewCheapestSelector(nil)
+	got, err := selector.Pick(context.Background(), "mixed", "m", cliproxyexecutor.Options{}, []*Auth{{ID: "a"}, {ID: "b"}})
+	if err != nil {
+		t.Fatalf("Pick() error = %v", err)
+	}
+	if got == nil {
+		t.Fatal("Pick() returned no auth")
+	}
+}
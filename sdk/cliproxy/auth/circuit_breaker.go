@@ -0,0 +1,413 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+)
+
+// CircuitState is the state of a circuit breaker.
+type CircuitState string
+
+const (
+	// CircuitClosed lets traffic through while counting consecutive failures.
+	CircuitClosed CircuitState = "closed"
+	// CircuitOpen rejects traffic until the open period elapses.
+	CircuitOpen CircuitState = "open"
+	// CircuitHalfOpen admits a single probe request to decide whether to close again.
+	CircuitHalfOpen CircuitState = "half-open"
+)
+
+const (
+	// CircuitScopeAuth identifies a circuit guarding a single credential.
+	CircuitScopeAuth = "auth"
+	// CircuitScopeProvider identifies a circuit shared by every credential targeting the same base URL.
+	CircuitScopeProvider = "provider"
+
+	defaultCircuitFailureThreshold         = 5
+	defaultCircuitProviderFailureThreshold = 10
+	defaultCircuitOpenDuration             = 30 * time.Second
+
+	circuitOpenCode      = "circuit_open"
+	circuitEventCapacity = 100
+)
+
+// CircuitSnapshot reports the state of one circuit.
+type CircuitSnapshot struct {
+	Scope               string       `json:"scope"`
+	Key                 string       `json:"key"`
+	Provider            string       `json:"provider,omitempty"`
+	State               CircuitState `json:"state"`
+	ConsecutiveFailures int          `json:"consecutive_failures"`
+	OpenedAt            time.Time    `json:"opened_at,omitempty"`
+	RetryAt             time.Time    `json:"retry_at,omitempty"`
+	LastError           string       `json:"last_error,omitempty"`
+}
+
+type circuit struct {
+	scope     string
+	key       string
+	provider  string
+	state     CircuitState
+	failures  int
+	openedAt  time.Time
+	retryAt   time.Time
+	probing   bool
+	probeAt   time.Time
+	probeAuth string
+	lastError string
+}
+
+// circuitOutcome is the verdict an attempt gives on the upstream behind its circuits.
+type circuitOutcome int
+
+const (
+	// circuitNeutral says nothing about the upstream (client errors, quota, cancellation);
+	// it only frees a half-open probe slot held by the attempt.
+	circuitNeutral circuitOutcome = iota
+	circuitSuccess
+	circuitFailure
+)
+
+type circuitSettings struct {
+	authThreshold     int
+	providerThreshold int
+	openFor           time.Duration
+}
+
+// circuitBreakers tracks per-auth and per-upstream circuits plus a short history of transitions.
+type circuitBreakers struct {
+	mu       sync.Mutex
+	circuits map[string]*circuit
+	events   []Event
+}
+
+func newCircuitBreakers() *circuitBreakers {
+	return &circuitBreakers{circuits: make(map[string]*circuit)}
+}
+
+// providerCircuitKey groups auths by provider and upstream base URL.
+func providerCircuitKey(auth *Auth) string {
+	provider := strings.ToLower(strings.TrimSpace(auth.Provider))
+	if auth.Attributes != nil {
+		if base := strings.TrimRight(strings.TrimSpace(auth.Attributes["base_url"]), "/"); base != "" {
+			return provider + "|" + base
+		}
+	}
+	return provider
+}
+
+func circuitMapKey(scope, key string) string {
+	return scope + ":" + key
+}
+
+// circuitsFor returns the existing auth and provider circuits for auth; missing ones are nil.
+func (b *circuitBreakers) circuitsFor(auth *Auth) [2]*circuit {
+	return [2]*circuit{
+		b.circuits[circuitMapKey(CircuitScopeAuth, auth.ID)],
+		b.circuits[circuitMapKey(CircuitScopeProvider, providerCircuitKey(auth))],
+	}
+}
+
+// admits reports whether c lets a request through at now without changing state.
+func (c *circuit) admits(now time.Time, openFor time.Duration) bool {
+	if c == nil {
+		return true
+	}
+	switch c.state {
+	case CircuitOpen:
+		return !now.Before(c.retryAt)
+	case CircuitHalfOpen:
+		// A probe that never reported back is considered lost after one open period.
+		return !c.probing || now.Sub(c.probeAt) >= openFor
+	default:
+		return true
+	}
+}
+
+// available reports whether both circuits guarding auth admit traffic.
+func (b *circuitBreakers) available(auth *Auth, now time.Time, settings circuitSettings) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, c := range b.circuitsFor(auth) {
+		if !c.admits(now, settings.openFor) {
+			return false
+		}
+	}
+	return true
+}
+
+// acquire admits a request for auth, turning elapsed open circuits half-open and
+// claiming their single probe slot.
+func (b *circuitBreakers) acquire(auth *Auth, now time.Time, settings circuitSettings) (bool, []Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	circuits := b.circuitsFor(auth)
+	for _, c := range circuits {
+		if !c.admits(now, settings.openFor) {
+			return false, nil
+		}
+	}
+	var events []Event
+	for _, c := range circuits {
+		if c == nil {
+			continue
+		}
+		switch c.state {
+		case CircuitOpen:
+			c.state = CircuitHalfOpen
+			c.probing = true
+			c.probeAt = now
+			c.probeAuth = auth.ID
+			events = append(events, b.recordEventLocked(c, EventCircuitHalfOpen, auth.ID, now, "admitting probe request"))
+		case CircuitHalfOpen:
+			c.probing = true
+			c.probeAt = now
+			c.probeAuth = auth.ID
+		}
+	}
+	return true, events
+}
+
+// record folds a request outcome into the circuits guarding auth. Only a success closes a
+// circuit; a neutral outcome leaves failure counts alone and frees the probe slot auth holds.
+func (b *circuitBreakers) record(auth *Auth, outcome circuitOutcome, message string, now time.Time, settings circuitSettings) []Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var events []Event
+	scopes := [2]struct {
+		scope     string
+		key       string
+		threshold int
+	}{
+		{CircuitScopeAuth, auth.ID, settings.authThreshold},
+		{CircuitScopeProvider, providerCircuitKey(auth), settings.providerThreshold},
+	}
+	for _, scope := range scopes {
+		mapKey := circuitMapKey(scope.scope, scope.key)
+		c := b.circuits[mapKey]
+		if outcome == circuitNeutral {
+			if c != nil && c.state == CircuitHalfOpen && c.probing && c.probeAuth == auth.ID {
+				c.probing = false
+				c.probeAuth = ""
+			}
+			continue
+		}
+		if outcome == circuitSuccess {
+			if c == nil {
+				continue
+			}
+			if c.state != CircuitClosed {
+				events = append(events, b.recordEventLocked(c, EventCircuitClosed, auth.ID, now, "request succeeded"))
+			}
+			delete(b.circuits, mapKey)
+			continue
+		}
+		if c == nil {
+			c = &circuit{scope: scope.scope, key: scope.key, provider: auth.Provider, state: CircuitClosed}
+			b.circuits[mapKey] = c
+		}
+		c.failures++
+		c.lastError = message
+		switch c.state {
+		case CircuitHalfOpen:
+			c.open(now, settings.openFor)
+			events = append(events, b.recordEventLocked(c, EventCircuitOpened, auth.ID, now, "probe request failed"))
+		case CircuitClosed:
+			if c.failures >= scope.threshold {
+				c.open(now, settings.openFor)
+				events = append(events, b.recordEventLocked(c, EventCircuitOpened, auth.ID, now, "consecutive failure threshold reached"))
+			}
+		}
+	}
+	return events
+}
+
+func (c *circuit) open(now time.Time, openFor time.Duration) {
+	c.state = CircuitOpen
+	c.openedAt = now
+	c.retryAt = now.Add(openFor)
+	c.probing = false
+	c.probeAt = time.Time{}
+	c.probeAuth = ""
+}
+
+func (b *circuitBreakers) recordEventLocked(c *circuit, eventType EventType, authID string, now time.Time, message string) Event {
+	event := Event{
+		Type:      eventType,
+		Time:      now,
+		AuthID:    authID,
+		Provider:  c.provider,
+		Scope:     c.scope,
+		Key:       c.key,
+		Message:   message,
+		Failures:  c.failures,
+		LastError: c.lastError,
+	}
+	if c.state == CircuitOpen {
+		event.RetryAt = c.retryAt
+	}
+	if len(b.events) >= circuitEventCapacity {
+		copy(b.events, b.events[1:])
+		b.events = b.events[:len(b.events)-1]
+	}
+	b.events = append(b.events, event)
+	return event
+}
+
+func (b *circuitBreakers) snapshot() []CircuitSnapshot {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	out := make([]CircuitSnapshot, 0, len(b.circuits))
+	for _, c := range b.circuits {
+		out = append(out, CircuitSnapshot{
+			Scope:               c.scope,
+			Key:                 c.key,
+			Provider:            c.provider,
+			State:               c.state,
+			ConsecutiveFailures: c.failures,
+			OpenedAt:            c.openedAt,
+			RetryAt:             c.retryAt,
+			LastError:           c.lastError,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Scope != out[j].Scope {
+			return out[i].Scope < out[j].Scope
+		}
+		return out[i].Key < out[j].Key
+	})
+	return out
+}
+
+func (b *circuitBreakers) history() []Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	out := make([]Event, len(b.events))
+	copy(out, b.events)
+	return out
+}
+
+// circuitSettings returns the effective circuit breaker settings and whether breaking is enabled.
+func (m *Manager) circuitSettings() (circuitSettings, bool) {
+	cfg, _ := m.runtimeConfig.Load().(*internalconfig.Config)
+	if cfg == nil || !cfg.CircuitBreaker.Enable {
+		return circuitSettings{}, false
+	}
+	settings := circuitSettings{
+		authThreshold:     cfg.CircuitBreaker.FailureThreshold,
+		providerThreshold: cfg.CircuitBreaker.ProviderFailureThreshold,
+		openFor:           time.Duration(cfg.CircuitBreaker.OpenSeconds) * time.Second,
+	}
+	if settings.authThreshold <= 0 {
+		settings.authThreshold = defaultCircuitFailureThreshold
+	}
+	if settings.providerThreshold <= 0 {
+		settings.providerThreshold = defaultCircuitProviderFailureThreshold
+	}
+	if settings.openFor <= 0 {
+		settings.openFor = defaultCircuitOpenDuration
+	}
+	return settings, true
+}
+
+// circuitAvailable reports whether the circuits guarding auth currently admit traffic.
+func (m *Manager) circuitAvailable(auth *Auth, now time.Time) bool {
+	settings, enabled := m.circuitSettings()
+	if !enabled {
+		return true
+	}
+	return m.breakers.available(auth, now, settings)
+}
+
+// acquireCircuit admits a request for auth through its circuits.
+func (m *Manager) acquireCircuit(auth *Auth, now time.Time) (bool, []Event) {
+	settings, enabled := m.circuitSettings()
+	if !enabled {
+		return true, nil
+	}
+	return m.breakers.acquire(auth, now, settings)
+}
+
+// recordCircuitResult updates the circuits guarding auth with the request outcome.
+// Only upstream failures (5xx, timeouts and network errors) count towards opening a circuit
+// and only a success closes one. Client errors, quota errors and cancellations say nothing
+// about the upstream and just free the probe slot.
+func (m *Manager) recordCircuitResult(ctx context.Context, auth *Auth, result Result, now time.Time) []Event {
+	settings, enabled := m.circuitSettings()
+	if !enabled || auth == nil {
+		return nil
+	}
+	outcome := circuitSuccess
+	message := ""
+	if !result.Success {
+		outcome = circuitNeutral
+		if result.Error != nil && !attemptCancelled(ctx, result.Error) {
+			status := statusCodeFromResult(result.Error)
+			if status == 0 || status == http.StatusRequestTimeout || status >= http.StatusInternalServerError {
+				outcome = circuitFailure
+			}
+			message = result.Error.Message
+		}
+	}
+	return m.breakers.record(auth, outcome, message, now, settings)
+}
+
+// releaseCircuitProbe frees the half-open probe slot held by an attempt on auth that ended
+// without recording a result, such as a cancelled request or a losing hedge.
+func (m *Manager) releaseCircuitProbe(auth *Auth) {
+	settings, enabled := m.circuitSettings()
+	if !enabled || auth == nil {
+		return
+	}
+	m.breakers.record(auth, circuitNeutral, "", time.Now(), settings)
+}
+
+// attemptCancelled reports whether an attempt failed because its request was cancelled
+// rather than because of the upstream.
+func attemptCancelled(ctx context.Context, err *Error) bool {
+	if ctx != nil && errors.Is(ctx.Err(), context.Canceled) {
+		return true
+	}
+	return err != nil && err.HTTPStatus == 0 && strings.Contains(err.Message, context.Canceled.Error())
+}
+
+func newCircuitOpenError() *Error {
+	return &Error{
+		Code:       circuitOpenCode,
+		Message:    "circuit breaker is open for all matching credentials",
+		Retryable:  true,
+		HTTPStatus: http.StatusServiceUnavailable,
+	}
+}
+
+// CircuitBreakerEnabled reports whether circuit breaking is configured on.
+func (m *Manager) CircuitBreakerEnabled() bool {
+	if m == nil {
+		return false
+	}
+	_, enabled := m.circuitSettings()
+	return enabled
+}
+
+// CircuitStates returns a snapshot of every circuit that is tracking failures or not closed.
+func (m *Manager) CircuitStates() []CircuitSnapshot {
+	if m == nil || m.breakers == nil {
+		return nil
+	}
+	return m.breakers.snapshot()
+}
+
+// CircuitEvents returns the most recent circuit state transitions, oldest first.
+func (m *Manager) CircuitEvents() []Event {
+	if m == nil || m.breakers == nil {
+		return nil
+	}
+	return m.breakers.history()
+}
@@ -0,0 +1,204 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+func TestCircuitBreakers_OpensHalfOpensAndCloses(t *testing.T) {
+	t.Parallel()
+
+	breakers := newCircuitBreakers()
+	settings := circuitSettings{authThreshold: 2, providerThreshold: 10, openFor: 30 * time.Second}
+	auth := &Auth{ID: "cb-auth", Provider: "claude", Attributes: map[string]string{"base_url": "https://api.example.com/"}}
+	now := time.Now()
+
+	if events := breakers.record(auth, circuitFailure, "boom", now, settings); len(events) != 0 {
+		t.Fatalf("record() events = %v, want none below threshold", events)
+	}
+	events := breakers.record(auth, circuitFailure, "boom", now, settings)
+	if len(events) != 1 || events[0].Type != EventCircuitOpened || events[0].Scope != CircuitScopeAuth {
+		t.Fatalf("record() events = %v, want auth circuit opened", events)
+	}
+	if breakers.available(auth, now.Add(time.Second), settings) {
+		t.Fatal("available() = true while circuit is open")
+	}
+
+	probeAt := now.Add(31 * time.Second)
+	admitted, events := breakers.acquire(auth, probeAt, settings)
+	if !admitted || len(events) != 1 || events[0].Type != EventCircuitHalfOpen {
+		t.Fatalf("acquire() = %v, %v, want probe admitted with half-open event", admitted, events)
+	}
+	if admitted, _ = breakers.acquire(auth, probeAt, settings); admitted {
+		t.Fatal("acquire() admitted a second request while the probe is in flight")
+	}
+
+	events = breakers.record(auth, circuitSuccess, "", probeAt, settings)
+	if len(events) != 1 || events[0].Type != EventCircuitClosed {
+		t.Fatalf("record() events = %v, want circuit closed", events)
+	}
+	if states := breakers.snapshot(); len(states) != 0 {
+		t.Fatalf("snapshot() = %v, want no tracked circuits after recovery", states)
+	}
+	if got := len(breakers.history()); got != 3 {
+		t.Fatalf("history() len = %d, want 3", got)
+	}
+}
+
+func TestCircuitBreakers_FailedProbeReopens(t *testing.T) {
+	t.Parallel()
+
+	breakers := newCircuitBreakers()
+	settings := circuitSettings{authThreshold: 1, providerThreshold: 10, openFor: 10 * time.Second}
+	auth := &Auth{ID: "cb-probe", Provider: "codex"}
+	now := time.Now()
+
+	breakers.record(auth, circuitFailure, "boom", now, settings)
+	probeAt := now.Add(11 * time.Second)
+	if admitted, _ := breakers.acquire(auth, probeAt, settings); !admitted {
+		t.Fatal("acquire() rejected the half-open probe")
+	}
+	events := breakers.record(auth, circuitFailure, "still failing", probeAt, settings)
+	if len(events) != 1 || events[0].Type != EventCircuitOpened || !events[0].RetryAt.Equal(probeAt.Add(10*time.Second)) {
+		t.Fatalf("record() events = %v, want circuit re-opened for another period", events)
+	}
+	if breakers.available(auth, probeAt.Add(time.Second), settings) {
+		t.Fatal("available() = true after failed probe")
+	}
+}
+
+func TestCircuitBreakers_ProviderCircuitSharedByBaseURL(t *testing.T) {
+	t.Parallel()
+
+	breakers := newCircuitBreakers()
+	settings := circuitSettings{authThreshold: 10, providerThreshold: 2, openFor: time.Minute}
+	first := &Auth{ID: "cb-first", Provider: "openai-compatibility", Attributes: map[string]string{"base_url": "https://llm.example.com"}}
+	second := &Auth{ID: "cb-second", Provider: "openai-compatibility", Attributes: map[string]string{"base_url": "https://llm.example.com/"}}
+	other := &Auth{ID: "cb-other", Provider: "openai-compatibility", Attributes: map[string]string{"base_url": "https://other.example.com"}}
+	now := time.Now()
+
+	breakers.record(first, circuitFailure, "boom", now, settings)
+	events := breakers.record(second, circuitFailure, "boom", now, settings)
+	if len(events) != 1 || events[0].Scope != CircuitScopeProvider {
+		t.Fatalf("record() events = %v, want provider circuit opened", events)
+	}
+	if breakers.available(first, now, settings) || breakers.available(second, now, settings) {
+		t.Fatal("available() = true for auth behind an open provider circuit")
+	}
+	if !breakers.available(other, now, settings) {
+		t.Fatal("available() = false for auth on a different base URL")
+	}
+}
+
+func TestCircuitBreakers_ClientErrorsDoNotCount(t *testing.T) {
+	t.Parallel()
+
+	m := NewManager(nil, nil, nil)
+	m.SetConfig(&internalconfig.Config{CircuitBreaker: internalconfig.CircuitBreakerConfig{Enable: true, FailureThreshold: 1}})
+	auth := &Auth{ID: "cb-client", Provider: "claude"}
+	now := time.Now()
+
+	for _, status := range []int{http.StatusBadRequest, http.StatusTooManyRequests} {
+		m.recordCircuitResult(context.Background(), auth, Result{AuthID: auth.ID, Error: &Error{HTTPStatus: status, Message: "client"}}, now)
+	}
+	if !m.circuitAvailable(auth, now) {
+		t.Fatal("circuitAvailable() = false after client errors")
+	}
+	m.recordCircuitResult(context.Background(), auth, Result{AuthID: auth.ID, Error: &Error{Message: "connection reset"}}, now)
+	if m.circuitAvailable(auth, now) {
+		t.Fatal("circuitAvailable() = true after network error at threshold 1")
+	}
+}
+
+func TestCircuitBreakers_CancellationDoesNotCount(t *testing.T) {
+	t.Parallel()
+
+	m := NewManager(nil, nil, nil)
+	m.SetConfig(&internalconfig.Config{CircuitBreaker: internalconfig.CircuitBreakerConfig{Enable: true, FailureThreshold: 1}})
+	auth := &Auth{ID: "cb-cancel", Provider: "claude"}
+	now := time.Now()
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	m.recordCircuitResult(cancelled, auth, Result{AuthID: auth.ID, Error: &Error{Message: "read: connection closed"}}, now)
+	m.recordCircuitResult(context.Background(), auth, Result{AuthID: auth.ID, Error: &Error{Message: "post: context canceled"}}, now)
+	if !m.circuitAvailable(auth, now) {
+		t.Fatal("circuitAvailable() = false after cancelled requests")
+	}
+}
+
+func TestCircuitBreakers_OnlySuccessCloses(t *testing.T) {
+	t.Parallel()
+
+	breakers := newCircuitBreakers()
+	settings := circuitSettings{authThreshold: 10, providerThreshold: 1, openFor: time.Minute}
+	failing := &Auth{ID: "cb-failing", Provider: "codex"}
+	sibling := &Auth{ID: "cb-sibling", Provider: "codex"}
+	now := time.Now()
+
+	breakers.record(failing, circuitFailure, "boom", now, settings)
+	if events := breakers.record(sibling, circuitNeutral, "quota", now, settings); len(events) != 0 {
+		t.Fatalf("record() events = %v, want none for a quota error", events)
+	}
+	if breakers.available(sibling, now, settings) {
+		t.Fatal("available() = true after a quota error cleared the open provider circuit")
+	}
+}
+
+func TestCircuitBreakers_ReleasedProbeReadmits(t *testing.T) {
+	t.Parallel()
+
+	m := NewManager(nil, nil, nil)
+	m.SetConfig(&internalconfig.Config{CircuitBreaker: internalconfig.CircuitBreakerConfig{Enable: true, FailureThreshold: 1, OpenSeconds: 10}})
+	auth := &Auth{ID: "cb-release", Provider: "claude"}
+	now := time.Now()
+
+	m.recordCircuitResult(context.Background(), auth, Result{AuthID: auth.ID, Error: &Error{Message: "connection reset"}}, now)
+	probeAt := now.Add(11 * time.Second)
+	if admitted, _ := m.acquireCircuit(auth, probeAt); !admitted {
+		t.Fatal("acquireCircuit() rejected the half-open probe")
+	}
+	if m.circuitAvailable(auth, probeAt) {
+		t.Fatal("circuitAvailable() = true while the probe is in flight")
+	}
+	m.releaseCircuitProbe(auth)
+	if admitted, _ := m.acquireCircuit(auth, probeAt.Add(time.Second)); !admitted {
+		t.Fatal("acquireCircuit() still blocked after the probe ended without a result")
+	}
+}
+
+func TestManagerExecute_CircuitOpenRejectsRequests(t *testing.T) {
+	m := NewManager(nil, nil, nil)
+	m.SetConfig(&internalconfig.Config{CircuitBreaker: internalconfig.CircuitBreakerConfig{Enable: true, FailureThreshold: 1}})
+	executor := &fallbackTestExecutor{provider: "circuit-test", err: &Error{HTTPStatus: http.StatusBadGateway, Message: "bad gateway"}}
+	m.RegisterExecutor(executor)
+	registerFallbackTestAuth(t, m, "circuit-auth", "circuit-test", "circuit-model")
+
+	var received []Event
+	m.AddEventListener(func(_ context.Context, event Event) { received = append(received, event) })
+
+	req := cliproxyexecutor.Request{Model: "circuit-model"}
+	if _, err := m.Execute(context.Background(), []string{"circuit-test"}, req, cliproxyexecutor.Options{}); err == nil {
+		t.Fatal("Execute() error = nil, want upstream failure")
+	}
+	_, err := m.Execute(context.Background(), []string{"circuit-test"}, req, cliproxyexecutor.Options{})
+	var authErr *Error
+	if !errors.As(err, &authErr) || authErr.Code != circuitOpenCode || authErr.StatusCode() != http.StatusServiceUnavailable {
+		t.Fatalf("Execute() error = %v, want circuit_open", err)
+	}
+	if len(executor.models) != 1 {
+		t.Fatalf("executor calls = %d, want 1", len(executor.models))
+	}
+	if len(received) != 1 || received[0].Type != EventCircuitOpened {
+		t.Fatalf("events = %v, want one circuit_opened", received)
+	}
+	if states := m.CircuitStates(); len(states) != 2 {
+		t.Fatalf("CircuitStates() = %v, want auth and provider circuits", states)
+	}
+}
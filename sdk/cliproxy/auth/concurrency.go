@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+// defaultConcurrencyQueueTimeout bounds how long a request waits for a free slot when every
+// matching auth is at its max-concurrency limit and max-concurrency-wait-seconds is unset.
+const defaultConcurrencyQueueTimeout = 5 * time.Second
+
+const authSaturatedCode = "auth_saturated"
+
+// inFlightTracker counts in-flight requests per auth and signals waiters when a slot frees up.
+type inFlightTracker struct {
+	mu      sync.Mutex
+	counts  map[string]int
+	changed chan struct{}
+}
+
+func newInFlightTracker() *inFlightTracker {
+	return &inFlightTracker{
+		counts:  make(map[string]int),
+		changed: make(chan struct{}),
+	}
+}
+
+// tryAcquire reserves a slot for authID unless limit (> 0) is already reached.
+func (t *inFlightTracker) tryAcquire(authID string, limit int) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if limit > 0 && t.counts[authID] >= limit {
+		return false
+	}
+	t.counts[authID]++
+	return true
+}
+
+// release frees a slot previously reserved for authID and wakes any waiters.
+func (t *inFlightTracker) release(authID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.counts[authID] <= 1 {
+		delete(t.counts, authID)
+	} else {
+		t.counts[authID]--
+	}
+	close(t.changed)
+	t.changed = make(chan struct{})
+}
+
+func (t *inFlightTracker) count(authID string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.counts[authID]
+}
+
+// waitChan returns a channel closed on the next release.
+func (t *inFlightTracker) waitChan() <-chan struct{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.changed
+}
+
+func (t *inFlightTracker) saturated(auth *Auth) bool {
+	limit := auth.MaxConcurrency()
+	return limit > 0 && t.count(auth.ID) >= limit
+}
+
+func newAuthSaturatedError() *Error {
+	return &Error{
+		Code:       authSaturatedCode,
+		Message:    "all credentials are at their max-concurrency limit",
+		Retryable:  true,
+		HTTPStatus: http.StatusTooManyRequests,
+	}
+}
+
+func isAuthSaturatedError(err error) bool {
+	var authErr *Error
+	return errors.As(err, &authErr) && authErr != nil && authErr.Code == authSaturatedCode
+}
+
+// InFlight returns the number of requests currently executing on the auth.
+func (m *Manager) InFlight(authID string) int {
+	if m == nil || m.inFlight == nil {
+		return 0
+	}
+	return m.inFlight.count(authID)
+}
+
+// pickNextMixedQueued behaves like pickNextMixed but waits briefly for a free slot when
+// every matching auth is saturated. The returned auth holds an in-flight slot that the
+// caller must release with releaseInFlight.
+func (m *Manager) pickNextMixedQueued(ctx context.Context, providers []string, model string, opts cliproxyexecutor.Options, tried map[string]struct{}) (*Auth, ProviderExecutor, string, error) {
+	var deadline time.Time
+	for {
+		changed := m.inFlight.waitChan()
+		auth, executor, provider, err := m.pickNextMixed(ctx, providers, model, opts, tried)
+		if err == nil || !isAuthSaturatedError(err) {
+			if len(tried) == 0 {
+				m.noteModelAvailability(ctx, providers, model, err)
+			}
+			return auth, executor, provider, err
+		}
+		if deadline.IsZero() {
+			deadline = time.Now().Add(m.concurrencyQueueTimeout())
+		}
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return nil, nil, "", err
+		}
+		timer := time.NewTimer(remaining)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, nil, "", ctx.Err()
+		case <-timer.C:
+			return nil, nil, "", err
+		case <-changed:
+			timer.Stop()
+		}
+	}
+}
+
+// concurrencyQueueTimeout returns the configured max-concurrency-wait-seconds.
+func (m *Manager) concurrencyQueueTimeout() time.Duration {
+	cfg, _ := m.runtimeConfig.Load().(*internalconfig.Config)
+	if cfg == nil || cfg.MaxConcurrencyWaitSeconds == 0 {
+		return defaultConcurrencyQueueTimeout
+	}
+	if cfg.MaxConcurrencyWaitSeconds < 0 {
+		return 0
+	}
+	return time.Duration(cfg.MaxConcurrencyWaitSeconds) * time.Second
+}
+
+func (m *Manager) releaseInFlight(authID string) {
+	m.inFlight.release(authID)
+}
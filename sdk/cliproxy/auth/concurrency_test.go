@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+type blockingTestExecutor struct {
+	provider string
+	started  chan string
+	release  chan struct{}
+}
+
+func (e *blockingTestExecutor) Identifier() string { return e.provider }
+
+func (e *blockingTestExecutor) Execute(ctx context.Context, auth *Auth, _ cliproxyexecutor.Request, _ cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	e.started <- auth.ID
+	select {
+	case <-e.release:
+	case <-ctx.Done():
+		return cliproxyexecutor.Response{}, ctx.Err()
+	}
+	return cliproxyexecutor.Response{Payload: []byte(auth.ID)}, nil
+}
+
+func (e *blockingTestExecutor) ExecuteStream(context.Context, *Auth, cliproxyexecutor.Request, cliproxyexecutor.Options) (<-chan cliproxyexecutor.StreamChunk, error) {
+	return nil, &Error{Message: "not implemented"}
+}
+
+func (e *blockingTestExecutor) Refresh(_ context.Context, auth *Auth) (*Auth, error) {
+	return auth, nil
+}
+
+func (e *blockingTestExecutor) CountTokens(context.Context, *Auth, cliproxyexecutor.Request, cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	return cliproxyexecutor.Response{}, &Error{Message: "not implemented"}
+}
+
+func (e *blockingTestExecutor) HttpRequest(context.Context, *Auth, *http.Request) (*http.Response, error) {
+	return nil, &Error{Message: "not implemented"}
+}
+
+func TestManagerExecute_RespectsMaxConcurrency(t *testing.T) {
+	m := NewManager(nil, &FillFirstSelector{}, nil)
+	executor := &blockingTestExecutor{provider: "concurrency-test", started: make(chan string, 4), release: make(chan struct{})}
+	m.RegisterExecutor(executor)
+	registerFallbackTestAuth(t, m, "concurrency-a", "concurrency-test", "concurrency-model")
+	registerFallbackTestAuth(t, m, "concurrency-b", "concurrency-test", "concurrency-model")
+	for _, id := range []string{"concurrency-a", "concurrency-b"} {
+		auth, _ := m.GetByID(id)
+		auth.Attributes = map[string]string{"max_concurrency": "1"}
+		if _, err := m.Update(context.Background(), auth); err != nil {
+			t.Fatalf("update auth: %v", err)
+		}
+	}
+
+	req := cliproxyexecutor.Request{Model: "concurrency-model"}
+	results := make(chan error, 3)
+	for i := 0; i < 3; i++ {
+		go func() {
+			_, err := m.Execute(context.Background(), []string{"concurrency-test"}, req, cliproxyexecutor.Options{})
+			results <- err
+		}()
+	}
+
+	seen := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		select {
+		case id := <-executor.started:
+			seen[id] = true
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for executions to start")
+		}
+	}
+	if !seen["concurrency-a"] || !seen["concurrency-b"] {
+		t.Fatalf("started auths = %v, want both auths in use", seen)
+	}
+	if got := m.InFlight("concurrency-a"); got != 1 {
+		t.Fatalf("InFlight(concurrency-a) = %d, want 1", got)
+	}
+	select {
+	case id := <-executor.started:
+		t.Fatalf("third request started on %s while all auths were saturated", id)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(executor.release)
+	<-executor.started
+	for i := 0; i < 3; i++ {
+		if err := <-results; err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+	}
+	if got := m.InFlight("concurrency-a") + m.InFlight("concurrency-b"); got != 0 {
+		t.Fatalf("in-flight after completion = %d, want 0", got)
+	}
+}
+
+func TestManagerConcurrencyQueueTimeout_Configurable(t *testing.T) {
+	t.Parallel()
+
+	m := NewManager(nil, nil, nil)
+	if got := m.concurrencyQueueTimeout(); got != defaultConcurrencyQueueTimeout {
+		t.Fatalf("default timeout = %v, want %v", got, defaultConcurrencyQueueTimeout)
+	}
+	m.SetConfig(&internalconfig.Config{MaxConcurrencyWaitSeconds: 30})
+	if got := m.concurrencyQueueTimeout(); got != 30*time.Second {
+		t.Fatalf("timeout = %v, want 30s", got)
+	}
+	m.SetConfig(&internalconfig.Config{MaxConcurrencyWaitSeconds: -1})
+	if got := m.concurrencyQueueTimeout(); got != 0 {
+		t.Fatalf("timeout = %v, want 0 when waiting is disabled", got)
+	}
+}
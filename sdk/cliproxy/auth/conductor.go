@@ -21,7 +21,9 @@ import (
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/thinking"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
 	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
 	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // ProviderExecutor defines the contract required by Manager to execute provider calls.
@@ -84,6 +86,11 @@ type Result struct {
 	RetryAfter *time.Duration
 	// Error describes the failure when Success is false.
 	Error *Error
+	// Latency is the total time spent on the upstream call, zero when unknown.
+	Latency time.Duration
+	// FirstByteLatency is the time until the first stream chunk arrived; zero for non-streaming
+	// calls and when unknown.
+	FirstByteLatency time.Duration
 }
 
 // Selector chooses an auth candidate for execution.
@@ -91,6 +98,12 @@ type Selector interface {
 	Pick(ctx context.Context, provider, model string, opts cliproxyexecutor.Options, auths []*Auth) (*Auth, error)
 }
 
+// ResultObserver is implemented by selectors that learn from execution results.
+// Manager forwards every result recorded through MarkResult to the active selector.
+type ResultObserver interface {
+	ObserveResult(result Result)
+}
+
 // Hook captures lifecycle callbacks for observing auth changes.
 type Hook interface {
 	// OnAuthRegistered fires when a new auth is registered.
@@ -101,6 +114,27 @@ type Hook interface {
 	OnResult(ctx context.Context, result Result)
 }
 
+// hookChain calls every hook in order.
+type hookChain []Hook
+
+func (c hookChain) OnAuthRegistered(ctx context.Context, auth *Auth) {
+	for _, hook := range c {
+		hook.OnAuthRegistered(ctx, auth)
+	}
+}
+
+func (c hookChain) OnAuthUpdated(ctx context.Context, auth *Auth) {
+	for _, hook := range c {
+		hook.OnAuthUpdated(ctx, auth)
+	}
+}
+
+func (c hookChain) OnResult(ctx context.Context, result Result) {
+	for _, hook := range c {
+		hook.OnResult(ctx, result)
+	}
+}
+
 // NoopHook provides optional hook defaults.
 type NoopHook struct{}
 
@@ -123,6 +157,24 @@ type Manager struct {
 	auths     map[string]*Auth
 	// providerOffsets tracks per-model provider rotation state for multi-provider routing.
 	providerOffsets map[string]int
+	// inFlight counts executing requests per auth to enforce max-concurrency.
+	inFlight *inFlightTracker
+	// breakers tracks circuit breaker state per auth and per upstream base URL.
+	breakers *circuitBreakers
+	// events fans out auth lifecycle events to registered listeners.
+	events eventBus
+	// modelOutages holds the routes whose credentials were all unavailable at the last pick.
+	modelOutages sync.Map
+	// queue orders requests waiting for cooling-down credentials to recover.
+	queue *cooldownQueue
+
+	// pendingStates holds availability state restored at Load for auths registered later.
+	pendingStates map[string]*persistedAuthState
+	// stateSaveTimer debounces availability state writes to a StateStore; stateWriteMu
+	// serialises the writes themselves.
+	stateSaveMu    sync.Mutex
+	stateSaveTimer *time.Timer
+	stateWriteMu   sync.Mutex
 
 	// Retry controls request retry behavior.
 	requestRetry     atomic.Int32
@@ -144,6 +196,8 @@ type Manager struct {
 
 	// Auto refresh state
 	refreshCancel context.CancelFunc
+	// refreshFailures counts consecutive refresh failures per auth, guarded by mu.
+	refreshFailures map[string]int
 }
 
 // NewManager constructs a manager with optional custom selector and hook.
@@ -161,6 +215,10 @@ func NewManager(store Store, selector Selector, hook Hook) *Manager {
 		hook:            hook,
 		auths:           make(map[string]*Auth),
 		providerOffsets: make(map[string]int),
+		inFlight:        newInFlightTracker(),
+		breakers:        newCircuitBreakers(),
+		queue:           newCooldownQueue(),
+		refreshFailures: make(map[string]int),
 	}
 	// atomic.Value requires non-nil initial value.
 	manager.runtimeConfig.Store(&internalconfig.Config{})
@@ -415,7 +473,9 @@ func (m *Manager) Register(ctx context.Context, auth *Auth) (*Auth, error) {
 	}
 	auth.EnsureIndex()
 	m.mu.Lock()
-	m.auths[auth.ID] = auth.Clone()
+	stored := auth.Clone()
+	m.restorePendingStateLocked(stored)
+	m.auths[auth.ID] = stored
 	m.mu.Unlock()
 	m.rebuildAPIKeyModelAliasFromRuntimeConfig()
 	_ = m.persist(ctx, auth)
@@ -434,7 +494,9 @@ func (m *Manager) Update(ctx context.Context, auth *Auth) (*Auth, error) {
 		auth.indexAssigned = existing.indexAssigned
 	}
 	auth.EnsureIndex()
-	m.auths[auth.ID] = auth.Clone()
+	stored := auth.Clone()
+	m.restorePendingStateLocked(stored)
+	m.auths[auth.ID] = stored
 	m.mu.Unlock()
 	m.rebuildAPIKeyModelAliasFromRuntimeConfig()
 	_ = m.persist(ctx, auth)
@@ -461,6 +523,7 @@ func (m *Manager) Load(ctx context.Context) error {
 		auth.EnsureIndex()
 		m.auths[auth.ID] = auth.Clone()
 	}
+	m.loadPersistedStateLocked(ctx)
 	cfg, _ := m.runtimeConfig.Load().(*internalconfig.Config)
 	if cfg == nil {
 		cfg = &internalconfig.Config{}
@@ -471,12 +534,16 @@ func (m *Manager) Load(ctx context.Context) error {
 
 // Execute performs a non-streaming execution using the configured selector and executor.
 // It supports multiple providers for the same model and round-robins the starting provider per model.
+// When the model is unavailable, configured model-fallbacks are tried in order.
 func (m *Manager) Execute(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
 	normalized := m.normalizeProviders(providers)
 	if len(normalized) == 0 {
 		return cliproxyexecutor.Response{}, &Error{Code: "provider_not_found", Message: "no provider supplied"}
 	}
+	return executeWithModelFallbacks(ctx, m, normalized, req, opts, m.executeWithRetry)
+}
 
+func (m *Manager) executeWithRetry(ctx context.Context, normalized []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
 	_, maxWait := m.retrySettings()
 
 	var lastErr error
@@ -490,12 +557,13 @@ func (m *Manager) Execute(ctx context.Context, providers []string, req cliproxye
 		if !shouldRetry {
 			break
 		}
+		traceRetryWait(ctx, attempt, wait, errExec)
 		if errWait := waitForCooldown(ctx, wait); errWait != nil {
 			return cliproxyexecutor.Response{}, errWait
 		}
 	}
 	if lastErr != nil {
-		return cliproxyexecutor.Response{}, lastErr
+		return waitInCooldownQueue(ctx, m, normalized, req, opts, lastErr, m.executeMixedOnce)
 	}
 	return cliproxyexecutor.Response{}, &Error{Code: "auth_not_found", Message: "no auth available"}
 }
@@ -521,6 +589,7 @@ func (m *Manager) ExecuteCount(ctx context.Context, providers []string, req clip
 		if !shouldRetry {
 			break
 		}
+		traceRetryWait(ctx, attempt, wait, errExec)
 		if errWait := waitForCooldown(ctx, wait); errWait != nil {
 			return cliproxyexecutor.Response{}, errWait
 		}
@@ -533,12 +602,16 @@ func (m *Manager) ExecuteCount(ctx context.Context, providers []string, req clip
 
 // ExecuteStream performs a streaming execution using the configured selector and executor.
 // It supports multiple providers for the same model and round-robins the starting provider per model.
+// When the model is unavailable, configured model-fallbacks are tried in order.
 func (m *Manager) ExecuteStream(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (<-chan cliproxyexecutor.StreamChunk, error) {
 	normalized := m.normalizeProviders(providers)
 	if len(normalized) == 0 {
 		return nil, &Error{Code: "provider_not_found", Message: "no provider supplied"}
 	}
+	return executeWithModelFallbacks(ctx, m, normalized, req, opts, m.executeStreamWithRetry)
+}
 
+func (m *Manager) executeStreamWithRetry(ctx context.Context, normalized []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (<-chan cliproxyexecutor.StreamChunk, error) {
 	_, maxWait := m.retrySettings()
 
 	var lastErr error
@@ -552,12 +625,13 @@ func (m *Manager) ExecuteStream(ctx context.Context, providers []string, req cli
 		if !shouldRetry {
 			break
 		}
+		traceRetryWait(ctx, attempt, wait, errStream)
 		if errWait := waitForCooldown(ctx, wait); errWait != nil {
 			return nil, errWait
 		}
 	}
 	if lastErr != nil {
-		return nil, lastErr
+		return waitInCooldownQueue(ctx, m, normalized, req, opts, lastErr, m.executeStreamMixedOnce)
 	}
 	return nil, &Error{Code: "auth_not_found", Message: "no auth available"}
 }
@@ -571,7 +645,7 @@ func (m *Manager) executeMixedOnce(ctx context.Context, providers []string, req
 	tried := make(map[string]struct{})
 	var lastErr error
 	for {
-		auth, executor, provider, errPick := m.pickNextMixed(ctx, providers, routeModel, opts, tried)
+		auth, executor, provider, errPick := m.pickNextMixedQueued(ctx, providers, routeModel, opts, tried)
 		if errPick != nil {
 			if lastErr != nil {
 				return cliproxyexecutor.Response{}, lastErr
@@ -583,41 +657,73 @@ func (m *Manager) executeMixedOnce(ctx context.Context, providers []string, req
 		debugLogAuthSelection(entry, auth, provider, req.Model)
 
 		tried[auth.ID] = struct{}{}
-		execCtx := ctx
-		if rt := m.roundTripperFor(auth); rt != nil {
-			execCtx = context.WithValue(execCtx, roundTripperContextKey{}, rt)
-			execCtx = context.WithValue(execCtx, "cliproxy.roundtripper", rt)
+		var resp cliproxyexecutor.Response
+		var errExec error
+		if delay := m.hedgeDelay(routeModel); delay > 0 {
+			resp, errExec = m.executeMixedHedged(ctx, providers, routeModel, req, opts, tried, auth, executor, provider, delay)
+		} else {
+			resp, errExec = m.executeMixedAttempt(ctx, auth, executor, provider, routeModel, req, opts)
 		}
-		execReq := req
-		execReq.Model = rewriteModelForAuth(routeModel, auth)
-		execReq.Model = m.applyOAuthModelAlias(auth, execReq.Model)
-		execReq.Model = m.applyAPIKeyModelAlias(auth, execReq.Model)
-		resp, errExec := executor.Execute(execCtx, auth, execReq, opts)
-		result := Result{AuthID: auth.ID, Provider: provider, Model: routeModel, Success: errExec == nil}
 		if errExec != nil {
-			if errCtx := execCtx.Err(); errCtx != nil {
+			if errCtx := ctx.Err(); errCtx != nil {
 				return cliproxyexecutor.Response{}, errCtx
 			}
-			result.Error = &Error{Message: errExec.Error()}
-			var se cliproxyexecutor.StatusError
-			if errors.As(errExec, &se) && se != nil {
-				result.Error.HTTPStatus = se.StatusCode()
-			}
-			if ra := retryAfterFromError(errExec); ra != nil {
-				result.RetryAfter = ra
-			}
-			m.MarkResult(execCtx, result)
 			if isRequestInvalidError(errExec) {
 				return cliproxyexecutor.Response{}, errExec
 			}
 			lastErr = errExec
 			continue
 		}
-		m.MarkResult(execCtx, result)
 		return resp, nil
 	}
 }
 
+// executeMixedAttempt runs a single non-streaming request on auth, releases its in-flight
+// slot and records the outcome. Cancellation is returned without marking the auth. A Timing
+// already attached to ctx is reused so the caller can watch for the response headers.
+func (m *Manager) executeMixedAttempt(ctx context.Context, auth *Auth, executor ProviderExecutor, provider, routeModel string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	defer m.releaseCircuitProbe(auth)
+	execCtx := ctx
+	if rt := m.roundTripperFor(auth); rt != nil {
+		execCtx = context.WithValue(execCtx, roundTripperContextKey{}, rt)
+		execCtx = context.WithValue(execCtx, "cliproxy.roundtripper", rt)
+	}
+	execReq := req
+	execReq.Model = rewriteModelForAuth(routeModel, auth)
+	execReq.Model = m.applyOAuthModelAlias(auth, execReq.Model)
+	execReq.Model = m.applyAPIKeyModelAlias(auth, execReq.Model)
+	if usage.TimingFromContext(execCtx) == nil {
+		execCtx = usage.WithTiming(execCtx)
+	}
+	execCtx, span := startAttemptSpan(execCtx, auth, provider, routeModel)
+	defer span.End()
+	startedAt := time.Now()
+	resp, errExec := executor.Execute(execCtx, auth, execReq, opts)
+	m.releaseInFlight(auth.ID)
+	elapsed := time.Since(startedAt)
+	result := Result{AuthID: auth.ID, Provider: provider, Model: routeModel, Success: errExec == nil, Latency: elapsed}
+	if errExec != nil {
+		if errCtx := execCtx.Err(); errCtx != nil {
+			span.RecordError(errCtx)
+			return cliproxyexecutor.Response{}, errCtx
+		}
+		result.Error = &Error{Message: errExec.Error()}
+		var se cliproxyexecutor.StatusError
+		if errors.As(errExec, &se) && se != nil {
+			result.Error.HTTPStatus = se.StatusCode()
+		}
+		if ra := retryAfterFromError(errExec); ra != nil {
+			result.RetryAfter = ra
+		}
+		m.MarkResult(execCtx, result)
+		recordAttemptResult(span, result)
+		return cliproxyexecutor.Response{}, errExec
+	}
+	m.MarkResult(execCtx, result)
+	recordAttemptResult(span, result)
+	return resp, nil
+}
+
 func (m *Manager) executeCountMixedOnce(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
 	if len(providers) == 0 {
 		return cliproxyexecutor.Response{}, &Error{Code: "provider_not_found", Message: "no provider supplied"}
@@ -627,7 +733,7 @@ func (m *Manager) executeCountMixedOnce(ctx context.Context, providers []string,
 	tried := make(map[string]struct{})
 	var lastErr error
 	for {
-		auth, executor, provider, errPick := m.pickNextMixed(ctx, providers, routeModel, opts, tried)
+		auth, executor, provider, errPick := m.pickNextMixedQueued(ctx, providers, routeModel, opts, tried)
 		if errPick != nil {
 			if lastErr != nil {
 				return cliproxyexecutor.Response{}, lastErr
@@ -648,10 +754,17 @@ func (m *Manager) executeCountMixedOnce(ctx context.Context, providers []string,
 		execReq.Model = rewriteModelForAuth(routeModel, auth)
 		execReq.Model = m.applyOAuthModelAlias(auth, execReq.Model)
 		execReq.Model = m.applyAPIKeyModelAlias(auth, execReq.Model)
+		execCtx = usage.WithTiming(execCtx)
+		execCtx, span := startAttemptSpan(execCtx, auth, provider, routeModel)
 		resp, errExec := executor.CountTokens(execCtx, auth, execReq, opts)
+		m.releaseInFlight(auth.ID)
+		// Token counts are cheap calls; leaving latency unset keeps them out of latency scores.
 		result := Result{AuthID: auth.ID, Provider: provider, Model: routeModel, Success: errExec == nil}
 		if errExec != nil {
 			if errCtx := execCtx.Err(); errCtx != nil {
+				m.releaseCircuitProbe(auth)
+				span.RecordError(errCtx)
+				span.End()
 				return cliproxyexecutor.Response{}, errCtx
 			}
 			result.Error = &Error{Message: errExec.Error()}
@@ -663,6 +776,8 @@ func (m *Manager) executeCountMixedOnce(ctx context.Context, providers []string,
 				result.RetryAfter = ra
 			}
 			m.MarkResult(execCtx, result)
+			recordAttemptResult(span, result)
+			span.End()
 			if isRequestInvalidError(errExec) {
 				return cliproxyexecutor.Response{}, errExec
 			}
@@ -670,6 +785,8 @@ func (m *Manager) executeCountMixedOnce(ctx context.Context, providers []string,
 			continue
 		}
 		m.MarkResult(execCtx, result)
+		recordAttemptResult(span, result)
+		span.End()
 		return resp, nil
 	}
 }
@@ -683,7 +800,7 @@ func (m *Manager) executeStreamMixedOnce(ctx context.Context, providers []string
 	tried := make(map[string]struct{})
 	var lastErr error
 	for {
-		auth, executor, provider, errPick := m.pickNextMixed(ctx, providers, routeModel, opts, tried)
+		auth, executor, provider, errPick := m.pickNextMixedQueued(ctx, providers, routeModel, opts, tried)
 		if errPick != nil {
 			if lastErr != nil {
 				return nil, lastErr
@@ -695,68 +812,115 @@ func (m *Manager) executeStreamMixedOnce(ctx context.Context, providers []string
 		debugLogAuthSelection(entry, auth, provider, req.Model)
 
 		tried[auth.ID] = struct{}{}
-		execCtx := ctx
-		if rt := m.roundTripperFor(auth); rt != nil {
-			execCtx = context.WithValue(execCtx, roundTripperContextKey{}, rt)
-			execCtx = context.WithValue(execCtx, "cliproxy.roundtripper", rt)
-		}
-		execReq := req
-		execReq.Model = rewriteModelForAuth(routeModel, auth)
-		execReq.Model = m.applyOAuthModelAlias(auth, execReq.Model)
-		execReq.Model = m.applyAPIKeyModelAlias(auth, execReq.Model)
-		chunks, errStream := executor.ExecuteStream(execCtx, auth, execReq, opts)
+		chunks, errStream := m.executeStreamAttempt(ctx, auth, executor, provider, routeModel, req, opts)
 		if errStream != nil {
-			if errCtx := execCtx.Err(); errCtx != nil {
+			if errCtx := ctx.Err(); errCtx != nil {
 				return nil, errCtx
 			}
-			rerr := &Error{Message: errStream.Error()}
-			var se cliproxyexecutor.StatusError
-			if errors.As(errStream, &se) && se != nil {
-				rerr.HTTPStatus = se.StatusCode()
-			}
-			result := Result{AuthID: auth.ID, Provider: provider, Model: routeModel, Success: false, Error: rerr}
-			result.RetryAfter = retryAfterFromError(errStream)
-			m.MarkResult(execCtx, result)
 			if isRequestInvalidError(errStream) {
 				return nil, errStream
 			}
 			lastErr = errStream
 			continue
 		}
-		out := make(chan cliproxyexecutor.StreamChunk)
-		go func(streamCtx context.Context, streamAuth *Auth, streamProvider string, streamChunks <-chan cliproxyexecutor.StreamChunk) {
-			defer close(out)
-			var failed bool
-			forward := true
-			for chunk := range streamChunks {
-				if chunk.Err != nil && !failed {
-					failed = true
-					rerr := &Error{Message: chunk.Err.Error()}
-					var se cliproxyexecutor.StatusError
-					if errors.As(chunk.Err, &se) && se != nil {
-						rerr.HTTPStatus = se.StatusCode()
-					}
-					m.MarkResult(streamCtx, Result{AuthID: streamAuth.ID, Provider: streamProvider, Model: routeModel, Success: false, Error: rerr})
-				}
-				if !forward {
-					continue
-				}
-				if streamCtx == nil {
-					out <- chunk
-					continue
+		return chunks, nil
+	}
+}
+
+// executeStreamAttempt starts a streaming request on auth. The returned channel forwards
+// the upstream chunks, and the in-flight slot is released and the outcome recorded once the
+// stream ends. When the stream cannot be started the failure is recorded and returned;
+// cancellation is returned without marking the auth.
+func (m *Manager) executeStreamAttempt(ctx context.Context, auth *Auth, executor ProviderExecutor, provider, routeModel string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (<-chan cliproxyexecutor.StreamChunk, error) {
+	execCtx := ctx
+	if rt := m.roundTripperFor(auth); rt != nil {
+		execCtx = context.WithValue(execCtx, roundTripperContextKey{}, rt)
+		execCtx = context.WithValue(execCtx, "cliproxy.roundtripper", rt)
+	}
+	execReq := req
+	execReq.Model = rewriteModelForAuth(routeModel, auth)
+	execReq.Model = m.applyOAuthModelAlias(auth, execReq.Model)
+	execReq.Model = m.applyAPIKeyModelAlias(auth, execReq.Model)
+	execCtx = usage.WithTiming(execCtx)
+	execCtx, span := startAttemptSpan(execCtx, auth, provider, routeModel)
+	span.SetAttributes(attribute.Bool("cliproxy.stream", true))
+	startedAt := time.Now()
+	chunks, errStream := executor.ExecuteStream(execCtx, auth, execReq, opts)
+	if errStream != nil {
+		m.releaseInFlight(auth.ID)
+		if errCtx := execCtx.Err(); errCtx != nil {
+			m.releaseCircuitProbe(auth)
+			span.RecordError(errCtx)
+			span.End()
+			return nil, errCtx
+		}
+		rerr := &Error{Message: errStream.Error()}
+		var se cliproxyexecutor.StatusError
+		if errors.As(errStream, &se) && se != nil {
+			rerr.HTTPStatus = se.StatusCode()
+		}
+		result := Result{AuthID: auth.ID, Provider: provider, Model: routeModel, Success: false, Error: rerr, Latency: time.Since(startedAt)}
+		result.RetryAfter = retryAfterFromError(errStream)
+		m.MarkResult(execCtx, result)
+		recordAttemptResult(span, result)
+		span.End()
+		return nil, errStream
+	}
+	out := make(chan cliproxyexecutor.StreamChunk)
+	go func(streamCtx context.Context, streamAuth *Auth, streamProvider string, streamChunks <-chan cliproxyexecutor.StreamChunk) {
+		defer close(out)
+		defer m.releaseInFlight(streamAuth.ID)
+		defer m.releaseCircuitProbe(streamAuth)
+		defer span.End()
+		var failed bool
+		var firstByte time.Duration
+		chunkCount := 0
+		forward := true
+		for chunk := range streamChunks {
+			if len(chunk.Payload) > 0 {
+				chunkCount++
+				if firstByte == 0 {
+					firstByte = time.Since(startedAt)
 				}
-				select {
-				case <-streamCtx.Done():
-					forward = false
-				case out <- chunk:
+			}
+			switch {
+			case chunk.Err == nil || failed:
+			case streamCtx != nil && streamCtx.Err() != nil:
+				// Cancelled by the client; the auth is not at fault.
+				failed = true
+				span.RecordError(streamCtx.Err())
+			default:
+				failed = true
+				rerr := &Error{Message: chunk.Err.Error()}
+				var se cliproxyexecutor.StatusError
+				if errors.As(chunk.Err, &se) && se != nil {
+					rerr.HTTPStatus = se.StatusCode()
 				}
+				result := Result{AuthID: streamAuth.ID, Provider: streamProvider, Model: routeModel, Success: false, Error: rerr, Latency: time.Since(startedAt), FirstByteLatency: firstByte}
+				m.MarkResult(streamCtx, result)
+				recordAttemptResult(span, result)
+			}
+			if !forward {
+				continue
 			}
-			if !failed {
-				m.MarkResult(streamCtx, Result{AuthID: streamAuth.ID, Provider: streamProvider, Model: routeModel, Success: true})
+			if streamCtx == nil {
+				out <- chunk
+				continue
 			}
-		}(execCtx, auth.Clone(), provider, chunks)
-		return out, nil
-	}
+			select {
+			case <-streamCtx.Done():
+				forward = false
+			case out <- chunk:
+			}
+		}
+		span.SetAttributes(attribute.Int("cliproxy.stream.chunks", chunkCount))
+		if !failed {
+			result := Result{AuthID: streamAuth.ID, Provider: streamProvider, Model: routeModel, Success: true, Latency: time.Since(startedAt), FirstByteLatency: firstByte}
+			m.MarkResult(streamCtx, result)
+			recordAttemptResult(span, result)
+		}
+	}(execCtx, auth.Clone(), provider, chunks)
+	return out, nil
 }
 
 func ensureRequestedModelMetadata(opts cliproxyexecutor.Options, requestedModel string) cliproxyexecutor.Options {
@@ -1148,16 +1312,21 @@ func (m *Manager) MarkResult(ctx context.Context, result Result) {
 	if result.AuthID == "" {
 		return
 	}
+	RequestStatsFromContext(ctx).record(result)
 
 	shouldResumeModel := false
 	shouldSuspendModel := false
 	suspendReason := ""
 	clearModelQuota := false
 	setModelQuota := false
+	availabilityChanged := false
+	var circuitEvents []Event
 
 	m.mu.Lock()
 	if auth, ok := m.auths[result.AuthID]; ok && auth != nil {
 		now := time.Now()
+		before := snapshotAvailability(auth)
+		circuitEvents = m.recordCircuitResult(ctx, auth, result, now)
 
 		if result.Success {
 			if result.Model != "" {
@@ -1246,6 +1415,7 @@ func (m *Manager) MarkResult(ctx context.Context, result Result) {
 			}
 		}
 
+		availabilityChanged = !before.equal(snapshotAvailability(auth))
 		_ = m.persist(ctx, auth)
 	}
 	m.mu.Unlock()
@@ -1261,6 +1431,17 @@ func (m *Manager) MarkResult(ctx context.Context, result Result) {
 	} else if shouldSuspendModel {
 		registry.GetGlobalRegistry().SuspendClientModel(result.AuthID, result.Model, suspendReason)
 	}
+	m.emitEvents(ctx, circuitEvents)
+	if availabilityChanged {
+		m.scheduleStateSave()
+	}
+
+	m.mu.RLock()
+	selector := m.selector
+	m.mu.RUnlock()
+	if observer, ok := selector.(ResultObserver); ok {
+		observer.ObserveResult(result)
+	}
 
 	m.hook.OnResult(ctx, result)
 }
@@ -1555,6 +1736,22 @@ func (m *Manager) GetByID(id string) (*Auth, bool) {
 	return auth.Clone(), true
 }
 
+// HasPrefix reports whether any enabled auth is configured with the credential prefix.
+func (m *Manager) HasPrefix(prefix string) bool {
+	prefix = strings.Trim(strings.TrimSpace(prefix), "/")
+	if m == nil || prefix == "" {
+		return false
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, auth := range m.auths {
+		if auth != nil && !auth.Disabled && strings.TrimSpace(auth.Prefix) == prefix {
+			return true
+		}
+	}
+	return false
+}
+
 func (m *Manager) pickNext(ctx context.Context, provider, model string, opts cliproxyexecutor.Options, tried map[string]struct{}) (*Auth, ProviderExecutor, error) {
 	m.mu.RLock()
 	executor, okExecutor := m.executors[provider]
@@ -1579,6 +1776,9 @@ func (m *Manager) pickNext(ctx context.Context, provider, model string, opts cli
 		if _, used := tried[candidate.ID]; used {
 			continue
 		}
+		if !credentialPrefixAllowed(ctx, candidate) {
+			continue
+		}
 		if modelKey != "" && registryRef != nil && !registryRef.ClientSupportsModel(candidate.ID, modelKey) {
 			continue
 		}
@@ -1634,6 +1834,9 @@ func (m *Manager) pickNextMixed(ctx context.Context, providers []string, model s
 		}
 	}
 	registryRef := registry.GetGlobalRegistry()
+	now := time.Now()
+	saturated := 0
+	circuitOpen := 0
 	for _, candidate := range m.auths {
 		if candidate == nil || candidate.Disabled {
 			continue
@@ -1648,16 +1851,33 @@ func (m *Manager) pickNextMixed(ctx context.Context, providers []string, model s
 		if _, used := tried[candidate.ID]; used {
 			continue
 		}
+		if !credentialPrefixAllowed(ctx, candidate) {
+			continue
+		}
 		if _, ok := m.executors[providerKey]; !ok {
 			continue
 		}
 		if modelKey != "" && registryRef != nil && !registryRef.ClientSupportsModel(candidate.ID, modelKey) {
 			continue
 		}
+		if m.inFlight.saturated(candidate) {
+			saturated++
+			continue
+		}
+		if !m.circuitAvailable(candidate, now) {
+			circuitOpen++
+			continue
+		}
 		candidates = append(candidates, candidate)
 	}
 	if len(candidates) == 0 {
 		m.mu.RUnlock()
+		if saturated > 0 {
+			return nil, nil, "", newAuthSaturatedError()
+		}
+		if circuitOpen > 0 {
+			return nil, nil, "", newCircuitOpenError()
+		}
 		return nil, nil, "", &Error{Code: "auth_not_found", Message: "no auth available"}
 	}
 	selected, errPick := m.selector.Pick(ctx, "mixed", model, opts, candidates)
@@ -1675,8 +1895,19 @@ func (m *Manager) pickNextMixed(ctx context.Context, providers []string, model s
 		m.mu.RUnlock()
 		return nil, nil, "", &Error{Code: "executor_not_found", Message: "executor not registered"}
 	}
+	if !m.inFlight.tryAcquire(selected.ID, selected.MaxConcurrency()) {
+		m.mu.RUnlock()
+		return nil, nil, "", newAuthSaturatedError()
+	}
+	admitted, circuitEvents := m.acquireCircuit(selected, now)
+	if !admitted {
+		m.mu.RUnlock()
+		m.releaseInFlight(selected.ID)
+		return nil, nil, "", newCircuitOpenError()
+	}
 	authCopy := selected.Clone()
 	m.mu.RUnlock()
+	m.emitEvents(ctx, circuitEvents)
 	if !selected.indexAssigned {
 		m.mu.Lock()
 		if current := m.auths[authCopy.ID]; current != nil && !current.indexAssigned {
@@ -2022,13 +2253,17 @@ func (m *Manager) refreshAuth(ctx context.Context, id string) {
 	log.Debugf("refreshed %s, %s, %v", auth.Provider, auth.ID, err)
 	now := time.Now()
 	if err != nil {
+		failures := 0
 		m.mu.Lock()
 		if current := m.auths[id]; current != nil {
 			current.NextRefreshAfter = now.Add(refreshFailureBackoff)
 			current.LastError = &Error{Message: err.Error()}
 			m.auths[id] = current
+			m.refreshFailures[id]++
+			failures = m.refreshFailures[id]
 		}
 		m.mu.Unlock()
+		m.emitEvents(ctx, []Event{{Type: EventRefreshFailed, Time: now, AuthID: auth.ID, Provider: auth.Provider, Failures: failures, LastError: err.Error()}})
 		return
 	}
 	if updated == nil {
@@ -2043,7 +2278,11 @@ func (m *Manager) refreshAuth(ctx context.Context, id string) {
 	updated.NextRefreshAfter = time.Time{}
 	updated.LastError = nil
 	updated.UpdatedAt = now
+	m.mu.Lock()
+	delete(m.refreshFailures, id)
+	m.mu.Unlock()
 	_, _ = m.Update(ctx, updated)
+	m.emitEvents(ctx, []Event{{Type: EventRefreshSucceeded, Time: now, AuthID: auth.ID, Provider: auth.Provider}})
 }
 
 func (m *Manager) executorFor(provider string) ProviderExecutor {
@@ -0,0 +1,238 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/thinking"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+const (
+	defaultCooldownQueueMaxWait  = 60 * time.Second
+	defaultCooldownQueueMaxDepth = 100
+
+	// cooldownQueuePollInterval caps how long the head of a queue sleeps before re-checking
+	// availability, since credentials can also recover through unrelated successful requests.
+	cooldownQueuePollInterval = time.Second
+	cooldownQueueMinInterval  = 50 * time.Millisecond
+)
+
+var queuePriorityClasses = map[string]int{"low": 0, "normal": 1, "high": 2}
+
+type queueTicket struct {
+	priority int
+	seq      uint64
+}
+
+// cooldownQueue orders requests waiting for a model's credentials to recover.
+// Only the head of each model's queue retries, so requests are admitted in priority
+// order and first-come first-served within a priority class.
+type cooldownQueue struct {
+	mu      sync.Mutex
+	seq     uint64
+	waiting map[string][]*queueTicket
+	changed chan struct{}
+}
+
+func newCooldownQueue() *cooldownQueue {
+	return &cooldownQueue{
+		waiting: make(map[string][]*queueTicket),
+		changed: make(chan struct{}),
+	}
+}
+
+// enqueue adds a ticket for model unless maxDepth requests are already waiting.
+func (q *cooldownQueue) enqueue(model string, priority, maxDepth int) (*queueTicket, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	tickets := q.waiting[model]
+	if len(tickets) >= maxDepth {
+		return nil, false
+	}
+	q.seq++
+	ticket := &queueTicket{priority: priority, seq: q.seq}
+	tickets = append(tickets, ticket)
+	sort.SliceStable(tickets, func(i, j int) bool {
+		if tickets[i].priority != tickets[j].priority {
+			return tickets[i].priority > tickets[j].priority
+		}
+		return tickets[i].seq < tickets[j].seq
+	})
+	q.waiting[model] = tickets
+	q.broadcastLocked()
+	return ticket, true
+}
+
+func (q *cooldownQueue) remove(model string, ticket *queueTicket) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	tickets := q.waiting[model]
+	for i, candidate := range tickets {
+		if candidate == ticket {
+			tickets = append(tickets[:i], tickets[i+1:]...)
+			break
+		}
+	}
+	if len(tickets) == 0 {
+		delete(q.waiting, model)
+	} else {
+		q.waiting[model] = tickets
+	}
+	q.broadcastLocked()
+}
+
+func (q *cooldownQueue) isHead(model string, ticket *queueTicket) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	tickets := q.waiting[model]
+	return len(tickets) > 0 && tickets[0] == ticket
+}
+
+func (q *cooldownQueue) depth(model string) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.waiting[model])
+}
+
+// waitChan returns a channel closed on the next queue change.
+func (q *cooldownQueue) waitChan() <-chan struct{} {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.changed
+}
+
+func (q *cooldownQueue) broadcastLocked() {
+	close(q.changed)
+	q.changed = make(chan struct{})
+}
+
+type queueNotifierContextKey struct{}
+
+// WithQueueNotifier returns a context whose requests call notify once when they start
+// waiting in the cooldown queue. Streaming handlers use it to send keep-alives while queued.
+func WithQueueNotifier(ctx context.Context, notify func()) context.Context {
+	if notify == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, queueNotifierContextKey{}, notify)
+}
+
+func notifyQueued(ctx context.Context) {
+	if notify, ok := ctx.Value(queueNotifierContextKey{}).(func()); ok && notify != nil {
+		notify()
+	}
+}
+
+// QueueDepth returns the number of requests waiting in the cooldown queue for model.
+func (m *Manager) QueueDepth(model string) int {
+	if m == nil || m.queue == nil {
+		return 0
+	}
+	return m.queue.depth(cooldownQueueKey(model))
+}
+
+func cooldownQueueKey(model string) string {
+	return strings.ToLower(strings.TrimSpace(thinking.ParseSuffix(model).ModelName))
+}
+
+// isQueueableError reports whether err means the model's credentials are temporarily
+// unavailable: all cooling down or all at their concurrency limit.
+func isQueueableError(err error) bool {
+	var cooldownErr *modelCooldownError
+	return errors.As(err, &cooldownErr) || isAuthSaturatedError(err)
+}
+
+// queuePriority resolves the priority class of the client API key carried by ctx.
+func queuePriority(ctx context.Context, priorities map[string]string) int {
+	normal := queuePriorityClasses["normal"]
+	if len(priorities) == 0 || ctx == nil {
+		return normal
+	}
+	getter, ok := ctx.Value("gin").(interface{ Get(string) (any, bool) })
+	if !ok || getter == nil {
+		return normal
+	}
+	value, exists := getter.Get("apiKey")
+	if !exists {
+		return normal
+	}
+	key, _ := value.(string)
+	if priority, ok := queuePriorityClasses[priorities[key]]; ok {
+		return priority
+	}
+	return normal
+}
+
+// waitInCooldownQueue queues a request whose model has no available credential and retries
+// it as credentials recover. It returns firstErr immediately when queueing is disabled or the
+// queue is full, and the latest availability error when the maximum wait elapses.
+func waitInCooldownQueue[T any](ctx context.Context, m *Manager, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, firstErr error, exec func(context.Context, []string, cliproxyexecutor.Request, cliproxyexecutor.Options) (T, error)) (T, error) {
+	var zero T
+	cfg, _ := m.runtimeConfig.Load().(*internalconfig.Config)
+	if cfg == nil || !cfg.CooldownQueue.Enable || !isQueueableError(firstErr) {
+		return zero, firstErr
+	}
+	maxWait := time.Duration(cfg.CooldownQueue.MaxWaitSeconds) * time.Second
+	if maxWait <= 0 {
+		maxWait = defaultCooldownQueueMaxWait
+	}
+	maxDepth := cfg.CooldownQueue.MaxDepth
+	if maxDepth <= 0 {
+		maxDepth = defaultCooldownQueueMaxDepth
+	}
+
+	model := cooldownQueueKey(req.Model)
+	ticket, ok := m.queue.enqueue(model, queuePriority(ctx, cfg.CooldownQueue.Priorities), maxDepth)
+	if !ok {
+		logEntryWithRequestID(ctx).Debugf("cooldown queue for %s is full", model)
+		return zero, firstErr
+	}
+	defer m.queue.remove(model, ticket)
+	notifyQueued(ctx)
+
+	deadline := time.NewTimer(maxWait)
+	defer deadline.Stop()
+	lastErr := firstErr
+	for {
+		changed := m.queue.waitChan()
+		if m.queue.isHead(model, ticket) {
+			// attempt -1 ignores per-auth retry budgets; the queue has its own deadline.
+			wait, found := m.closestCooldownWait(providers, req.Model, -1)
+			if !found || wait > cooldownQueuePollInterval {
+				wait = cooldownQueuePollInterval
+			}
+			if wait < cooldownQueueMinInterval {
+				wait = cooldownQueueMinInterval
+			}
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return zero, ctx.Err()
+			case <-deadline.C:
+				timer.Stop()
+				return zero, lastErr
+			case <-timer.C:
+			}
+			result, err := exec(ctx, providers, req, opts)
+			if err == nil || !isQueueableError(err) {
+				return result, err
+			}
+			lastErr = err
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return zero, ctx.Err()
+		case <-deadline.C:
+			return zero, lastErr
+		case <-changed:
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+func TestCooldownQueue_OrdersByPriorityThenArrival(t *testing.T) {
+	t.Parallel()
+
+	q := newCooldownQueue()
+	low, _ := q.enqueue("model", queuePriorityClasses["low"], 10)
+	first, _ := q.enqueue("model", queuePriorityClasses["normal"], 10)
+	second, _ := q.enqueue("model", queuePriorityClasses["normal"], 10)
+	high, _ := q.enqueue("model", queuePriorityClasses["high"], 10)
+
+	for _, want := range []*queueTicket{high, first, second, low} {
+		if !q.isHead("model", want) {
+			t.Fatalf("isHead() = false for ticket seq %d", want.seq)
+		}
+		q.remove("model", want)
+	}
+	if depth := q.depth("model"); depth != 0 {
+		t.Fatalf("depth() = %d, want 0", depth)
+	}
+}
+
+func TestCooldownQueue_RejectsBeyondMaxDepth(t *testing.T) {
+	t.Parallel()
+
+	q := newCooldownQueue()
+	if _, ok := q.enqueue("model", 1, 1); !ok {
+		t.Fatal("enqueue() rejected the first request")
+	}
+	if _, ok := q.enqueue("model", 1, 1); ok {
+		t.Fatal("enqueue() accepted a request beyond max depth")
+	}
+	if _, ok := q.enqueue("other", 1, 1); !ok {
+		t.Fatal("enqueue() applied the depth limit across models")
+	}
+}
+
+func registerCoolingTestAuth(t *testing.T, m *Manager, id, provider, model string, recoverIn time.Duration) {
+	t.Helper()
+	registerFallbackTestAuth(t, m, id, provider, model)
+	auth, _ := m.GetByID(id)
+	next := time.Now().Add(recoverIn)
+	auth.ModelStates = map[string]*ModelState{model: {
+		Status:         StatusError,
+		Unavailable:    true,
+		NextRetryAfter: next,
+		Quota:          QuotaState{Exceeded: true, NextRecoverAt: next},
+	}}
+	if _, err := m.Update(context.Background(), auth); err != nil {
+		t.Fatalf("update auth: %v", err)
+	}
+}
+
+func TestManagerExecute_QueuesUntilCooldownEnds(t *testing.T) {
+	m := NewManager(nil, nil, nil)
+	m.SetConfig(&internalconfig.Config{CooldownQueue: internalconfig.CooldownQueueConfig{Enable: true, MaxWaitSeconds: 5}})
+	m.RegisterExecutor(&fallbackTestExecutor{provider: "queue-test"})
+	registerCoolingTestAuth(t, m, "queue-auth", "queue-test", "queue-model", 200*time.Millisecond)
+
+	queued := false
+	ctx := WithQueueNotifier(context.Background(), func() { queued = true })
+	resp, err := m.Execute(ctx, []string{"queue-test"}, cliproxyexecutor.Request{Model: "queue-model"}, cliproxyexecutor.Options{})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if string(resp.Payload) != "queue-test" {
+		t.Fatalf("Execute() payload = %q, want queue-test", resp.Payload)
+	}
+	if !queued {
+		t.Fatal("queue notifier was not called")
+	}
+	if depth := m.QueueDepth("queue-model"); depth != 0 {
+		t.Fatalf("QueueDepth() = %d, want 0 after admission", depth)
+	}
+}
+
+func TestManagerExecute_QueueTimesOutWithCooldownError(t *testing.T) {
+	m := NewManager(nil, nil, nil)
+	m.SetConfig(&internalconfig.Config{CooldownQueue: internalconfig.CooldownQueueConfig{Enable: true, MaxWaitSeconds: 1}})
+	m.RegisterExecutor(&fallbackTestExecutor{provider: "queue-timeout"})
+	registerCoolingTestAuth(t, m, "queue-timeout-auth", "queue-timeout", "queue-timeout-model", time.Hour)
+
+	started := time.Now()
+	_, err := m.Execute(context.Background(), []string{"queue-timeout"}, cliproxyexecutor.Request{Model: "queue-timeout-model"}, cliproxyexecutor.Options{})
+	var cooldownErr *modelCooldownError
+	if !errors.As(err, &cooldownErr) {
+		t.Fatalf("Execute() error = %v, want model cooldown error", err)
+	}
+	if elapsed := time.Since(started); elapsed < 900*time.Millisecond {
+		t.Fatalf("Execute() returned after %s, want to wait for max-wait", elapsed)
+	}
+}
@@ -0,0 +1,130 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EventType identifies the kind of auth lifecycle event.
+type EventType string
+
+const (
+	// EventCircuitOpened fires when a circuit opens after repeated failures.
+	EventCircuitOpened EventType = "circuit_opened"
+	// EventCircuitHalfOpen fires when an open circuit admits a probe request.
+	EventCircuitHalfOpen EventType = "circuit_half_open"
+	// EventCircuitClosed fires when a probe succeeds and the circuit closes again.
+	EventCircuitClosed EventType = "circuit_closed"
+	// EventRefreshSucceeded fires when an auth's credentials were refreshed.
+	EventRefreshSucceeded EventType = "refresh_succeeded"
+	// EventRefreshFailed fires when refreshing an auth fails. Failures counts consecutive failures.
+	EventRefreshFailed EventType = "refresh_failed"
+	// EventModelUnavailable fires once when every credential for a model is cooling down or
+	// otherwise unavailable. Key holds the model and Provider the requested providers.
+	EventModelUnavailable EventType = "model_unavailable"
+)
+
+// Event describes a notable change in auth or upstream health.
+type Event struct {
+	Type      EventType `json:"type"`
+	Time      time.Time `json:"time"`
+	AuthID    string    `json:"auth_id,omitempty"`
+	Provider  string    `json:"provider,omitempty"`
+	Scope     string    `json:"scope,omitempty"`
+	Key       string    `json:"key,omitempty"`
+	Message   string    `json:"message,omitempty"`
+	RetryAt   time.Time `json:"retry_at,omitempty"`
+	Failures  int       `json:"failures,omitempty"`
+	LastError string    `json:"last_error,omitempty"`
+}
+
+// EventListener receives auth lifecycle events. Listeners run synchronously and should return quickly.
+type EventListener func(ctx context.Context, event Event)
+
+type eventBus struct {
+	mu        sync.RWMutex
+	listeners []EventListener
+}
+
+func (b *eventBus) add(listener EventListener) {
+	if listener == nil {
+		return
+	}
+	b.mu.Lock()
+	b.listeners = append(b.listeners, listener)
+	b.mu.Unlock()
+}
+
+func (b *eventBus) emit(ctx context.Context, event Event) {
+	b.mu.RLock()
+	listeners := b.listeners
+	b.mu.RUnlock()
+	for _, listener := range listeners {
+		listener(ctx, event)
+	}
+}
+
+// AddEventListener registers a listener for auth lifecycle events.
+func (m *Manager) AddEventListener(listener EventListener) {
+	if m == nil {
+		return
+	}
+	m.events.add(listener)
+}
+
+func (m *Manager) emitEvents(ctx context.Context, events []Event) {
+	if m == nil {
+		return
+	}
+	for _, event := range events {
+		logEntryWithRequestID(ctx).Infof("auth event %s: %s %s %s", event.Type, event.Scope, event.Key, event.Message)
+		m.events.emit(ctx, event)
+	}
+}
+
+// AddHook registers an additional hook called after the hook given to NewManager. It must
+// be called before the manager starts serving requests.
+func (m *Manager) AddHook(hook Hook) {
+	if m == nil || hook == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if chain, ok := m.hook.(hookChain); ok {
+		m.hook = append(chain, hook)
+		return
+	}
+	m.hook = hookChain{m.hook, hook}
+}
+
+// noteModelAvailability emits EventModelUnavailable when a first pick for model fails
+// because every matching credential is cooling down or otherwise unavailable, and forgets
+// the outage once a pick succeeds again, so listeners see one event per outage.
+func (m *Manager) noteModelAvailability(ctx context.Context, providers []string, model string, err error) {
+	key := strings.Join(providers, ",") + "|" + model
+	if err == nil {
+		m.modelOutages.Delete(key)
+		return
+	}
+	now := time.Now()
+	event := Event{Type: EventModelUnavailable, Time: now, Provider: strings.Join(providers, ","), Scope: "model", Key: model}
+	var cooldownErr *modelCooldownError
+	var authErr *Error
+	switch {
+	case errors.As(err, &cooldownErr):
+		event.RetryAt = now.Add(cooldownErr.resetIn)
+		event.Message = "all credentials are cooling down"
+	case errors.As(err, &authErr) && authErr.Code == "auth_unavailable":
+		event.Message = authErr.Message
+	default:
+		// Open circuits report their own events.
+		return
+	}
+	if _, seen := m.modelOutages.LoadOrStore(key, struct{}{}); seen {
+		return
+	}
+	m.emitEvents(ctx, []Event{event})
+}
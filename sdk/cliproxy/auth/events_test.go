@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+func TestManagerExecute_EmitsModelUnavailableOncePerOutage(t *testing.T) {
+	m := NewManager(nil, nil, nil)
+	m.RegisterExecutor(&fallbackTestExecutor{provider: "outage-test"})
+	registerCoolingTestAuth(t, m, "outage-auth", "outage-test", "outage-model", time.Hour)
+
+	var received []Event
+	m.AddEventListener(func(_ context.Context, event Event) { received = append(received, event) })
+
+	req := cliproxyexecutor.Request{Model: "outage-model"}
+	for i := 0; i < 2; i++ {
+		if _, err := m.Execute(context.Background(), []string{"outage-test"}, req, cliproxyexecutor.Options{}); err == nil {
+			t.Fatal("Execute() error = nil, want cooldown error")
+		}
+	}
+	if len(received) != 1 || received[0].Type != EventModelUnavailable || received[0].Key != "outage-model" || received[0].Provider != "outage-test" {
+		t.Fatalf("events = %v, want one model_unavailable", received)
+	}
+	if until := time.Until(received[0].RetryAt); until < 50*time.Minute {
+		t.Fatalf("RetryAt in %v, want about an hour", until)
+	}
+
+	// Recovery resets the outage so the next one is reported again.
+	m.noteModelAvailability(context.Background(), []string{"outage-test"}, "outage-model", nil)
+	if _, err := m.Execute(context.Background(), []string{"outage-test"}, req, cliproxyexecutor.Options{}); err == nil {
+		t.Fatal("Execute() error = nil, want cooldown error")
+	}
+	if len(received) != 2 {
+		t.Fatalf("events after recovery = %d, want 2", len(received))
+	}
+}
+
+type recordingHook struct {
+	NoopHook
+	results []Result
+}
+
+func (h *recordingHook) OnResult(_ context.Context, result Result) {
+	h.results = append(h.results, result)
+}
+
+func TestManagerAddHook_CallsEveryHook(t *testing.T) {
+	t.Parallel()
+
+	first := &recordingHook{}
+	second := &recordingHook{}
+	m := NewManager(nil, nil, first)
+	m.AddHook(second)
+	m.MarkResult(context.Background(), Result{AuthID: "missing", Success: true})
+	if len(first.results) != 1 || len(second.results) != 1 {
+		t.Fatalf("results = %d/%d, want 1/1", len(first.results), len(second.results))
+	}
+}
@@ -0,0 +1,164 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/thinking"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+// ServedModelHeader is the response header reporting the model that actually served a request.
+// The manager only reports the model through WithServedModelReporter; handlers set the header.
+const ServedModelHeader = "X-CPA-Served-Model"
+
+// executeWithModelFallbacks runs exec for the requested model and, when it fails with a
+// fallback-eligible error, walks the configured model-fallbacks chain in order.
+// Each fallback resolves its own providers so executors re-translate the original payload.
+func executeWithModelFallbacks[T any](ctx context.Context, m *Manager, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, exec func(context.Context, []string, cliproxyexecutor.Request, cliproxyexecutor.Options) (T, error)) (T, error) {
+	requestedModel := req.Model
+	result, err := exec(ctx, providers, req, opts)
+	if err == nil {
+		reportServedModel(ctx, requestedModel)
+		return result, nil
+	}
+	for _, fallbackModel := range m.modelFallbackChain(requestedModel) {
+		if !isModelFallbackError(err) || ctx.Err() != nil {
+			break
+		}
+		fallbackProviders := filterModelAccess(ctx, fallbackModel, m.normalizeProviders(util.GetProviderName(thinking.ParseSuffix(fallbackModel).ModelName)))
+		if len(fallbackProviders) == 0 {
+			logEntryWithRequestID(ctx).Debugf("model fallback %s skipped: no provider available", fallbackModel)
+			continue
+		}
+		logEntryWithRequestID(ctx).Infof("model fallback %s -> %s: %v", requestedModel, fallbackModel, err)
+		fallbackReq := req
+		fallbackReq.Model = fallbackModel
+		fallbackResult, errFallback := exec(ctx, fallbackProviders, fallbackReq, withRequestedModelMetadata(opts, fallbackModel))
+		if errFallback == nil {
+			reportServedModel(ctx, fallbackModel)
+			return fallbackResult, nil
+		}
+		err = errFallback
+	}
+	var zero T
+	return zero, err
+}
+
+type modelAccessContextKey struct{}
+
+// ModelAccessFunc reports whether a request may use model served by provider.
+type ModelAccessFunc func(model, provider string) bool
+
+// WithModelAccess returns a context whose requests only fall back to models and providers
+// allowed by access. Handlers use it to apply per-client restrictions to model fallbacks.
+func WithModelAccess(ctx context.Context, access ModelAccessFunc) context.Context {
+	if access == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, modelAccessContextKey{}, access)
+}
+
+// filterModelAccess drops providers the context's ModelAccessFunc does not allow for model.
+func filterModelAccess(ctx context.Context, model string, providers []string) []string {
+	access, ok := ctx.Value(modelAccessContextKey{}).(ModelAccessFunc)
+	if !ok || access == nil {
+		return providers
+	}
+	out := make([]string, 0, len(providers))
+	for _, provider := range providers {
+		if access(model, provider) {
+			out = append(out, provider)
+		}
+	}
+	return out
+}
+
+// modelFallbackChain returns the configured fallback models for model.
+// A thinking suffix on the requested model is carried over to fallbacks that do not declare one.
+func (m *Manager) modelFallbackChain(model string) []string {
+	if m == nil {
+		return nil
+	}
+	cfg, _ := m.runtimeConfig.Load().(*internalconfig.Config)
+	if cfg == nil || len(cfg.ModelFallbacks) == 0 {
+		return nil
+	}
+	parsed := thinking.ParseSuffix(strings.TrimSpace(model))
+	chain := cfg.ModelFallbacks[strings.ToLower(parsed.ModelName)]
+	if len(chain) == 0 {
+		return nil
+	}
+	out := make([]string, 0, len(chain))
+	for _, entry := range chain {
+		if parsed.HasSuffix && !thinking.ParseSuffix(entry).HasSuffix {
+			entry = entry + "(" + parsed.RawSuffix + ")"
+		}
+		out = append(out, entry)
+	}
+	return out
+}
+
+// isModelFallbackError reports whether err indicates the requested model is unavailable
+// (exhausted or cooling credentials, upstream overload) rather than a problem with the request.
+func isModelFallbackError(err error) bool {
+	if err == nil || isRequestInvalidError(err) {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	status := statusCodeFromError(err)
+	if status == 0 {
+		var authErr *Error
+		if errors.As(err, &authErr) && authErr != nil {
+			switch authErr.Code {
+			case "auth_not_found", "auth_unavailable":
+				return true
+			}
+		}
+		return false
+	}
+	switch status {
+	case http.StatusUnauthorized, http.StatusPaymentRequired, http.StatusForbidden,
+		http.StatusNotFound, http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return true
+	default:
+		return status >= http.StatusInternalServerError
+	}
+}
+
+// withRequestedModelMetadata returns opts with the requested model metadata replaced by model.
+func withRequestedModelMetadata(opts cliproxyexecutor.Options, model string) cliproxyexecutor.Options {
+	meta := make(map[string]any, len(opts.Metadata)+1)
+	for k, v := range opts.Metadata {
+		meta[k] = v
+	}
+	meta[cliproxyexecutor.RequestedModelMetadataKey] = model
+	opts.Metadata = meta
+	return opts
+}
+
+type servedModelContextKey struct{}
+
+// WithServedModelReporter returns a context whose requests call report with the model that
+// served them, which differs from the requested model after a model fallback.
+func WithServedModelReporter(ctx context.Context, report func(model string)) context.Context {
+	if report == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, servedModelContextKey{}, report)
+}
+
+func reportServedModel(ctx context.Context, model string) {
+	if strings.TrimSpace(model) == "" {
+		return
+	}
+	if report, ok := ctx.Value(servedModelContextKey{}).(func(string)); ok && report != nil {
+		report(model)
+	}
+}
@@ -0,0 +1,134 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+type fallbackTestExecutor struct {
+	provider string
+	err      error
+	models   []string
+}
+
+func (e *fallbackTestExecutor) Identifier() string { return e.provider }
+
+func (e *fallbackTestExecutor) Execute(_ context.Context, _ *Auth, req cliproxyexecutor.Request, _ cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	e.models = append(e.models, req.Model)
+	if e.err != nil {
+		return cliproxyexecutor.Response{}, e.err
+	}
+	return cliproxyexecutor.Response{Payload: []byte(e.provider)}, nil
+}
+
+func (e *fallbackTestExecutor) ExecuteStream(context.Context, *Auth, cliproxyexecutor.Request, cliproxyexecutor.Options) (<-chan cliproxyexecutor.StreamChunk, error) {
+	return nil, e.err
+}
+
+func (e *fallbackTestExecutor) Refresh(_ context.Context, auth *Auth) (*Auth, error) {
+	return auth, nil
+}
+
+func (e *fallbackTestExecutor) CountTokens(context.Context, *Auth, cliproxyexecutor.Request, cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	return cliproxyexecutor.Response{}, e.err
+}
+
+func (e *fallbackTestExecutor) HttpRequest(context.Context, *Auth, *http.Request) (*http.Response, error) {
+	return nil, e.err
+}
+
+func registerFallbackTestAuth(t *testing.T, m *Manager, id, provider, model string) {
+	t.Helper()
+	registry.GetGlobalRegistry().RegisterClient(id, provider, []*registry.ModelInfo{{ID: model}})
+	t.Cleanup(func() { registry.GetGlobalRegistry().UnregisterClient(id) })
+	if _, err := m.Register(context.Background(), &Auth{ID: id, Provider: provider}); err != nil {
+		t.Fatalf("register auth: %v", err)
+	}
+}
+
+func TestManagerExecute_FallsBackToNextModel(t *testing.T) {
+	m := NewManager(nil, nil, nil)
+	m.SetConfig(&internalconfig.Config{ModelFallbacks: map[string][]string{
+		"fallback-primary-model": {"fallback-secondary-model"},
+	}})
+	primary := &fallbackTestExecutor{provider: "fallback-primary", err: &Error{HTTPStatus: http.StatusTooManyRequests, Message: "quota"}}
+	secondary := &fallbackTestExecutor{provider: "fallback-secondary"}
+	m.RegisterExecutor(primary)
+	m.RegisterExecutor(secondary)
+	registerFallbackTestAuth(t, m, "fallback-auth-primary", "fallback-primary", "fallback-primary-model")
+	registerFallbackTestAuth(t, m, "fallback-auth-secondary", "fallback-secondary", "fallback-secondary-model")
+
+	resp, err := m.Execute(context.Background(), []string{"fallback-primary"}, cliproxyexecutor.Request{Model: "fallback-primary-model"}, cliproxyexecutor.Options{})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if string(resp.Payload) != "fallback-secondary" {
+		t.Fatalf("Execute() payload = %q, want %q", resp.Payload, "fallback-secondary")
+	}
+	if len(secondary.models) != 1 || secondary.models[0] != "fallback-secondary-model" {
+		t.Fatalf("secondary models = %v, want [fallback-secondary-model]", secondary.models)
+	}
+}
+
+func TestManagerExecute_DoesNotFallBackOnInvalidRequest(t *testing.T) {
+	m := NewManager(nil, nil, nil)
+	m.SetConfig(&internalconfig.Config{ModelFallbacks: map[string][]string{
+		"invalid-primary-model": {"invalid-secondary-model"},
+	}})
+	primary := &fallbackTestExecutor{provider: "invalid-primary", err: &Error{HTTPStatus: http.StatusBadRequest, Message: "invalid_request_error"}}
+	secondary := &fallbackTestExecutor{provider: "invalid-secondary"}
+	m.RegisterExecutor(primary)
+	m.RegisterExecutor(secondary)
+	registerFallbackTestAuth(t, m, "invalid-auth-primary", "invalid-primary", "invalid-primary-model")
+	registerFallbackTestAuth(t, m, "invalid-auth-secondary", "invalid-secondary", "invalid-secondary-model")
+
+	if _, err := m.Execute(context.Background(), []string{"invalid-primary"}, cliproxyexecutor.Request{Model: "invalid-primary-model"}, cliproxyexecutor.Options{}); err == nil {
+		t.Fatal("Execute() error = nil, want invalid request error")
+	}
+	if len(secondary.models) != 0 {
+		t.Fatalf("secondary models = %v, want none", secondary.models)
+	}
+}
+
+func TestManagerModelFallbackChain_CarriesThinkingSuffix(t *testing.T) {
+	m := NewManager(nil, nil, nil)
+	m.SetConfig(&internalconfig.Config{ModelFallbacks: map[string][]string{
+		"claude-opus-4-5": {"gemini-claude-opus-4-5-thinking", "gpt-5(low)"},
+	}})
+
+	got := m.modelFallbackChain("Claude-Opus-4-5(high)")
+	want := []string{"gemini-claude-opus-4-5-thinking(high)", "gpt-5(low)"}
+	if len(got) != len(want) {
+		t.Fatalf("modelFallbackChain() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("modelFallbackChain()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestManagerExecute_ReportsServedModel(t *testing.T) {
+	m := NewManager(nil, nil, nil)
+	m.SetConfig(&internalconfig.Config{ModelFallbacks: map[string][]string{
+		"served-primary-model": {"served-secondary-model"},
+	}})
+	m.RegisterExecutor(&fallbackTestExecutor{provider: "served-primary", err: &Error{HTTPStatus: http.StatusTooManyRequests, Message: "quota"}})
+	m.RegisterExecutor(&fallbackTestExecutor{provider: "served-secondary"})
+	registerFallbackTestAuth(t, m, "served-auth-primary", "served-primary", "served-primary-model")
+	registerFallbackTestAuth(t, m, "served-auth-secondary", "served-secondary", "served-secondary-model")
+
+	var served string
+	ctx := WithServedModelReporter(context.Background(), func(model string) { served = model })
+	if _, err := m.Execute(ctx, []string{"served-primary"}, cliproxyexecutor.Request{Model: "served-primary-model"}, cliproxyexecutor.Options{}); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if served != "served-secondary-model" {
+		t.Fatalf("served model = %q, want %q", served, "served-secondary-model")
+	}
+}
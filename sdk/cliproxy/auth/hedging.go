@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/thinking"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+)
+
+// hedgeDelay returns how long a request for model may wait for its first response before
+// it is hedged, or zero when no hedging rule matches.
+func (m *Manager) hedgeDelay(model string) time.Duration {
+	cfg, _ := m.runtimeConfig.Load().(*internalconfig.Config)
+	if cfg == nil || len(cfg.Hedging) == 0 {
+		return 0
+	}
+	name := strings.ToLower(strings.TrimSpace(thinking.ParseSuffix(model).ModelName))
+	if name == "" {
+		return 0
+	}
+	for _, rule := range cfg.Hedging {
+		for _, pattern := range rule.Models {
+			if internalconfig.MatchWildcard(pattern, name) {
+				return time.Duration(rule.DelayMs) * time.Millisecond
+			}
+		}
+	}
+	return 0
+}
+
+type hedgeOutcome struct {
+	resp cliproxyexecutor.Response
+	err  error
+}
+
+// executeMixedHedged runs the request on auth and, if no response headers arrived within
+// delay, sends the same request to the next available auth. The first successful response
+// wins and the other attempt is cancelled. Both attempts are reported as hedged in usage.
+// When every launched attempt fails the last error is returned so the caller can continue
+// with the remaining auths.
+func (m *Manager) executeMixedHedged(ctx context.Context, providers []string, routeModel string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, tried map[string]struct{}, auth *Auth, executor ProviderExecutor, provider string, delay time.Duration) (cliproxyexecutor.Response, error) {
+	hedged := &atomic.Bool{}
+	outcomes := make(chan hedgeOutcome, 2)
+	var cancels []context.CancelFunc
+	defer func() {
+		for _, cancel := range cancels {
+			cancel()
+		}
+	}()
+	launch := func(attemptAuth *Auth, attemptExecutor ProviderExecutor, attemptProvider string) *usage.Timing {
+		attemptCtx, cancel := context.WithCancel(usage.WithHedgeFlag(ctx, hedged))
+		cancels = append(cancels, cancel)
+		attemptCtx = usage.WithTiming(attemptCtx)
+		go func() {
+			resp, err := m.executeMixedAttempt(attemptCtx, attemptAuth, attemptExecutor, attemptProvider, routeModel, req, opts)
+			outcomes <- hedgeOutcome{resp: resp, err: err}
+		}()
+		return usage.TimingFromContext(attemptCtx)
+	}
+
+	timing := launch(auth, executor, provider)
+	pending := 1
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	var lastErr error
+	for pending > 0 {
+		select {
+		case <-timer.C:
+			if timing.Latency() > 0 {
+				// Upstream answered in time and the body is on its way.
+				continue
+			}
+			hedgeAuth, hedgeExecutor, hedgeProvider, ok := m.pickHedge(ctx, providers, routeModel, opts, tried)
+			if !ok {
+				continue
+			}
+			hedged.Store(true)
+			logEntryWithRequestID(ctx).Debugf("hedging %s after %s: %s -> %s", routeModel, delay, auth.ID, hedgeAuth.ID)
+			launch(hedgeAuth, hedgeExecutor, hedgeProvider)
+			pending++
+		case outcome := <-outcomes:
+			pending--
+			if outcome.err == nil {
+				return outcome.resp, nil
+			}
+			lastErr = outcome.err
+			if ctx.Err() != nil || isRequestInvalidError(outcome.err) {
+				return cliproxyexecutor.Response{}, outcome.err
+			}
+		}
+	}
+	return cliproxyexecutor.Response{}, lastErr
+}
+
+// pickHedge selects the auth for a hedge attempt and marks it tried.
+func (m *Manager) pickHedge(ctx context.Context, providers []string, routeModel string, opts cliproxyexecutor.Options, tried map[string]struct{}) (*Auth, ProviderExecutor, string, bool) {
+	hedgeAuth, hedgeExecutor, hedgeProvider, errPick := m.pickNextMixed(ctx, providers, routeModel, opts, tried)
+	if errPick != nil {
+		logEntryWithRequestID(ctx).Debugf("hedge for %s skipped: %v", routeModel, errPick)
+		return nil, nil, "", false
+	}
+	tried[hedgeAuth.ID] = struct{}{}
+	return hedgeAuth, hedgeExecutor, hedgeProvider, true
+}
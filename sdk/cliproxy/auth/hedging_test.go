@@ -0,0 +1,239 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+)
+
+type hedgeTestExecutor struct {
+	provider string
+	delays   map[string]time.Duration
+	// markResponses reports response headers right away, before the delay.
+	markResponses bool
+
+	mu        sync.Mutex
+	cancelled []string
+	hedged    map[string]bool
+}
+
+func (e *hedgeTestExecutor) Identifier() string { return e.provider }
+
+func (e *hedgeTestExecutor) Execute(ctx context.Context, auth *Auth, _ cliproxyexecutor.Request, _ cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	if e.markResponses {
+		usage.TimingFromContext(ctx).MarkResponse(http.StatusOK)
+	}
+	select {
+	case <-time.After(e.delays[auth.ID]):
+	case <-ctx.Done():
+		e.mu.Lock()
+		e.cancelled = append(e.cancelled, auth.ID)
+		e.hedged[auth.ID] = usage.HedgedFromContext(ctx)
+		e.mu.Unlock()
+		return cliproxyexecutor.Response{}, ctx.Err()
+	}
+	e.mu.Lock()
+	e.hedged[auth.ID] = usage.HedgedFromContext(ctx)
+	e.mu.Unlock()
+	return cliproxyexecutor.Response{Payload: []byte(auth.ID)}, nil
+}
+
+func (e *hedgeTestExecutor) ExecuteStream(ctx context.Context, auth *Auth, _ cliproxyexecutor.Request, _ cliproxyexecutor.Options) (<-chan cliproxyexecutor.StreamChunk, error) {
+	e.mu.Lock()
+	e.hedged[auth.ID] = usage.HedgedFromContext(ctx)
+	e.mu.Unlock()
+	out := make(chan cliproxyexecutor.StreamChunk)
+	go func() {
+		defer close(out)
+		select {
+		case <-time.After(e.delays[auth.ID]):
+		case <-ctx.Done():
+			e.mu.Lock()
+			e.cancelled = append(e.cancelled, auth.ID)
+			e.hedged[auth.ID] = usage.HedgedFromContext(ctx)
+			e.mu.Unlock()
+			out <- cliproxyexecutor.StreamChunk{Err: ctx.Err()}
+			return
+		}
+		for i := 1; i <= 2; i++ {
+			select {
+			case out <- cliproxyexecutor.StreamChunk{Payload: []byte(fmt.Sprintf("%s:%d", auth.ID, i))}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+func (e *hedgeTestExecutor) Refresh(_ context.Context, auth *Auth) (*Auth, error) {
+	return auth, nil
+}
+
+func (e *hedgeTestExecutor) CountTokens(context.Context, *Auth, cliproxyexecutor.Request, cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	return cliproxyexecutor.Response{}, &Error{Message: "not implemented"}
+}
+
+func (e *hedgeTestExecutor) HttpRequest(context.Context, *Auth, *http.Request) (*http.Response, error) {
+	return nil, &Error{Message: "not implemented"}
+}
+
+func TestManagerExecute_HedgesSlowRequest(t *testing.T) {
+	m := NewManager(nil, &FillFirstSelector{}, nil)
+	m.SetConfig(&internalconfig.Config{Hedging: []internalconfig.HedgingRule{{Models: []string{"hedge-*"}, DelayMs: 20}}})
+	executor := &hedgeTestExecutor{
+		provider: "hedge-test",
+		delays:   map[string]time.Duration{"hedge-a": 5 * time.Second, "hedge-b": 0},
+		hedged:   map[string]bool{},
+	}
+	m.RegisterExecutor(executor)
+	registerFallbackTestAuth(t, m, "hedge-a", "hedge-test", "hedge-model")
+	registerFallbackTestAuth(t, m, "hedge-b", "hedge-test", "hedge-model")
+
+	started := time.Now()
+	resp, err := m.Execute(context.Background(), []string{"hedge-test"}, cliproxyexecutor.Request{Model: "hedge-model"}, cliproxyexecutor.Options{})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if string(resp.Payload) != "hedge-b" {
+		t.Fatalf("Execute() payload = %q, want hedge-b", resp.Payload)
+	}
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Fatalf("Execute() took %s, want hedge to win quickly", elapsed)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for m.InFlight("hedge-a") != 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	executor.mu.Lock()
+	defer executor.mu.Unlock()
+	if len(executor.cancelled) != 1 || executor.cancelled[0] != "hedge-a" {
+		t.Fatalf("cancelled = %v, want slow attempt cancelled", executor.cancelled)
+	}
+	if !executor.hedged["hedge-a"] || !executor.hedged["hedge-b"] {
+		t.Fatalf("hedged flags = %v, want both attempts marked", executor.hedged)
+	}
+	if auth, _ := m.GetByID("hedge-a"); auth.Status == StatusError {
+		t.Fatal("cancelled hedge attempt marked the auth as failed")
+	}
+}
+
+func TestManagerExecute_NoHedgeForUnmatchedModel(t *testing.T) {
+	m := NewManager(nil, &FillFirstSelector{}, nil)
+	m.SetConfig(&internalconfig.Config{Hedging: []internalconfig.HedgingRule{{Models: []string{"other-*"}, DelayMs: 1}}})
+	executor := &hedgeTestExecutor{
+		provider: "nohedge-test",
+		delays:   map[string]time.Duration{"nohedge-a": 50 * time.Millisecond, "nohedge-b": 0},
+		hedged:   map[string]bool{},
+	}
+	m.RegisterExecutor(executor)
+	registerFallbackTestAuth(t, m, "nohedge-a", "nohedge-test", "nohedge-model")
+	registerFallbackTestAuth(t, m, "nohedge-b", "nohedge-test", "nohedge-model")
+
+	resp, err := m.Execute(context.Background(), []string{"nohedge-test"}, cliproxyexecutor.Request{Model: "nohedge-model"}, cliproxyexecutor.Options{})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if string(resp.Payload) != "nohedge-a" {
+		t.Fatalf("Execute() payload = %q, want nohedge-a", resp.Payload)
+	}
+	if executor.hedged["nohedge-a"] {
+		t.Fatal("unmatched model was hedged")
+	}
+}
+
+func TestManagerHedgeDelay(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		pattern string
+		model   string
+		want    bool
+	}{
+		{"gpt-4o-mini", "gpt-4o-mini", true},
+		{"gpt-*", "gpt-5", true},
+		{"*-flash", "gemini-2.5-flash", true},
+		{"gemini-*-flash", "gemini-2.5-flash-lite", false},
+		{"*", "anything", true},
+		{"a*a", "a", false},
+	}
+	for _, tc := range cases {
+		m := NewManager(nil, nil, nil)
+		m.SetConfig(&internalconfig.Config{Hedging: []internalconfig.HedgingRule{{Models: []string{tc.pattern}, DelayMs: 10}}})
+		if got := m.hedgeDelay(tc.model) > 0; got != tc.want {
+			t.Errorf("hedgeDelay(%q) with pattern %q hedged = %v, want %v", tc.model, tc.pattern, got, tc.want)
+		}
+	}
+}
+
+func TestManagerExecute_NoHedgeAfterResponseHeaders(t *testing.T) {
+	m := NewManager(nil, &FillFirstSelector{}, nil)
+	m.SetConfig(&internalconfig.Config{Hedging: []internalconfig.HedgingRule{{Models: []string{"headers-*"}, DelayMs: 10}}})
+	executor := &hedgeTestExecutor{
+		provider:      "headers-test",
+		delays:        map[string]time.Duration{"headers-a": 100 * time.Millisecond, "headers-b": 0},
+		hedged:        map[string]bool{},
+		markResponses: true,
+	}
+	m.RegisterExecutor(executor)
+	registerFallbackTestAuth(t, m, "headers-a", "headers-test", "headers-model")
+	registerFallbackTestAuth(t, m, "headers-b", "headers-test", "headers-model")
+
+	resp, err := m.Execute(context.Background(), []string{"headers-test"}, cliproxyexecutor.Request{Model: "headers-model"}, cliproxyexecutor.Options{})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if string(resp.Payload) != "headers-a" {
+		t.Fatalf("Execute() payload = %q, want headers-a", resp.Payload)
+	}
+	executor.mu.Lock()
+	defer executor.mu.Unlock()
+	if _, ok := executor.hedged["headers-b"]; ok || executor.hedged["headers-a"] {
+		t.Fatalf("hedged flags = %v, want no hedge once headers arrived", executor.hedged)
+	}
+}
+
+func TestManagerExecuteStream_DoesNotHedge(t *testing.T) {
+	m := NewManager(nil, &FillFirstSelector{}, nil)
+	m.SetConfig(&internalconfig.Config{Hedging: []internalconfig.HedgingRule{{Models: []string{"stream-*"}, DelayMs: 10}}})
+	executor := &hedgeTestExecutor{
+		provider: "stream-hedge-test",
+		delays:   map[string]time.Duration{"stream-a": 100 * time.Millisecond, "stream-b": 0},
+		hedged:   map[string]bool{},
+	}
+	m.RegisterExecutor(executor)
+	registerFallbackTestAuth(t, m, "stream-a", "stream-hedge-test", "stream-model")
+	registerFallbackTestAuth(t, m, "stream-b", "stream-hedge-test", "stream-model")
+
+	chunks, err := m.ExecuteStream(context.Background(), []string{"stream-hedge-test"}, cliproxyexecutor.Request{Model: "stream-model"}, cliproxyexecutor.Options{})
+	if err != nil {
+		t.Fatalf("ExecuteStream() error = %v", err)
+	}
+	var payload []byte
+	for chunk := range chunks {
+		if chunk.Err != nil {
+			t.Fatalf("stream chunk error = %v", chunk.Err)
+		}
+		payload = append(payload, chunk.Payload...)
+	}
+	if string(payload) != "stream-a:1stream-a:2" {
+		t.Fatalf("stream payload = %q, want chunks from stream-a", payload)
+	}
+
+	executor.mu.Lock()
+	defer executor.mu.Unlock()
+	if _, started := executor.hedged["stream-b"]; started {
+		t.Fatal("stream request was hedged onto stream-b")
+	}
+	if executor.hedged["stream-a"] {
+		t.Fatal("stream attempt marked as hedged")
+	}
+}
@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Attempt describes one upstream execution recorded for an inbound request.
+type Attempt struct {
+	AuthID    string
+	Provider  string
+	Model     string
+	Success   bool
+	Status    int
+	Latency   time.Duration
+	FirstByte time.Duration
+}
+
+// RequestSummary is a point-in-time copy of RequestStats.
+type RequestSummary struct {
+	// Format is the inbound API format (e.g. "openai", "claude").
+	Format string
+	// Model is the requested model after alias and prefix resolution.
+	Model string
+	// Stream reports whether the client asked for a streaming response.
+	Stream bool
+	// Attempts lists every upstream execution in the order results were recorded.
+	Attempts []Attempt
+}
+
+// RequestStats collects the upstream attempts of a single inbound request. Handlers attach
+// it to the request context; the manager appends every result recorded through MarkResult.
+type RequestStats struct {
+	mu      sync.Mutex
+	summary RequestSummary
+}
+
+type requestStatsContextKey struct{}
+
+// NewRequestStats returns empty request stats.
+func NewRequestStats() *RequestStats {
+	return &RequestStats{}
+}
+
+// WithRequestStats returns a context carrying stats.
+func WithRequestStats(ctx context.Context, stats *RequestStats) context.Context {
+	if stats == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, requestStatsContextKey{}, stats)
+}
+
+// RequestStatsFromContext returns the stats attached to ctx, or nil.
+func RequestStatsFromContext(ctx context.Context) *RequestStats {
+	if ctx == nil {
+		return nil
+	}
+	stats, _ := ctx.Value(requestStatsContextKey{}).(*RequestStats)
+	return stats
+}
+
+// SetRequest records the inbound format, resolved model and streaming mode.
+func (s *RequestStats) SetRequest(format, model string, stream bool) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.summary.Format = format
+	s.summary.Model = model
+	s.summary.Stream = stream
+	s.mu.Unlock()
+}
+
+// Summary returns a copy of the collected stats.
+func (s *RequestStats) Summary() RequestSummary {
+	if s == nil {
+		return RequestSummary{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := s.summary
+	out.Attempts = append([]Attempt(nil), s.summary.Attempts...)
+	return out
+}
+
+func (s *RequestStats) record(result Result) {
+	if s == nil {
+		return
+	}
+	attempt := Attempt{
+		AuthID:    result.AuthID,
+		Provider:  result.Provider,
+		Model:     result.Model,
+		Success:   result.Success,
+		Latency:   result.Latency,
+		FirstByte: result.FirstByteLatency,
+	}
+	if result.Error != nil {
+		attempt.Status = result.Error.HTTPStatus
+	}
+	s.mu.Lock()
+	s.summary.Attempts = append(s.summary.Attempts, attempt)
+	s.mu.Unlock()
+}
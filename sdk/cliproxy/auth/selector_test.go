@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"sync"
 	"testing"
@@ -402,3 +403,112 @@ func TestRoundRobinSelectorPick_CursorKeyCap(t *testing.T) {
 		t.Fatalf("selector.cursors missing key %q", "gemini:m3")
 	}
 }
+
+func TestSessionAffinitySelectorPick_PinsSession(t *testing.T) {
+	t.Parallel()
+
+	selector := NewSessionAffinitySelector(time.Minute, "")
+	auths := []*Auth{{ID: "a"}, {ID: "b"}, {ID: "c"}}
+	opts := cliproxyexecutor.Options{
+		OriginalRequest: []byte(`{"system":"be brief","messages":[{"role":"user","content":"hello"},{"role":"assistant","content":"hi"}]}`),
+	}
+
+	first, err := selector.Pick(context.Background(), "claude", "claude-sonnet-4-5", opts, auths)
+	if err != nil {
+		t.Fatalf("Pick() error = %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		got, errPick := selector.Pick(context.Background(), "claude", "claude-sonnet-4-5", opts, auths)
+		if errPick != nil {
+			t.Fatalf("Pick() #%d error = %v", i, errPick)
+		}
+		if got.ID != first.ID {
+			t.Fatalf("Pick() #%d auth.ID = %q, want pinned %q", i, got.ID, first.ID)
+		}
+	}
+}
+
+func TestSessionAffinitySelectorPick_RepinsWhenPinnedAuthCoolsDown(t *testing.T) {
+	t.Parallel()
+
+	selector := NewSessionAffinitySelector(time.Minute, "")
+	model := "claude-sonnet-4-5"
+	auths := []*Auth{{ID: "a"}, {ID: "b"}}
+	opts := cliproxyexecutor.Options{
+		OriginalRequest: []byte(`{"metadata":{"user_id":"user-1"},"messages":[{"role":"user","content":"hello"}]}`),
+	}
+
+	first, err := selector.Pick(context.Background(), "claude", model, opts, auths)
+	if err != nil {
+		t.Fatalf("Pick() error = %v", err)
+	}
+	first.ModelStates = map[string]*ModelState{
+		model: {Unavailable: true, NextRetryAfter: time.Now().Add(time.Minute)},
+	}
+
+	second, err := selector.Pick(context.Background(), "claude", model, opts, auths)
+	if err != nil {
+		t.Fatalf("Pick() error = %v", err)
+	}
+	if second.ID == first.ID {
+		t.Fatalf("Pick() auth.ID = %q, want a different auth while pinned auth cools down", second.ID)
+	}
+
+	third, err := selector.Pick(context.Background(), "claude", model, opts, auths)
+	if err != nil {
+		t.Fatalf("Pick() error = %v", err)
+	}
+	if third.ID != second.ID {
+		t.Fatalf("Pick() auth.ID = %q, want re-pinned %q", third.ID, second.ID)
+	}
+}
+
+func TestSessionAffinitySelectorPick_EvictsLeastRecentlyUsedWhenFull(t *testing.T) {
+	t.Parallel()
+
+	selector := NewSessionAffinitySelector(time.Hour, "")
+	auths := []*Auth{{ID: "a"}, {ID: "b"}}
+	pick := func(user string) {
+		t.Helper()
+		opts := cliproxyexecutor.Options{OriginalRequest: []byte(`{"metadata":{"user_id":"` + user + `"}}`)}
+		if _, err := selector.Pick(context.Background(), "claude", "m", opts, auths); err != nil {
+			t.Fatalf("Pick() error = %v", err)
+		}
+	}
+	for i := 0; i < sessionAffinityMaxKeys; i++ {
+		pick(fmt.Sprintf("user-%d", i))
+	}
+	pick("user-0") // most recently used again
+	pick("user-new")
+
+	if len(selector.pins) != sessionAffinityMaxKeys {
+		t.Fatalf("pins = %d, want %d", len(selector.pins), sessionAffinityMaxKeys)
+	}
+	if _, ok := selector.pins["claude:m:user:user-0"]; !ok {
+		t.Fatal("recently used pin was evicted")
+	}
+	if _, ok := selector.pins["claude:m:user:user-1"]; ok {
+		t.Fatal("least recently used pin was kept")
+	}
+}
+
+func TestSessionAffinityKey_Sources(t *testing.T) {
+	t.Parallel()
+
+	claude := cliproxyexecutor.Options{OriginalRequest: []byte(`{"metadata":{"user_id":"u-1"},"messages":[{"role":"user","content":"x"}]}`)}
+	if got := sessionAffinityKey(context.Background(), DefaultSessionAffinityHeader, claude); got != "user:u-1" {
+		t.Fatalf("sessionAffinityKey() = %q, want %q", got, "user:u-1")
+	}
+
+	chatA := cliproxyexecutor.Options{OriginalRequest: []byte(`{"messages":[{"role":"system","content":"s"},{"role":"user","content":"q"}]}`)}
+	chatB := cliproxyexecutor.Options{OriginalRequest: []byte(`{"messages":[{"role":"system","content":"s"},{"role":"user","content":"q"},{"role":"assistant","content":"a"},{"role":"user","content":"q2"}]}`)}
+	keyA := sessionAffinityKey(context.Background(), DefaultSessionAffinityHeader, chatA)
+	keyB := sessionAffinityKey(context.Background(), DefaultSessionAffinityHeader, chatB)
+	if keyA == "" || keyA != keyB {
+		t.Fatalf("sessionAffinityKey() = %q and %q, want equal non-empty keys for the same conversation", keyA, keyB)
+	}
+
+	if got := sessionAffinityKey(context.Background(), DefaultSessionAffinityHeader, cliproxyexecutor.Options{}); got != "" {
+		t.Fatalf("sessionAffinityKey() = %q, want empty", got)
+	}
+}
@@ -0,0 +1,199 @@
+package auth
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	"github.com/tidwall/gjson"
+)
+
+const (
+	// DefaultSessionAffinityTTL is how long an idle session stays pinned to a credential.
+	DefaultSessionAffinityTTL = time.Hour
+	// DefaultSessionAffinityHeader is the request header carrying an explicit session key.
+	DefaultSessionAffinityHeader = "X-Session-Id"
+
+	sessionAffinityMaxKeys = 16384
+)
+
+type sessionPin struct {
+	key       string
+	authID    string
+	expiresAt time.Time
+}
+
+// SessionAffinitySelector pins a conversation to one credential so multi-turn sessions
+// reuse upstream prompt caches. The affinity key comes from an explicit request header,
+// the Claude metadata.user_id or OpenAI prompt_cache_key fields, or a hash of the system
+// prompt plus the first user message. When the pinned credential is unavailable the
+// session is re-pinned using round-robin over the remaining candidates.
+type SessionAffinitySelector struct {
+	ttl    time.Duration
+	header string
+
+	mu   sync.Mutex
+	pins map[string]*list.Element
+	// lru orders pins from least to most recently used; every element holds a *sessionPin.
+	lru      *list.List
+	fallback RoundRobinSelector
+}
+
+// NewSessionAffinitySelector constructs a sticky selector.
+// Non-positive ttl and empty header fall back to the defaults.
+func NewSessionAffinitySelector(ttl time.Duration, header string) *SessionAffinitySelector {
+	if ttl <= 0 {
+		ttl = DefaultSessionAffinityTTL
+	}
+	header = strings.TrimSpace(header)
+	if header == "" {
+		header = DefaultSessionAffinityHeader
+	}
+	return &SessionAffinitySelector{
+		ttl:    ttl,
+		header: header,
+		pins:   make(map[string]*list.Element),
+		lru:    list.New(),
+	}
+}
+
+// Pick returns the credential pinned to the request's session, pinning a new one when needed.
+func (s *SessionAffinitySelector) Pick(ctx context.Context, provider, model string, opts cliproxyexecutor.Options, auths []*Auth) (*Auth, error) {
+	now := time.Now()
+	available, err := getAvailableAuths(auths, provider, model, now)
+	if err != nil {
+		return nil, err
+	}
+	sessionKey := sessionAffinityKey(ctx, s.header, opts)
+	if sessionKey == "" {
+		return s.fallback.Pick(ctx, provider, model, opts, available)
+	}
+	pinKey := provider + ":" + canonicalModelKey(model) + ":" + sessionKey
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.pins == nil {
+		s.pins = make(map[string]*list.Element)
+		s.lru = list.New()
+	}
+	if elem, ok := s.pins[pinKey]; ok {
+		pin := elem.Value.(*sessionPin)
+		if pin.expiresAt.After(now) {
+			for _, candidate := range available {
+				if candidate.ID == pin.authID {
+					s.touchLocked(elem, candidate.ID, now)
+					return candidate, nil
+				}
+			}
+		}
+	}
+	selected, err := s.fallback.Pick(ctx, provider, model, opts, available)
+	if err != nil {
+		return nil, err
+	}
+	if elem, ok := s.pins[pinKey]; ok {
+		s.touchLocked(elem, selected.ID, now)
+		return selected, nil
+	}
+	if len(s.pins) >= sessionAffinityMaxKeys {
+		s.evictLocked(now)
+	}
+	s.pins[pinKey] = s.lru.PushBack(&sessionPin{key: pinKey, authID: selected.ID, expiresAt: now.Add(s.ttl)})
+	return selected, nil
+}
+
+// touchLocked re-pins elem to authID and marks it most recently used.
+func (s *SessionAffinitySelector) touchLocked(elem *list.Element, authID string, now time.Time) {
+	pin := elem.Value.(*sessionPin)
+	pin.authID = authID
+	pin.expiresAt = now.Add(s.ttl)
+	s.lru.MoveToBack(elem)
+}
+
+// evictLocked drops expired pins, then the least recently used ones until there is room
+// for a new pin. Pins share one TTL, so expired pins are always at the front.
+func (s *SessionAffinitySelector) evictLocked(now time.Time) {
+	for elem := s.lru.Front(); elem != nil; elem = s.lru.Front() {
+		pin := elem.Value.(*sessionPin)
+		if pin.expiresAt.After(now) && len(s.pins) < sessionAffinityMaxKeys {
+			return
+		}
+		s.lru.Remove(elem)
+		delete(s.pins, pin.key)
+	}
+}
+
+// sessionAffinityKey derives a stable session identifier for the request, or "" when none applies.
+func sessionAffinityKey(ctx context.Context, header string, opts cliproxyexecutor.Options) string {
+	if ctx != nil && header != "" {
+		if getter, ok := ctx.Value("gin").(interface{ GetHeader(string) string }); ok && getter != nil {
+			if value := strings.TrimSpace(getter.GetHeader(header)); value != "" {
+				return "header:" + value
+			}
+		}
+	}
+	payload := opts.OriginalRequest
+	if len(payload) == 0 || !gjson.ValidBytes(payload) {
+		return ""
+	}
+	if userID := strings.TrimSpace(gjson.GetBytes(payload, "metadata.user_id").String()); userID != "" {
+		return "user:" + userID
+	}
+	if cacheKey := strings.TrimSpace(gjson.GetBytes(payload, "prompt_cache_key").String()); cacheKey != "" {
+		return "cache:" + cacheKey
+	}
+	system, firstUser := conversationAnchors(payload)
+	if firstUser == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(system + "\x00" + firstUser))
+	return "prompt:" + hex.EncodeToString(sum[:16])
+}
+
+// conversationAnchors extracts the system prompt and first user message across
+// the Claude, OpenAI chat, OpenAI Responses and Gemini request shapes.
+func conversationAnchors(payload []byte) (system, firstUser string) {
+	root := gjson.ParseBytes(payload)
+	for _, path := range []string{"system", "instructions", "systemInstruction", "system_instruction"} {
+		if value := root.Get(path); value.Exists() {
+			system = value.Raw
+			break
+		}
+	}
+	for _, path := range []string{"messages", "input", "contents"} {
+		list := root.Get(path)
+		if !list.Exists() {
+			continue
+		}
+		if list.Type == gjson.String {
+			firstUser = list.Raw
+			break
+		}
+		list.ForEach(func(_, message gjson.Result) bool {
+			role := message.Get("role").String()
+			switch role {
+			case "system", "developer":
+				if system == "" {
+					system = message.Get("content").Raw
+				}
+			case "user":
+				content := message.Get("content")
+				if !content.Exists() {
+					content = message.Get("parts")
+				}
+				firstUser = content.Raw
+				return false
+			}
+			return true
+		})
+		if firstUser != "" {
+			break
+		}
+	}
+	return system, firstUser
+}
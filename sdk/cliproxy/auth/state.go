@@ -0,0 +1,316 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// StateStore is implemented by stores that persist runtime availability state (cooldowns,
+// quota windows and per-model status) so it survives restarts. The payload is an opaque
+// JSON document owned by the Manager.
+type StateStore interface {
+	// LoadState returns the last saved state document, or nil when none exists.
+	LoadState(ctx context.Context) ([]byte, error)
+	// SaveState replaces the stored state document.
+	SaveState(ctx context.Context, data []byte) error
+}
+
+// StateFlusher is implemented by state stores that defer part of a SaveState, such as a
+// remote push, and can complete it on demand before shutdown.
+type StateFlusher interface {
+	// FlushState completes any deferred state write.
+	FlushState(ctx context.Context) error
+}
+
+// stateSaveDelay batches bursts of result updates into a single state write.
+const stateSaveDelay = 2 * time.Second
+
+const stateDocumentVersion = 1
+
+// persistedAuthState is the runtime availability snapshot stored for one auth.
+type persistedAuthState struct {
+	Status         Status                 `json:"status,omitempty"`
+	StatusMessage  string                 `json:"status_message,omitempty"`
+	Unavailable    bool                   `json:"unavailable,omitempty"`
+	NextRetryAfter time.Time              `json:"next_retry_after,omitempty"`
+	Quota          QuotaState             `json:"quota"`
+	LastError      *Error                 `json:"last_error,omitempty"`
+	ModelStates    map[string]*ModelState `json:"model_states,omitempty"`
+}
+
+type stateDocument struct {
+	Version int                            `json:"version"`
+	SavedAt time.Time                      `json:"saved_at"`
+	Auths   map[string]*persistedAuthState `json:"auths"`
+}
+
+// captureAuthState extracts the availability state of auth that is still in effect at now.
+// It returns nil when nothing would outlive a restart.
+func captureAuthState(auth *Auth, now time.Time) *persistedAuthState {
+	if auth == nil || auth.Disabled {
+		return nil
+	}
+	state := &persistedAuthState{}
+	keep := false
+	if auth.Unavailable && auth.NextRetryAfter.After(now) {
+		state.Status = auth.Status
+		state.StatusMessage = auth.StatusMessage
+		state.Unavailable = true
+		state.NextRetryAfter = auth.NextRetryAfter
+		state.Quota = auth.Quota
+		state.LastError = cloneError(auth.LastError)
+		keep = true
+	}
+	for model, modelState := range auth.ModelStates {
+		if !modelStateActive(modelState, now) {
+			continue
+		}
+		if state.ModelStates == nil {
+			state.ModelStates = make(map[string]*ModelState)
+		}
+		copied := *modelState
+		copied.LastError = cloneError(modelState.LastError)
+		state.ModelStates[model] = &copied
+		keep = true
+	}
+	if !keep {
+		return nil
+	}
+	return state
+}
+
+// modelStateActive reports whether a model state still blocks or throttles the model at now.
+func modelStateActive(state *ModelState, now time.Time) bool {
+	if state == nil || !state.Unavailable {
+		return false
+	}
+	return state.NextRetryAfter.After(now) || state.Quota.NextRecoverAt.After(now)
+}
+
+// availability holds the persisted availability fields of an auth or model state.
+type availability struct {
+	unavailable    bool
+	nextRetryAfter time.Time
+	quota          QuotaState
+}
+
+func (a availability) equal(other availability) bool {
+	return a.unavailable == other.unavailable &&
+		a.nextRetryAfter.Equal(other.nextRetryAfter) &&
+		a.quota.Exceeded == other.quota.Exceeded &&
+		a.quota.Reason == other.quota.Reason &&
+		a.quota.NextRecoverAt.Equal(other.quota.NextRecoverAt) &&
+		a.quota.BackoffLevel == other.quota.BackoffLevel
+}
+
+// availabilitySnapshot records the availability fields of auth and its models, so
+// MarkResult can skip the state save when a result leaves them untouched.
+type availabilitySnapshot struct {
+	auth   availability
+	models map[string]availability
+}
+
+func snapshotAvailability(auth *Auth) availabilitySnapshot {
+	snapshot := availabilitySnapshot{
+		auth: availability{unavailable: auth.Unavailable, nextRetryAfter: auth.NextRetryAfter, quota: auth.Quota},
+	}
+	for model, state := range auth.ModelStates {
+		if state == nil {
+			continue
+		}
+		if snapshot.models == nil {
+			snapshot.models = make(map[string]availability, len(auth.ModelStates))
+		}
+		snapshot.models[model] = availability{unavailable: state.Unavailable, nextRetryAfter: state.NextRetryAfter, quota: state.Quota}
+	}
+	return snapshot
+}
+
+func (s availabilitySnapshot) equal(other availabilitySnapshot) bool {
+	if !s.auth.equal(other.auth) {
+		return false
+	}
+	// A missing model state is equivalent to a clear one, so creating a fresh state
+	// for a successful model does not count as a change.
+	for model, state := range s.models {
+		if !state.equal(other.models[model]) {
+			return false
+		}
+	}
+	for model, state := range other.models {
+		if _, ok := s.models[model]; !ok && !state.equal(availability{}) {
+			return false
+		}
+	}
+	return true
+}
+
+// applyAuthState restores persisted availability state onto auth, skipping entries whose
+// recovery time has already passed.
+func applyAuthState(auth *Auth, state *persistedAuthState, now time.Time) {
+	if auth == nil || state == nil || auth.Disabled {
+		return
+	}
+	if state.Unavailable && state.NextRetryAfter.After(now) {
+		auth.Status = state.Status
+		auth.StatusMessage = state.StatusMessage
+		auth.Unavailable = true
+		auth.NextRetryAfter = state.NextRetryAfter
+		auth.Quota = state.Quota
+		auth.LastError = cloneError(state.LastError)
+	}
+	restored := false
+	for model, modelState := range state.ModelStates {
+		if !modelStateActive(modelState, now) {
+			continue
+		}
+		if auth.ModelStates == nil {
+			auth.ModelStates = make(map[string]*ModelState)
+		}
+		copied := *modelState
+		auth.ModelStates[model] = &copied
+		restored = true
+	}
+	if restored {
+		if auth.Status == "" || auth.Status == StatusActive {
+			auth.Status = StatusError
+		}
+		updateAggregatedAvailability(auth, now)
+	}
+}
+
+// hasRuntimeState reports whether auth already carries availability state of its own.
+func hasRuntimeState(auth *Auth) bool {
+	return auth != nil && (len(auth.ModelStates) > 0 || auth.Unavailable)
+}
+
+func decodeStateDocument(data []byte) (map[string]*persistedAuthState, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var doc stateDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("decode auth state: %w", err)
+	}
+	if doc.Version != stateDocumentVersion {
+		return nil, fmt.Errorf("decode auth state: unsupported version %d", doc.Version)
+	}
+	return doc.Auths, nil
+}
+
+// loadPersistedStateLocked reads the saved state document and applies it to the loaded auths.
+// Entries for auths that are not loaded yet are kept until the auth is registered.
+// Callers must hold m.mu.
+func (m *Manager) loadPersistedStateLocked(ctx context.Context) {
+	stateStore, ok := m.store.(StateStore)
+	if !ok {
+		return
+	}
+	data, err := stateStore.LoadState(ctx)
+	if err != nil {
+		logEntryWithRequestID(ctx).Warnf("failed to load auth state: %v", err)
+		return
+	}
+	states, err := decodeStateDocument(data)
+	if err != nil {
+		logEntryWithRequestID(ctx).Warnf("failed to load auth state: %v", err)
+		return
+	}
+	now := time.Now()
+	m.pendingStates = make(map[string]*persistedAuthState, len(states))
+	for id, state := range states {
+		if state == nil {
+			continue
+		}
+		if auth, exists := m.auths[id]; exists && auth != nil {
+			applyAuthState(auth, state, now)
+		}
+		m.pendingStates[id] = state
+	}
+}
+
+// restorePendingStateLocked applies state loaded at startup to an auth registered later,
+// for example by the config or file watcher. Callers must hold m.mu.
+func (m *Manager) restorePendingStateLocked(auth *Auth) {
+	if len(m.pendingStates) == 0 || auth == nil {
+		return
+	}
+	state, ok := m.pendingStates[auth.ID]
+	if !ok {
+		return
+	}
+	delete(m.pendingStates, auth.ID)
+	if !hasRuntimeState(auth) {
+		applyAuthState(auth, state, time.Now())
+	}
+}
+
+// scheduleStateSave writes the availability state of all auths shortly after a change,
+// coalescing bursts of results into a single write.
+func (m *Manager) scheduleStateSave() {
+	if _, ok := m.store.(StateStore); !ok {
+		return
+	}
+	m.stateSaveMu.Lock()
+	defer m.stateSaveMu.Unlock()
+	if m.stateSaveTimer != nil {
+		return
+	}
+	m.stateSaveTimer = time.AfterFunc(stateSaveDelay, func() {
+		m.stateSaveMu.Lock()
+		m.stateSaveTimer = nil
+		m.stateSaveMu.Unlock()
+		if err := m.SaveState(context.Background()); err != nil {
+			log.Warnf("failed to save auth state: %v", err)
+		}
+	})
+}
+
+// SaveState persists the current availability state of all auths when the store supports it.
+func (m *Manager) SaveState(ctx context.Context) error {
+	if m == nil {
+		return nil
+	}
+	stateStore, ok := m.store.(StateStore)
+	if !ok {
+		return nil
+	}
+	now := time.Now()
+	doc := stateDocument{Version: stateDocumentVersion, SavedAt: now, Auths: make(map[string]*persistedAuthState)}
+	m.mu.RLock()
+	for id, auth := range m.auths {
+		if state := captureAuthState(auth, now); state != nil {
+			doc.Auths[id] = state
+		}
+	}
+	m.mu.RUnlock()
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("encode auth state: %w", err)
+	}
+	m.stateWriteMu.Lock()
+	defer m.stateWriteMu.Unlock()
+	return stateStore.SaveState(ctx, data)
+}
+
+// FlushState saves the current availability state and makes the store complete any write
+// it deferred. Call it before shutdown so the latest state is not lost.
+func (m *Manager) FlushState(ctx context.Context) error {
+	if m == nil {
+		return nil
+	}
+	if err := m.SaveState(ctx); err != nil {
+		return err
+	}
+	flusher, ok := m.store.(StateFlusher)
+	if !ok {
+		return nil
+	}
+	m.stateWriteMu.Lock()
+	defer m.stateWriteMu.Unlock()
+	return flusher.FlushState(ctx)
+}
@@ -0,0 +1,178 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+)
+
+type memoryStateStore struct {
+	mu      sync.Mutex
+	auths   map[string]*Auth
+	state   []byte
+	flushes int
+}
+
+func newMemoryStateStore() *memoryStateStore {
+	return &memoryStateStore{auths: make(map[string]*Auth)}
+}
+
+func (s *memoryStateStore) List(context.Context) ([]*Auth, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]*Auth, 0, len(s.auths))
+	for _, auth := range s.auths {
+		out = append(out, auth.Clone())
+	}
+	return out, nil
+}
+
+func (s *memoryStateStore) Save(_ context.Context, auth *Auth) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.auths[auth.ID] = &Auth{ID: auth.ID, Provider: auth.Provider}
+	return auth.ID, nil
+}
+
+func (s *memoryStateStore) Delete(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.auths, id)
+	return nil
+}
+
+func (s *memoryStateStore) LoadState(context.Context) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state, nil
+}
+
+func (s *memoryStateStore) SaveState(_ context.Context, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state = append([]byte(nil), data...)
+	return nil
+}
+
+func (s *memoryStateStore) FlushState(context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.flushes++
+	return nil
+}
+
+func TestManagerState_RestoresCooldownOnLoad(t *testing.T) {
+	store := newMemoryStateStore()
+	store.auths["state-auth"] = &Auth{ID: "state-auth", Provider: "state-test"}
+	first := NewManager(store, nil, nil)
+	registerCoolingTestAuth(t, first, "state-auth", "state-test", "state-model", time.Hour)
+	if err := first.SaveState(context.Background()); err != nil {
+		t.Fatalf("SaveState() error = %v", err)
+	}
+
+	second := NewManager(store, nil, nil)
+	if err := second.Load(context.Background()); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	auth, ok := second.GetByID("state-auth")
+	if !ok {
+		t.Fatal("auth not loaded")
+	}
+	state := auth.ModelStates["state-model"]
+	if state == nil || !state.Unavailable || !state.Quota.Exceeded {
+		t.Fatalf("model state = %+v, want restored cooldown", state)
+	}
+	if time.Until(state.NextRetryAfter) < 50*time.Minute {
+		t.Fatalf("NextRetryAfter = %s, want about an hour from now", state.NextRetryAfter)
+	}
+}
+
+func TestManagerState_DropsExpiredAndRestoresOnRegister(t *testing.T) {
+	now := time.Now()
+	doc := stateDocument{Version: stateDocumentVersion, SavedAt: now, Auths: map[string]*persistedAuthState{
+		"expired-auth": {ModelStates: map[string]*ModelState{"m": {
+			Status: StatusError, Unavailable: true, NextRetryAfter: now.Add(-time.Minute),
+		}}},
+		"late-auth": {
+			Status: StatusError, Unavailable: true, NextRetryAfter: now.Add(time.Hour),
+		},
+	}}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatalf("marshal state: %v", err)
+	}
+	store := newMemoryStateStore()
+	store.auths["expired-auth"] = &Auth{ID: "expired-auth", Provider: "state-test"}
+	store.state = data
+
+	m := NewManager(store, nil, nil)
+	if err = m.Load(context.Background()); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if auth, _ := m.GetByID("expired-auth"); len(auth.ModelStates) != 0 || auth.Unavailable {
+		t.Fatalf("expired state restored: %+v", auth.ModelStates)
+	}
+
+	if _, err = m.Register(context.Background(), &Auth{ID: "late-auth", Provider: "state-test"}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	auth, _ := m.GetByID("late-auth")
+	if !auth.Unavailable || auth.Status != StatusError {
+		t.Fatalf("late auth = unavailable %v status %q, want restored cooldown", auth.Unavailable, auth.Status)
+	}
+}
+
+func TestManagerFlushState_SavesAndFlushesStore(t *testing.T) {
+	store := newMemoryStateStore()
+	m := NewManager(store, nil, nil)
+	registerCoolingTestAuth(t, m, "flush-auth", "state-test", "state-model", time.Hour)
+
+	if err := m.FlushState(context.Background()); err != nil {
+		t.Fatalf("FlushState() error = %v", err)
+	}
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if store.flushes != 1 {
+		t.Fatalf("flushes = %d, want 1", store.flushes)
+	}
+	var doc stateDocument
+	if err := json.Unmarshal(store.state, &doc); err != nil {
+		t.Fatalf("decode state: %v", err)
+	}
+	if _, ok := doc.Auths["flush-auth"]; !ok {
+		t.Fatalf("saved state = %s, want flush-auth", store.state)
+	}
+}
+
+func TestManagerMarkResult_SchedulesSaveOnlyOnAvailabilityChange(t *testing.T) {
+	store := newMemoryStateStore()
+	m := NewManager(store, nil, nil)
+	registerFallbackTestAuth(t, m, "save-auth", "state-test", "state-model")
+	pending := func() bool {
+		m.stateSaveMu.Lock()
+		defer m.stateSaveMu.Unlock()
+		if m.stateSaveTimer == nil {
+			return false
+		}
+		m.stateSaveTimer.Stop()
+		m.stateSaveTimer = nil
+		return true
+	}
+
+	m.MarkResult(context.Background(), Result{AuthID: "save-auth", Provider: "state-test", Model: "state-model", Success: true})
+	if pending() {
+		t.Fatal("successful result on a healthy auth scheduled a state save")
+	}
+
+	m.MarkResult(context.Background(), Result{AuthID: "save-auth", Provider: "state-test", Model: "state-model", Error: &Error{HTTPStatus: 429, Message: "rate limited"}})
+	if !pending() {
+		t.Fatal("rate-limited result did not schedule a state save")
+	}
+
+	m.MarkResult(context.Background(), Result{AuthID: "save-auth", Provider: "state-test", Model: "state-model", Success: true})
+	if !pending() {
+		t.Fatal("recovering result did not schedule a state save")
+	}
+}
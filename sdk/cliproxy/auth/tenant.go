@@ -0,0 +1,40 @@
+package auth
+
+import (
+	"context"
+	"strings"
+)
+
+type credentialPrefixesContextKey struct{}
+
+// WithCredentialPrefixes returns a context whose requests are only served by credentials
+// carrying one of prefixes. Handlers use it to keep clients bound to a tenant's credentials,
+// including on model fallbacks. An empty list leaves ctx unchanged.
+func WithCredentialPrefixes(ctx context.Context, prefixes []string) context.Context {
+	if len(prefixes) == 0 {
+		return ctx
+	}
+	set := make(map[string]struct{}, len(prefixes))
+	for _, prefix := range prefixes {
+		if prefix = strings.Trim(strings.TrimSpace(prefix), "/"); prefix != "" {
+			set[prefix] = struct{}{}
+		}
+	}
+	if len(set) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, credentialPrefixesContextKey{}, set)
+}
+
+// credentialPrefixAllowed reports whether auth may serve a request under ctx's prefix binding.
+func credentialPrefixAllowed(ctx context.Context, auth *Auth) bool {
+	if ctx == nil || auth == nil {
+		return true
+	}
+	set, ok := ctx.Value(credentialPrefixesContextKey{}).(map[string]struct{})
+	if !ok {
+		return true
+	}
+	_, allowed := set[strings.Trim(strings.TrimSpace(auth.Prefix), "/")]
+	return allowed
+}
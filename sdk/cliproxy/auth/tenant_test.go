@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+type tenantTestExecutor struct {
+	fallbackTestExecutor
+}
+
+func (e *tenantTestExecutor) Execute(_ context.Context, auth *Auth, _ cliproxyexecutor.Request, _ cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	return cliproxyexecutor.Response{Payload: []byte(auth.ID)}, nil
+}
+
+func TestManagerExecute_RespectsCredentialPrefixes(t *testing.T) {
+	m := NewManager(nil, nil, nil)
+	m.RegisterExecutor(&tenantTestExecutor{fallbackTestExecutor{provider: "tenant"}})
+	for _, auth := range []*Auth{
+		{ID: "tenant-a", Provider: "tenant", Prefix: "team-a"},
+		{ID: "tenant-b", Provider: "tenant", Prefix: "team-b"},
+		{ID: "tenant-shared", Provider: "tenant"},
+	} {
+		registry.GetGlobalRegistry().RegisterClient(auth.ID, "tenant", []*registry.ModelInfo{{ID: "tenant-model"}})
+		t.Cleanup(func() { registry.GetGlobalRegistry().UnregisterClient(auth.ID) })
+		if _, err := m.Register(context.Background(), auth); err != nil {
+			t.Fatalf("register auth: %v", err)
+		}
+	}
+
+	ctx := WithCredentialPrefixes(context.Background(), []string{"/team-b/"})
+	for i := 0; i < 4; i++ {
+		resp, err := m.Execute(ctx, []string{"tenant"}, cliproxyexecutor.Request{Model: "tenant-model"}, cliproxyexecutor.Options{})
+		if err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+		if string(resp.Payload) != "tenant-b" {
+			t.Fatalf("Execute() served by %s, want tenant-b", resp.Payload)
+		}
+	}
+
+	ctx = WithCredentialPrefixes(context.Background(), []string{"team-c"})
+	if _, err := m.Execute(ctx, []string{"tenant"}, cliproxyexecutor.Request{Model: "tenant-model"}, cliproxyexecutor.Options{}); err == nil {
+		t.Fatal("Execute() with an unknown bound prefix succeeded")
+	}
+}
@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// startAttemptSpan opens a tracing span covering one upstream execution on auth. For
+// streams the span stays open until the upstream channel closes.
+func startAttemptSpan(ctx context.Context, auth *Auth, provider, model string) (context.Context, trace.Span) {
+	return tracing.Tracer().Start(ctx, "cliproxy.attempt", trace.WithAttributes(
+		attribute.String("cliproxy.provider", provider),
+		attribute.String("cliproxy.model", model),
+		attribute.String("cliproxy.auth_index", auth.Index),
+	))
+}
+
+// recordAttemptResult copies the outcome of an attempt onto its span.
+func recordAttemptResult(span trace.Span, result Result) {
+	if !span.IsRecording() {
+		return
+	}
+	if result.FirstByteLatency > 0 {
+		span.SetAttributes(attribute.Int64("cliproxy.first_byte_ms", result.FirstByteLatency.Milliseconds()))
+	}
+	if result.Error != nil {
+		if result.Error.HTTPStatus > 0 {
+			span.SetAttributes(attribute.Int("http.response.status_code", result.Error.HTTPStatus))
+		}
+		span.SetStatus(codes.Error, result.Error.Message)
+	}
+}
+
+// traceRetryWait notes a cooldown wait between retry rounds on the active span.
+func traceRetryWait(ctx context.Context, attempt int, wait time.Duration, err error) {
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return
+	}
+	attrs := []attribute.KeyValue{
+		attribute.Int("cliproxy.retry_round", attempt+1),
+		attribute.Int64("cliproxy.wait_ms", wait.Milliseconds()),
+	}
+	if err != nil {
+		attrs = append(attrs, attribute.String("cliproxy.last_error", err.Error()))
+	}
+	span.AddEvent("cliproxy.retry_wait", trace.WithAttributes(attrs...))
+}
@@ -238,6 +238,33 @@ func (a *Auth) RequestRetryOverride() (int, bool) {
 	return 0, false
 }
 
+// MaxConcurrency returns the maximum number of concurrent in-flight requests allowed
+// for the auth. It reads the "max_concurrency" attribute first and falls back to
+// metadata; 0 means unlimited.
+func (a *Auth) MaxConcurrency() int {
+	if a == nil {
+		return 0
+	}
+	if a.Attributes != nil {
+		if raw := strings.TrimSpace(a.Attributes["max_concurrency"]); raw != "" {
+			if parsed, err := strconv.Atoi(raw); err == nil && parsed > 0 {
+				return parsed
+			}
+		}
+	}
+	if a.Metadata == nil {
+		return 0
+	}
+	for _, key := range []string{"max_concurrency", "max-concurrency"} {
+		if val, ok := a.Metadata[key]; ok {
+			if parsed, okParse := parseIntAny(val); okParse && parsed > 0 {
+				return parsed
+			}
+		}
+	}
+	return 0
+}
+
 func parseBoolAny(val any) (bool, bool) {
 	switch typed := val.(type) {
 	case bool:
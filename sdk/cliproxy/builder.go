@@ -5,9 +5,10 @@ package cliproxy
 
 import (
 	"fmt"
-	"strings"
+	"time"
 
 	configaccess "github.com/router-for-me/CLIProxyAPI/v6/internal/access/config_access"
+	oidcjwt "github.com/router-for-me/CLIProxyAPI/v6/internal/access/oidc_jwt"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/api"
 	sdkaccess "github.com/router-for-me/CLIProxyAPI/v6/sdk/access"
 	sdkAuth "github.com/router-for-me/CLIProxyAPI/v6/sdk/auth"
@@ -188,6 +189,7 @@ func (b *Builder) Build() (*Service, error) {
 	}
 
 	configaccess.Register(&b.cfg.SDKConfig)
+	oidcjwt.Register(&b.cfg.SDKConfig)
 	accessManager.SetProviders(sdkaccess.RegisteredProviders())
 
 	coreManager := b.coreManager
@@ -197,17 +199,13 @@ func (b *Builder) Build() (*Service, error) {
 			dirSetter.SetBaseDir(b.cfg.AuthDir)
 		}
 
-		strategy := ""
+		var routing config.RoutingConfig
+		var pricing []config.ModelPrice
 		if b.cfg != nil {
-			strategy = strings.ToLower(strings.TrimSpace(b.cfg.Routing.Strategy))
-		}
-		var selector coreauth.Selector
-		switch strategy {
-		case "fill-first", "fillfirst", "ff":
-			selector = &coreauth.FillFirstSelector{}
-		default:
-			selector = &coreauth.RoundRobinSelector{}
+			routing = b.cfg.Routing
+			pricing = b.cfg.Pricing
 		}
+		selector := newSelectorForRouting(routing, pricing)
 
 		coreManager = coreauth.NewManager(tokenStore, selector, nil)
 	}
@@ -230,3 +228,30 @@ func (b *Builder) Build() (*Service, error) {
 	}
 	return service, nil
 }
+
+// normalizeRoutingStrategy maps routing.strategy aliases to their canonical names, falling
+// back to round-robin for unknown values.
+func normalizeRoutingStrategy(strategy string) string {
+	if normalized, ok := config.NormalizeRoutingStrategy(strategy); ok {
+		return normalized
+	}
+	return "round-robin"
+}
+
+// newSelectorForRouting constructs the credential selector described by the routing configuration.
+// The pricing table is only used by the cheapest strategy.
+func newSelectorForRouting(routing config.RoutingConfig, pricing []config.ModelPrice) coreauth.Selector {
+	switch normalizeRoutingStrategy(routing.Strategy) {
+	case "fill-first":
+		return &coreauth.FillFirstSelector{}
+	case "session-affinity":
+		ttl := time.Duration(routing.SessionAffinityTTL) * time.Second
+		return coreauth.NewSessionAffinitySelector(ttl, routing.SessionAffinityHeader)
+	case "adaptive":
+		return coreauth.NewAdaptiveSelector(routing.AdaptiveExploration)
+	case "cheapest":
+		return coreauth.NewCheapestSelector(pricing)
+	default:
+		return &coreauth.RoundRobinSelector{}
+	}
+}
@@ -8,13 +8,18 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"reflect"
 	"strings"
 	"sync"
 	"time"
 
+	virtualkeys "github.com/router-for-me/CLIProxyAPI/v6/internal/access/virtual_keys"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/api"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/metrics"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/notify"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/runtime/executor"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/tracing"
 	_ "github.com/router-for-me/CLIProxyAPI/v6/internal/usage"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/watcher"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/wsrelay"
@@ -337,6 +342,15 @@ func (s *Service) applyRetryConfig(cfg *config.Config) {
 	s.coreManager.SetRetryConfig(cfg.RequestRetry, maxInterval)
 }
 
+func (s *Service) applyTracingConfig(cfg *config.Config) {
+	if s == nil || cfg == nil {
+		return
+	}
+	if err := tracing.Configure(cfg.Tracing); err != nil {
+		log.Errorf("failed to configure tracing: %v", err)
+	}
+}
+
 func openAICompatInfoFromAuth(a *coreauth.Auth) (providerKey string, compatName string, ok bool) {
 	if a == nil {
 		return "", "", false
@@ -455,11 +469,25 @@ func (s *Service) Run(ctx context.Context) error {
 	}
 
 	s.applyRetryConfig(s.cfg)
+	s.applyTracingConfig(s.cfg)
 
 	if s.coreManager != nil {
 		if errLoad := s.coreManager.Load(ctx); errLoad != nil {
 			log.Warnf("failed to load auth store: %v", errLoad)
 		}
+		s.coreManager.AddEventListener(metrics.ObserveAuthEvent)
+		notify.Default().SetAuthLookup(s.coreManager.GetByID)
+		s.coreManager.AddHook(notify.Default())
+		s.coreManager.AddEventListener(notify.Default().HandleEvent)
+	}
+
+	virtualkeys.Default().SetOnChange(func() {
+		if s.accessManager != nil {
+			s.accessManager.SetProviders(sdkaccess.RegisteredProviders())
+		}
+	})
+	if errLoad := virtualkeys.Default().Load(ctx, sdkAuth.GetTokenStore()); errLoad != nil {
+		log.Warnf("failed to load virtual keys: %v", errLoad)
 	}
 
 	tokenResult, err := s.tokenProvider.Load(ctx, s.cfg)
@@ -532,10 +560,12 @@ func (s *Service) Run(ctx context.Context) error {
 
 	var watcherWrapper *WatcherWrapper
 	reloadCallback := func(newCfg *config.Config) {
-		previousStrategy := ""
+		var previousRouting config.RoutingConfig
+		var previousPricing []config.ModelPrice
 		s.cfgMu.RLock()
 		if s.cfg != nil {
-			previousStrategy = strings.ToLower(strings.TrimSpace(s.cfg.Routing.Strategy))
+			previousRouting = s.cfg.Routing
+			previousPricing = s.cfg.Pricing
 		}
 		s.cfgMu.RUnlock()
 
@@ -548,30 +578,17 @@ func (s *Service) Run(ctx context.Context) error {
 			return
 		}
 
-		nextStrategy := strings.ToLower(strings.TrimSpace(newCfg.Routing.Strategy))
-		normalizeStrategy := func(strategy string) string {
-			switch strategy {
-			case "fill-first", "fillfirst", "ff":
-				return "fill-first"
-			default:
-				return "round-robin"
-			}
-		}
-		previousStrategy = normalizeStrategy(previousStrategy)
-		nextStrategy = normalizeStrategy(nextStrategy)
-		if s.coreManager != nil && previousStrategy != nextStrategy {
-			var selector coreauth.Selector
-			switch nextStrategy {
-			case "fill-first":
-				selector = &coreauth.FillFirstSelector{}
-			default:
-				selector = &coreauth.RoundRobinSelector{}
-			}
-			s.coreManager.SetSelector(selector)
+		nextRouting := newCfg.Routing
+		previousRouting.Strategy = normalizeRoutingStrategy(previousRouting.Strategy)
+		nextRouting.Strategy = normalizeRoutingStrategy(nextRouting.Strategy)
+		pricingChanged := nextRouting.Strategy == "cheapest" && !reflect.DeepEqual(previousPricing, newCfg.Pricing)
+		if s.coreManager != nil && (previousRouting != nextRouting || pricingChanged) {
+			s.coreManager.SetSelector(newSelectorForRouting(nextRouting, newCfg.Pricing))
 		}
 
 		s.applyRetryConfig(newCfg)
 		s.applyPprofConfig(newCfg)
+		s.applyTracingConfig(newCfg)
 		if s.server != nil {
 			s.server.UpdateClients(newCfg)
 		}
@@ -645,6 +662,12 @@ func (s *Service) Shutdown(ctx context.Context) error {
 		}
 		if s.coreManager != nil {
 			s.coreManager.StopAutoRefresh()
+			if err := s.coreManager.FlushState(ctx); err != nil {
+				log.Warnf("failed to save auth state: %v", err)
+			}
+		}
+		if err := virtualkeys.Default().Flush(ctx); err != nil {
+			log.Warnf("failed to save virtual keys: %v", err)
 		}
 		if s.watcher != nil {
 			if err := s.watcher.Stop(); err != nil {
@@ -686,6 +709,7 @@ func (s *Service) Shutdown(ctx context.Context) error {
 		}
 
 		usage.StopDefault()
+		tracing.Shutdown(ctx)
 	})
 	return shutdownErr
 }
@@ -1073,7 +1097,7 @@ func applyExcludedModels(models []*ModelInfo, excluded []string) []*ModelInfo {
 		modelID := strings.ToLower(strings.TrimSpace(model.ID))
 		blocked := false
 		for _, pattern := range patterns {
-			if matchWildcard(pattern, modelID) {
+			if config.MatchWildcard(pattern, modelID) {
 				blocked = true
 				break
 			}
@@ -1127,50 +1151,6 @@ func applyModelPrefixes(models []*ModelInfo, prefix string, forceModelPrefix boo
 	return out
 }
 
-// matchWildcard performs case-insensitive wildcard matching where '*' matches any substring.
-func matchWildcard(pattern, value string) bool {
-	if pattern == "" {
-		return false
-	}
-
-	// Fast path for exact match (no wildcard present).
-	if !strings.Contains(pattern, "*") {
-		return pattern == value
-	}
-
-	parts := strings.Split(pattern, "*")
-	// Handle prefix.
-	if prefix := parts[0]; prefix != "" {
-		if !strings.HasPrefix(value, prefix) {
-			return false
-		}
-		value = value[len(prefix):]
-	}
-
-	// Handle suffix.
-	if suffix := parts[len(parts)-1]; suffix != "" {
-		if !strings.HasSuffix(value, suffix) {
-			return false
-		}
-		value = value[:len(value)-len(suffix)]
-	}
-
-	// Handle middle segments in order.
-	for i := 1; i < len(parts)-1; i++ {
-		segment := parts[i]
-		if segment == "" {
-			continue
-		}
-		idx := strings.Index(value, segment)
-		if idx < 0 {
-			return false
-		}
-		value = value[idx+len(segment):]
-	}
-
-	return true
-}
-
 type modelEntry interface {
 	GetName() string
 	GetAlias() string
@@ -3,6 +3,7 @@ package usage
 import (
 	"context"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -18,7 +19,25 @@ type Record struct {
 	Source      string
 	RequestedAt time.Time
 	Failed      bool
+	Hedged      bool
 	Detail      Detail
+
+	// Format is the inbound API format of the client request (e.g. "openai", "claude").
+	Format string
+	// StatusCode is the HTTP status of the final upstream response, zero when none arrived.
+	StatusCode int
+	// Latency is the time until upstream response headers arrived.
+	Latency time.Duration
+	// FirstToken is the time until the first upstream response body byte arrived.
+	FirstToken time.Duration
+	// StreamDuration is the time from the first byte until the record was published, for
+	// streaming requests only.
+	StreamDuration time.Duration
+	// Retries counts the attempts made for the same client request before this one.
+	Retries int
+	// AuthsTried counts the distinct credentials used for the client request so far,
+	// including this one.
+	AuthsTried int
 }
 
 // Detail holds the token usage breakdown.
@@ -179,3 +198,24 @@ func StartDefault(ctx context.Context) { DefaultManager().Start(ctx) }
 
 // StopDefault stops the default manager's dispatcher.
 func StopDefault() { DefaultManager().Stop() }
+
+type hedgedContextKey struct{}
+
+// WithHedgeFlag returns a context whose usage records are marked hedged once flag is set.
+// The flag may be set after the request starts, so an attempt launched before a hedge
+// is still reported as hedged.
+func WithHedgeFlag(ctx context.Context, flag *atomic.Bool) context.Context {
+	if flag == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, hedgedContextKey{}, flag)
+}
+
+// HedgedFromContext reports whether the request carried by ctx has been hedged.
+func HedgedFromContext(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	flag, ok := ctx.Value(hedgedContextKey{}).(*atomic.Bool)
+	return ok && flag != nil && flag.Load()
+}
@@ -0,0 +1,84 @@
+package usage
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// Timing collects upstream timing for one execution attempt. The manager attaches it to
+// the attempt context and the executor HTTP transport fills it in, so usage records can
+// report latency without every executor measuring it.
+type Timing struct {
+	start     time.Time
+	headers   atomic.Int64
+	firstByte atomic.Int64
+	status    atomic.Int64
+}
+
+type timingContextKey struct{}
+
+// WithTiming returns a context carrying a new Timing that starts now.
+func WithTiming(ctx context.Context) context.Context {
+	return context.WithValue(ctx, timingContextKey{}, &Timing{start: time.Now()})
+}
+
+// TimingFromContext returns the Timing attached to ctx, or nil.
+func TimingFromContext(ctx context.Context) *Timing {
+	if ctx == nil {
+		return nil
+	}
+	timing, _ := ctx.Value(timingContextKey{}).(*Timing)
+	return timing
+}
+
+// MarkResponse records the arrival of upstream response headers with status. A later
+// response, such as a retry against a fallback endpoint, replaces the earlier one.
+func (t *Timing) MarkResponse(status int) {
+	if t == nil {
+		return
+	}
+	t.headers.Store(int64(time.Since(t.start)))
+	t.firstByte.Store(0)
+	t.status.Store(int64(status))
+}
+
+// MarkFirstByte records the first body byte of the current response.
+func (t *Timing) MarkFirstByte() {
+	if t == nil {
+		return
+	}
+	t.firstByte.CompareAndSwap(0, int64(time.Since(t.start)))
+}
+
+// Latency returns the time until upstream response headers arrived, zero when unknown.
+func (t *Timing) Latency() time.Duration {
+	if t == nil {
+		return 0
+	}
+	return time.Duration(t.headers.Load())
+}
+
+// FirstByte returns the time until the first response body byte, zero when unknown.
+func (t *Timing) FirstByte() time.Duration {
+	if t == nil {
+		return 0
+	}
+	return time.Duration(t.firstByte.Load())
+}
+
+// Elapsed returns the time since the attempt started.
+func (t *Timing) Elapsed() time.Duration {
+	if t == nil {
+		return 0
+	}
+	return time.Since(t.start)
+}
+
+// StatusCode returns the HTTP status of the latest upstream response, zero when none arrived.
+func (t *Timing) StatusCode() int {
+	if t == nil {
+		return 0
+	}
+	return int(t.status.Load())
+}
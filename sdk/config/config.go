@@ -13,6 +13,11 @@ type Config = internalconfig.Config
 type StreamingConfig = internalconfig.StreamingConfig
 type TLSConfig = internalconfig.TLSConfig
 type RemoteManagement = internalconfig.RemoteManagement
+type RoutingConfig = internalconfig.RoutingConfig
+type CircuitBreakerConfig = internalconfig.CircuitBreakerConfig
+type CooldownQueueConfig = internalconfig.CooldownQueueConfig
+type ModelPrice = internalconfig.ModelPrice
+type APIKeyPolicy = internalconfig.APIKeyPolicy
 type AmpCode = internalconfig.AmpCode
 type OAuthModelAlias = internalconfig.OAuthModelAlias
 type PayloadConfig = internalconfig.PayloadConfig
@@ -49,6 +54,14 @@ func SaveConfigPreserveCommentsUpdateNestedScalar(configFile string, path []stri
 	return internalconfig.SaveConfigPreserveCommentsUpdateNestedScalar(configFile, path, value)
 }
 
+// MatchWildcard reports whether value matches pattern, where '*' matches any substring.
+func MatchWildcard(pattern, value string) bool { return internalconfig.MatchWildcard(pattern, value) }
+
+// NormalizeRoutingStrategy maps a routing.strategy value or alias to its canonical name.
+func NormalizeRoutingStrategy(strategy string) (string, bool) {
+	return internalconfig.NormalizeRoutingStrategy(strategy)
+}
+
 func NormalizeCommentIndentation(data []byte) []byte {
 	return internalconfig.NormalizeCommentIndentation(data)
 }
@@ -3,6 +3,10 @@ package translator
 import (
 	"context"
 	"sync"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Registry manages translation functions across schemas.
@@ -121,6 +125,21 @@ func TranslateRequest(from, to Format, model string, rawJSON []byte, stream bool
 	return defaultRegistry.TranslateRequest(from, to, model, rawJSON, stream)
 }
 
+// TranslateRequestContext is TranslateRequest on the default registry, recorded as a
+// tracing span under the span carried by ctx.
+func TranslateRequestContext(ctx context.Context, from, to Format, model string, rawJSON []byte, stream bool) []byte {
+	_, span := tracing.Tracer().Start(ctx, "translate.request", trace.WithAttributes(
+		attribute.String("translate.from", from.String()),
+		attribute.String("translate.to", to.String()),
+		attribute.String("translate.model", model),
+		attribute.Int("translate.input_bytes", len(rawJSON)),
+	))
+	out := defaultRegistry.TranslateRequest(from, to, model, rawJSON, stream)
+	span.SetAttributes(attribute.Int("translate.output_bytes", len(out)))
+	span.End()
+	return out
+}
+
 // HasResponseTransformer inspects the default registry.
 func HasResponseTransformer(from, to Format) bool {
 	return defaultRegistry.HasResponseTransformer(from, to)
@@ -133,7 +152,14 @@ func TranslateStream(ctx context.Context, from, to Format, model string, origina
 
 // TranslateNonStream is a helper on the default registry.
 func TranslateNonStream(ctx context.Context, from, to Format, model string, originalRequestRawJSON, requestRawJSON, rawJSON []byte, param *any) string {
-	return defaultRegistry.TranslateNonStream(ctx, from, to, model, originalRequestRawJSON, requestRawJSON, rawJSON, param)
+	_, span := tracing.Tracer().Start(ctx, "translate.response", trace.WithAttributes(
+		attribute.String("translate.from", from.String()),
+		attribute.String("translate.to", to.String()),
+		attribute.String("translate.model", model),
+	))
+	out := defaultRegistry.TranslateNonStream(ctx, from, to, model, originalRequestRawJSON, requestRawJSON, rawJSON, param)
+	span.End()
+	return out
 }
 
 // TranslateTokenCount is a helper on the default registry.